@@ -0,0 +1,140 @@
+package main
+
+import (
+	"aviator/aircraft"
+	"aviator/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// aircraftCrud is a router to route API routes to the correct backend method
+func aircraftCrud(ctx context.Context, request events.APIGatewayProxyRequest, path string, stage string,
+	aircraftApi aircraft.AircraftApiInterface, errorClient utils.ApiErrorClient) (events.APIGatewayProxyResponse, error) {
+	registration := request.PathParameters["registration"]
+
+	var responseBody []byte
+	switch request.HTTPMethod {
+	case http.MethodGet:
+		switch path {
+		case "/aircraft":
+			var input aircraft.ListInput
+			queryParams := request.QueryStringParameters
+			limitString, ok := queryParams["limit"]
+			if ok {
+				i, err := strconv.ParseInt(limitString, 10, 64)
+				if err != nil {
+					return errorClient.ClientError(400, errors.New("Invalid limit"))
+				}
+				input.Limit = aws.Int32(int32(i))
+			}
+
+			nextTokenStr, ok := queryParams["nextToken"]
+			if ok {
+				input.NextToken = &nextTokenStr
+			}
+
+			aircraft, err := aircraftApi.List(input)
+			errorClient.SetLogger(aircraftApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(aircraft)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		case fmt.Sprintf("/aircraft/%s", registration):
+			aircraft, err := aircraftApi.Get(registration)
+			errorClient.SetLogger(aircraftApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(aircraft)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	case http.MethodPost:
+		switch path {
+		case "/aircraft":
+			var requestBody aircraft.Aircraft
+			err := json.Unmarshal([]byte(request.Body), &requestBody)
+			if err != nil {
+				return errorClient.ClientError(400, err)
+			}
+
+			aircraft, err := aircraftApi.Create(requestBody)
+			errorClient.SetLogger(aircraftApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+
+			responseBody, err = json.Marshal(aircraft)
+			if err != nil {
+				return errorClient.ClientError(500, err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusCreated,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	case http.MethodPut:
+		switch path {
+		case fmt.Sprintf("/aircraft/%s", registration):
+			var requestBody aircraft.Aircraft
+			err := json.Unmarshal([]byte(request.Body), &requestBody)
+			if err != nil {
+				return errorClient.ClientError(400, err)
+			}
+			requestBody.Registration = registration
+
+			aircraft, err := aircraftApi.Update(requestBody)
+			errorClient.SetLogger(aircraftApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+
+			responseBody, err = json.Marshal(aircraft)
+			if err != nil {
+				return errorClient.ClientError(500, err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	case http.MethodDelete:
+		switch path {
+		case fmt.Sprintf("/aircraft/%s", registration):
+			err := aircraftApi.Delete(registration)
+			errorClient.SetLogger(aircraftApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNoContent,
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	}
+
+	return errorClient.ClientError(400, errors.New("bad request"))
+}
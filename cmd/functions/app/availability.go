@@ -0,0 +1,77 @@
+package main
+
+import (
+	"aviator/reservation"
+	"aviator/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// availability returns the free slots of the aircraft and resources of the club
+func availability(ctx context.Context, request events.APIGatewayProxyRequest, path string, stage string,
+	reservationApi reservation.ReservationApiInterface, errorClient utils.ApiErrorClient) (events.APIGatewayProxyResponse, error) {
+	if request.HTTPMethod != http.MethodGet || path != "/availability" {
+		return errorClient.ClientError(400, errors.New("bad request"))
+	}
+
+	var input reservation.AvailabilityInput
+	queryParams := request.QueryStringParameters
+	aircraftString, ok := queryParams["aircraft"]
+	if ok {
+		input.Aircraft = strings.Split(aircraftString, ",")
+	}
+
+	resourceString, ok := queryParams["resource"]
+	if ok {
+		input.Resources = strings.Split(resourceString, ",")
+	}
+
+	startString, ok := queryParams["start"]
+	if ok {
+		start, err := parseTimeParameter(startString)
+		if err != nil {
+			return errorClient.ClientError(400, errors.New("Invalid start"))
+		}
+		input.Start = start
+	}
+
+	endString, ok := queryParams["end"]
+	if ok {
+		end, err := parseTimeParameter(endString)
+		if err != nil {
+			return errorClient.ClientError(400, errors.New("Invalid end"))
+		}
+		input.End = end
+	}
+
+	minDurationString, ok := queryParams["minDuration"]
+	if ok {
+		minutes, err := strconv.ParseInt(minDurationString, 10, 64)
+		if err != nil {
+			return errorClient.ClientError(400, errors.New("Invalid minDuration"))
+		}
+		input.MinDuration = time.Duration(minutes) * time.Minute
+	}
+
+	output, err := reservationApi.Availability(input)
+	errorClient.SetLogger(reservationApi.Logger())
+	if err != nil {
+		return errorClient.AwsError(err)
+	}
+	responseBody, err := json.Marshal(output)
+	if err != nil {
+		return errorClient.AwsError(err)
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(responseBody),
+		Headers:    utils.ResponseHeaders(),
+	}, nil
+}
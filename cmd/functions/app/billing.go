@@ -0,0 +1,57 @@
+package main
+
+import (
+	"aviator/billing"
+	"aviator/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// billingCrud is a router to route API routes to the correct backend method
+func billingCrud(ctx context.Context, request events.APIGatewayProxyRequest, path string, stage string,
+	billingApi billing.BillingApiInterface, errorClient utils.ApiErrorClient) (events.APIGatewayProxyResponse, error) {
+	memberId := request.PathParameters["memberId"]
+	if request.HTTPMethod != http.MethodGet || path != fmt.Sprintf("/members/%s/statement", memberId) {
+		return errorClient.ClientError(400, errors.New("bad request"))
+	}
+
+	var input billing.StatementInput
+	queryParams := request.QueryStringParameters
+	startString, ok := queryParams["start"]
+	if ok {
+		start, err := parseTimeParameter(startString)
+		if err != nil {
+			return errorClient.ClientError(400, errors.New("Invalid start"))
+		}
+		input.Start = start
+	}
+
+	endString, ok := queryParams["end"]
+	if ok {
+		end, err := parseTimeParameter(endString)
+		if err != nil {
+			return errorClient.ClientError(400, errors.New("Invalid end"))
+		}
+		input.End = end
+	}
+
+	statement, err := billingApi.Statement(memberId, input)
+	errorClient.SetLogger(billingApi.Logger())
+	if err != nil {
+		return errorClient.AwsError(err)
+	}
+	responseBody, err := json.Marshal(statement)
+	if err != nil {
+		return errorClient.AwsError(err)
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(responseBody),
+		Headers:    utils.ResponseHeaders(),
+	}, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"aviator/checkout"
+	"aviator/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// checkoutCrud is a router to route API routes to the correct backend method
+func checkoutCrud(ctx context.Context, request events.APIGatewayProxyRequest, path string, stage string,
+	checkoutApi checkout.CheckoutApiInterface, errorClient utils.ApiErrorClient) (events.APIGatewayProxyResponse, error) {
+	memberId := request.PathParameters["memberId"]
+	registration := request.PathParameters["registration"]
+	aircraftType := request.PathParameters["aircraftType"]
+
+	var responseBody []byte
+	switch request.HTTPMethod {
+	case http.MethodGet:
+		switch path {
+		case fmt.Sprintf("/members/%s/checkouts", memberId):
+			var input checkout.ListInput
+			queryParams := request.QueryStringParameters
+			limitString, ok := queryParams["limit"]
+			if ok {
+				i, err := strconv.ParseInt(limitString, 10, 64)
+				if err != nil {
+					return errorClient.ClientError(400, errors.New("Invalid limit"))
+				}
+				input.Limit = aws.Int32(int32(i))
+			}
+
+			nextTokenStr, ok := queryParams["nextToken"]
+			if ok {
+				input.NextToken = &nextTokenStr
+			}
+
+			checkouts, err := checkoutApi.List(memberId, input)
+			errorClient.SetLogger(checkoutApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(checkouts)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	case http.MethodPost:
+		switch path {
+		case fmt.Sprintf("/members/%s/checkouts", memberId):
+			var requestBody checkout.Checkout
+			err := json.Unmarshal([]byte(request.Body), &requestBody)
+			if err != nil {
+				return errorClient.ClientError(400, err)
+			}
+			requestBody.MemberId = memberId
+
+			checkout, err := checkoutApi.Grant(requestBody)
+			errorClient.SetLogger(checkoutApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+
+			responseBody, err = json.Marshal(checkout)
+			if err != nil {
+				return errorClient.ClientError(500, err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusCreated,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	case http.MethodDelete:
+		var err error
+		switch path {
+		case fmt.Sprintf("/members/%s/checkouts/aircraft/%s", memberId, registration):
+			err = checkoutApi.Revoke(memberId, checkout.TARGET_AIRCRAFT, registration)
+		case fmt.Sprintf("/members/%s/checkouts/aircraft-types/%s", memberId, aircraftType):
+			err = checkoutApi.Revoke(memberId, checkout.TARGET_AIRCRAFT_TYPE, aircraftType)
+		default:
+			return errorClient.ClientError(400, errors.New("bad request"))
+		}
+		errorClient.SetLogger(checkoutApi.Logger())
+		if err != nil {
+			return errorClient.AwsError(err)
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNoContent,
+			Headers:    utils.ResponseHeaders(),
+		}, nil
+	}
+
+	return errorClient.ClientError(400, errors.New("bad request"))
+}
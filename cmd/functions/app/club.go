@@ -0,0 +1,127 @@
+package main
+
+import (
+	"aviator/club"
+	"aviator/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// clubCrud is a router to route API routes to the correct backend method
+func clubCrud(ctx context.Context, request events.APIGatewayProxyRequest, path string, stage string,
+	clubApi club.ClubApiInterface, errorClient utils.ApiErrorClient) (events.APIGatewayProxyResponse, error) {
+	clubId := request.PathParameters["clubId"]
+
+	var responseBody []byte
+	switch request.HTTPMethod {
+	case http.MethodGet:
+		switch path {
+		case "/clubs":
+			var input club.ListInput
+			queryParams := request.QueryStringParameters
+			limitString, ok := queryParams["limit"]
+			if ok {
+				i, err := strconv.ParseInt(limitString, 10, 64)
+				if err != nil {
+					return errorClient.ClientError(400, errors.New("Invalid limit"))
+				}
+				input.Limit = aws.Int32(int32(i))
+			}
+
+			nextTokenStr, ok := queryParams["nextToken"]
+			if ok {
+				input.NextToken = &nextTokenStr
+			}
+
+			clubs, err := clubApi.List(input)
+			errorClient.SetLogger(clubApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(clubs)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		case fmt.Sprintf("/clubs/%s", clubId):
+			club, err := clubApi.Get(clubId)
+			errorClient.SetLogger(clubApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(club)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	case http.MethodPost:
+		switch path {
+		case "/clubs":
+			var requestBody club.Club
+			err := json.Unmarshal([]byte(request.Body), &requestBody)
+			if err != nil {
+				return errorClient.ClientError(400, err)
+			}
+
+			club, err := clubApi.CreateOrUpdate(requestBody)
+			errorClient.SetLogger(clubApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+
+			responseBody, err = json.Marshal(club)
+			if err != nil {
+				return errorClient.ClientError(500, err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusCreated,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	case http.MethodPut:
+		switch path {
+		case fmt.Sprintf("/clubs/%s", clubId):
+			var requestBody club.Club
+			err := json.Unmarshal([]byte(request.Body), &requestBody)
+			if err != nil {
+				return errorClient.ClientError(400, err)
+			}
+			requestBody.Id = clubId
+
+			club, err := clubApi.CreateOrUpdate(requestBody)
+			errorClient.SetLogger(clubApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+
+			responseBody, err = json.Marshal(club)
+			if err != nil {
+				return errorClient.ClientError(500, err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	}
+
+	return errorClient.ClientError(400, errors.New("bad request"))
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"aviator/flightlog"
+	"aviator/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// flightLogCrud is a router to route API routes to the correct backend method
+func flightLogCrud(ctx context.Context, request events.APIGatewayProxyRequest, path string, stage string,
+	flightLogApi flightlog.FlightLogApiInterface, errorClient utils.ApiErrorClient) (events.APIGatewayProxyResponse, error) {
+	reservationId := request.PathParameters["reservationId"]
+	registration := request.PathParameters["registration"]
+
+	if request.HTTPMethod != http.MethodGet {
+		return errorClient.ClientError(400, errors.New("bad request"))
+	}
+
+	var response any
+	var err error
+	switch path {
+	case fmt.Sprintf("/reservations/%s/flight-log", reservationId):
+		response, err = flightLogApi.Get(reservationId)
+	case fmt.Sprintf("/aircraft/%s/flight-logs", registration):
+		var input flightlog.ListInput
+		queryParams := request.QueryStringParameters
+		limitString, ok := queryParams["limit"]
+		if ok {
+			i, err := strconv.ParseInt(limitString, 10, 64)
+			if err != nil {
+				return errorClient.ClientError(400, errors.New("Invalid limit"))
+			}
+			input.Limit = aws.Int32(int32(i))
+		}
+
+		nextTokenStr, ok := queryParams["nextToken"]
+		if ok {
+			input.NextToken = &nextTokenStr
+		}
+
+		response, err = flightLogApi.List(registration, input)
+	default:
+		return errorClient.ClientError(400, errors.New("bad request"))
+	}
+	errorClient.SetLogger(flightLogApi.Logger())
+	if err != nil {
+		return errorClient.AwsError(err)
+	}
+
+	responseBody, err := json.Marshal(response)
+	if err != nil {
+		return errorClient.AwsError(err)
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(responseBody),
+		Headers:    utils.ResponseHeaders(),
+	}, nil
+}
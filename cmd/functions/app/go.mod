@@ -29,6 +29,7 @@ require (
 	github.com/aws/smithy-go v1.20.1 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
 	github.com/oklog/ulid/v2 v2.1.0 // indirect
+	rsc.io/qr v0.2.0 // indirect
 )
 
 replace aviator => ../../../lib/aviator
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"aviator/invoice"
+	"aviator/utils"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Body of an invoice generation request
+type generateInvoiceInput struct {
+	// Invoiced month: e.g. 2024-04
+	Month string `json:"month"`
+}
+
+// invoiceCrud is a router to route API routes to the correct backend method
+func invoiceCrud(ctx context.Context, request events.APIGatewayProxyRequest, path string, stage string,
+	invoiceApi invoice.InvoiceApiInterface, errorClient utils.ApiErrorClient) (events.APIGatewayProxyResponse, error) {
+	memberId := request.PathParameters["memberId"]
+	month := request.PathParameters["month"]
+
+	var responseBody []byte
+	switch request.HTTPMethod {
+	case http.MethodGet:
+		switch path {
+		case fmt.Sprintf("/members/%s/invoices", memberId):
+			var input invoice.ListInput
+			queryParams := request.QueryStringParameters
+			limitString, ok := queryParams["limit"]
+			if ok {
+				i, err := strconv.ParseInt(limitString, 10, 64)
+				if err != nil {
+					return errorClient.ClientError(400, errors.New("Invalid limit"))
+				}
+				input.Limit = aws.Int32(int32(i))
+			}
+
+			nextTokenStr, ok := queryParams["nextToken"]
+			if ok {
+				input.NextToken = &nextTokenStr
+			}
+
+			invoices, err := invoiceApi.List(memberId, input)
+			errorClient.SetLogger(invoiceApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(invoices)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		case fmt.Sprintf("/members/%s/invoices/%s", memberId, month):
+			out, err := invoiceApi.Get(memberId, month)
+			errorClient.SetLogger(invoiceApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(out)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		case fmt.Sprintf("/members/%s/invoices/%s/pdf", memberId, month):
+			pdf, err := invoiceApi.PDF(memberId, month)
+			errorClient.SetLogger(invoiceApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			// API Gateway decodes the body back to binary for clients accepting application/pdf
+			headers := utils.ResponseHeaders()
+			headers["Content-Type"] = "application/pdf"
+			headers["Content-Disposition"] = fmt.Sprintf("attachment; filename=\"invoice-%s.pdf\"", month)
+			return events.APIGatewayProxyResponse{
+				StatusCode:      http.StatusOK,
+				Body:            base64.StdEncoding.EncodeToString(pdf),
+				IsBase64Encoded: true,
+				Headers:         headers,
+			}, nil
+		}
+	case http.MethodPost:
+		if path != fmt.Sprintf("/members/%s/invoices", memberId) {
+			break
+		}
+		var input generateInvoiceInput
+		err := json.Unmarshal([]byte(request.Body), &input)
+		if err != nil {
+			return errorClient.ClientError(400, err)
+		}
+
+		out, err := invoiceApi.Generate(memberId, input.Month)
+		errorClient.SetLogger(invoiceApi.Logger())
+		if err != nil {
+			return errorClient.AwsError(err)
+		}
+		responseBody, err = json.Marshal(out)
+		if err != nil {
+			return errorClient.AwsError(err)
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusCreated,
+			Body:       string(responseBody),
+			Headers:    utils.ResponseHeaders(),
+		}, nil
+	}
+	return errorClient.ClientError(400, errors.New("bad request"))
+}
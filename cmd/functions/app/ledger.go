@@ -0,0 +1,100 @@
+package main
+
+import (
+	"aviator/ledger"
+	"aviator/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// ledgerCrud is a router to route API routes to the correct backend method
+func ledgerCrud(ctx context.Context, request events.APIGatewayProxyRequest, path string, stage string,
+	ledgerApi ledger.LedgerApiInterface, errorClient utils.ApiErrorClient) (events.APIGatewayProxyResponse, error) {
+	memberId := request.PathParameters["memberId"]
+
+	var responseBody []byte
+	switch request.HTTPMethod {
+	case http.MethodGet:
+		switch path {
+		case fmt.Sprintf("/members/%s/balance", memberId):
+			balance, err := ledgerApi.Balance(memberId)
+			errorClient.SetLogger(ledgerApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(balance)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		case fmt.Sprintf("/members/%s/ledger", memberId):
+			var input ledger.ListInput
+			queryParams := request.QueryStringParameters
+			limitString, ok := queryParams["limit"]
+			if ok {
+				i, err := strconv.ParseInt(limitString, 10, 64)
+				if err != nil {
+					return errorClient.ClientError(400, errors.New("Invalid limit"))
+				}
+				input.Limit = aws.Int32(int32(i))
+			}
+
+			nextTokenStr, ok := queryParams["nextToken"]
+			if ok {
+				input.NextToken = &nextTokenStr
+			}
+
+			entries, err := ledgerApi.List(memberId, input)
+			errorClient.SetLogger(ledgerApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(entries)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	case http.MethodPost:
+		if path != fmt.Sprintf("/members/%s/ledger", memberId) {
+			break
+		}
+		var input ledger.Entry
+		err := json.Unmarshal([]byte(request.Body), &input)
+		if err != nil {
+			return errorClient.ClientError(400, err)
+		}
+		input.MemberId = memberId
+
+		entry, err := ledgerApi.Record(input)
+		errorClient.SetLogger(ledgerApi.Logger())
+		if err != nil {
+			return errorClient.AwsError(err)
+		}
+		responseBody, err = json.Marshal(entry)
+		if err != nil {
+			return errorClient.AwsError(err)
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusCreated,
+			Body:       string(responseBody),
+			Headers:    utils.ResponseHeaders(),
+		}, nil
+	}
+	return errorClient.ClientError(400, errors.New("bad request"))
+}
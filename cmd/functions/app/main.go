@@ -4,14 +4,30 @@ Lambda app handles all API calls.
 package main
 
 import (
+	"aviator/aircraft"
+	"aviator/auth"
+	"aviator/billing"
+	"aviator/checkout"
+	"aviator/club"
 	"aviator/database"
+	"aviator/flightlog"
+	"aviator/invoice"
+	"aviator/ledger"
+	"aviator/maintenance"
+	"aviator/member"
 	"aviator/reservation"
+	"aviator/reservationtype"
+	"aviator/resource"
+	"aviator/tenant"
 	"aviator/utils"
+	"aviator/weather"
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"strings"
+	_ "time/tzdata"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -19,6 +35,17 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 )
 
+// Kept across invocations of the same Lambda instance, so that the JWKS document is not fetched on every call
+var authClient = auth.NewFromConfig(
+	auth.Config{
+		Logger:   slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		JwksFile: os.Getenv("JWKS_FILE"),
+		JwksUrl:  os.Getenv("JWKS_URL"),
+		Issuer:   os.Getenv("JWT_ISSUER"),
+		Audience: os.Getenv("JWT_AUDIENCE"),
+	},
+)
+
 func HandleRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	stage := request.StageVariables["name"]
@@ -41,23 +68,132 @@ func HandleRequest(ctx context.Context, request events.APIGatewayProxyRequest) (
 		},
 	)
 
-	reservationClient := reservation.NewFromConfig(
-		reservation.Config{
-			Logger:         logger,
-			DatabaseClient: *databaseClient,
+	errorClient := utils.NewFromConfig("en", logger)
+
+	// Every key is derived from the tenant of the caller, requests without a valid token are rejected
+	authClient.SetLogger(logger)
+	identity, err := authClient.Authenticate(requestHeader(request, "Authorization"))
+	if err != nil {
+		return errorClient.AwsError(err)
+	}
+	tenantId := identity.TenantId
+	logger = logger.With("tenant", tenantId, "user", identity.UserId, "role", identity.UserRole)
+	errorClient.SetLogger(logger)
+
+	// All clients share the scope of the caller, so none of them can read or write outside of its club and role
+	scope := tenant.Scope{
+		Logger:         logger,
+		DatabaseClient: *databaseClient,
+		TenantId:       tenantId,
+		UserId:         identity.UserId,
+		UserRole:       identity.UserRole,
+	}
+
+	reservationClient := reservation.NewFromConfig(reservation.Config{Scope: scope})
+	aircraftClient := aircraft.NewFromConfig(aircraft.Config{Scope: scope})
+	memberClient := member.NewFromConfig(member.Config{Scope: scope})
+	reservationTypeClient := reservationtype.NewFromConfig(reservationtype.Config{Scope: scope})
+	resourceClient := resource.NewFromConfig(resource.Config{Scope: scope})
+	checkoutClient := checkout.NewFromConfig(checkout.Config{Scope: scope})
+	billingClient := billing.NewFromConfig(billing.Config{Scope: scope})
+	invoiceClient := invoice.NewFromConfig(invoice.Config{Scope: scope})
+	ledgerClient := ledger.NewFromConfig(ledger.Config{Scope: scope})
+	weatherClient := weather.NewFromConfig(weather.Config{Scope: scope})
+	flightLogClient := flightlog.NewFromConfig(flightlog.Config{Scope: scope})
+	maintenanceClient := maintenance.NewFromConfig(maintenance.Config{Scope: scope})
+	clubClient := club.NewFromConfig(
+		club.Config{
+			Scope:            scope,
+			PlatformTenantId: os.Getenv("PLATFORM_TENANT_ID"),
 		},
 	)
 
-	errorClient := utils.NewFromConfig("en", logger)
+	if path == fmt.Sprintf("/reservations/%s/flight-log", request.PathParameters["reservationId"]) ||
+		path == fmt.Sprintf("/aircraft/%s/flight-logs", request.PathParameters["registration"]) {
+		flightLogClient.SetLogger(logger)
+		return flightLogCrud(ctx, request, path, stage, flightLogClient, *errorClient)
+	}
 
 	if strings.HasPrefix(path, "/reservations") {
 		reservationClient.SetLogger(logger)
 		return reservationCrud(ctx, request, path, stage, reservationClient, *errorClient)
 	}
 
+	if strings.HasPrefix(path, "/availability") {
+		reservationClient.SetLogger(logger)
+		return availability(ctx, request, path, stage, reservationClient, *errorClient)
+	}
+
+	if strings.HasPrefix(path, "/reservation-types") {
+		reservationTypeClient.SetLogger(logger)
+		return reservationTypeCrud(ctx, request, path, stage, reservationTypeClient, *errorClient)
+	}
+
+	if strings.HasPrefix(path, fmt.Sprintf("/aircraft/%s/maintenance", request.PathParameters["registration"])) {
+		maintenanceClient.SetLogger(logger)
+		reservationClient.SetLogger(logger)
+		return maintenanceCrud(ctx, request, path, stage, maintenanceClient, reservationClient, *errorClient)
+	}
+
+	if strings.HasPrefix(path, "/aircraft") {
+		aircraftClient.SetLogger(logger)
+		return aircraftCrud(ctx, request, path, stage, aircraftClient, *errorClient)
+	}
+
+	if strings.HasPrefix(path, "/resources") {
+		resourceClient.SetLogger(logger)
+		return resourceCrud(ctx, request, path, stage, resourceClient, *errorClient)
+	}
+
+	if strings.HasPrefix(path, fmt.Sprintf("/members/%s/checkouts", request.PathParameters["memberId"])) {
+		checkoutClient.SetLogger(logger)
+		return checkoutCrud(ctx, request, path, stage, checkoutClient, *errorClient)
+	}
+
+	if path == fmt.Sprintf("/members/%s/statement", request.PathParameters["memberId"]) {
+		billingClient.SetLogger(logger)
+		return billingCrud(ctx, request, path, stage, billingClient, *errorClient)
+	}
+
+	if strings.HasPrefix(path, fmt.Sprintf("/members/%s/invoices", request.PathParameters["memberId"])) {
+		invoiceClient.SetLogger(logger)
+		return invoiceCrud(ctx, request, path, stage, invoiceClient, *errorClient)
+	}
+
+	if path == fmt.Sprintf("/members/%s/balance", request.PathParameters["memberId"]) ||
+		path == fmt.Sprintf("/members/%s/ledger", request.PathParameters["memberId"]) {
+		ledgerClient.SetLogger(logger)
+		return ledgerCrud(ctx, request, path, stage, ledgerClient, *errorClient)
+	}
+
+	if strings.HasPrefix(path, "/members") {
+		memberClient.SetLogger(logger)
+		return memberCrud(ctx, request, path, stage, memberClient, *errorClient)
+	}
+
+	if strings.HasPrefix(path, "/weather-stations") {
+		weatherClient.SetLogger(logger)
+		return weatherCrud(ctx, request, path, stage, weatherClient, *errorClient)
+	}
+
+	if strings.HasPrefix(path, "/clubs") {
+		clubClient.SetLogger(logger)
+		return clubCrud(ctx, request, path, stage, clubClient, *errorClient)
+	}
+
 	return errorClient.ClientError(400, errors.New("bad request"))
 }
 
+// requestHeader returns the value of a header of the request, whatever its case
+func requestHeader(request events.APIGatewayProxyRequest, header string) string {
+	for name, value := range request.Headers {
+		if strings.EqualFold(name, header) {
+			return value
+		}
+	}
+	return ""
+}
+
 func main() {
 	lambda.Start(HandleRequest)
 }
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"aviator/maintenance"
+	"aviator/reservation"
+	"aviator/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// maintenanceCrud is a router to route API routes to the correct backend method
+func maintenanceCrud(ctx context.Context, request events.APIGatewayProxyRequest, path string, stage string,
+	maintenanceApi maintenance.MaintenanceApiInterface, reservationApi reservation.ReservationApiInterface,
+	errorClient utils.ApiErrorClient) (events.APIGatewayProxyResponse, error) {
+	registration := request.PathParameters["registration"]
+	blockId := request.PathParameters["blockId"]
+
+	var responseBody []byte
+	switch request.HTTPMethod {
+	case http.MethodGet:
+		switch path {
+		case fmt.Sprintf("/aircraft/%s/maintenance", registration):
+			var input maintenance.ListInput
+			queryParams := request.QueryStringParameters
+			limitString, ok := queryParams["limit"]
+			if ok {
+				i, err := strconv.ParseInt(limitString, 10, 64)
+				if err != nil {
+					return errorClient.ClientError(400, errors.New("Invalid limit"))
+				}
+				input.Limit = aws.Int32(int32(i))
+			}
+
+			nextTokenStr, ok := queryParams["nextToken"]
+			if ok {
+				input.NextToken = &nextTokenStr
+			}
+
+			blocks, err := maintenanceApi.List(registration, input)
+			errorClient.SetLogger(maintenanceApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(blocks)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		case fmt.Sprintf("/aircraft/%s/maintenance/%s", registration, blockId):
+			block, err := maintenanceApi.Get(registration, blockId)
+			errorClient.SetLogger(maintenanceApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(block)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	case http.MethodPost, http.MethodPut:
+		statusCode := http.StatusOK
+		switch {
+		case request.HTTPMethod == http.MethodPost && path == fmt.Sprintf("/aircraft/%s/maintenance", registration):
+			statusCode = http.StatusCreated
+		case request.HTTPMethod == http.MethodPut && path == fmt.Sprintf("/aircraft/%s/maintenance/%s", registration, blockId):
+		default:
+			return errorClient.ClientError(400, errors.New("bad request"))
+		}
+
+		var requestBody maintenance.Block
+		err := json.Unmarshal([]byte(request.Body), &requestBody)
+		if err != nil {
+			return errorClient.ClientError(400, err)
+		}
+		requestBody.Aircraft = registration
+		requestBody.Id = blockId
+
+		block, err := maintenanceApi.CreateOrUpdate(requestBody)
+		errorClient.SetLogger(maintenanceApi.Logger())
+		if err != nil {
+			return errorClient.AwsError(err)
+		}
+
+		// The block is returned with the reservations it invalidates, so dispatchers can contact the pilots
+		impact, err := reservationApi.MaintenanceImpact(*block)
+		errorClient.SetLogger(reservationApi.Logger())
+		if err != nil {
+			return errorClient.AwsError(err)
+		}
+
+		responseBody, err = json.Marshal(impact)
+		if err != nil {
+			return errorClient.ClientError(500, err)
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: statusCode,
+			Body:       string(responseBody),
+			Headers:    utils.ResponseHeaders(),
+		}, nil
+	case http.MethodDelete:
+		switch path {
+		case fmt.Sprintf("/aircraft/%s/maintenance/%s", registration, blockId):
+			err := maintenanceApi.Delete(registration, blockId)
+			errorClient.SetLogger(maintenanceApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNoContent,
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	}
+
+	return errorClient.ClientError(400, errors.New("bad request"))
+}
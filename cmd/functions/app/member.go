@@ -0,0 +1,140 @@
+package main
+
+import (
+	"aviator/member"
+	"aviator/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// memberCrud is a router to route API routes to the correct backend method
+func memberCrud(ctx context.Context, request events.APIGatewayProxyRequest, path string, stage string,
+	memberApi member.MemberApiInterface, errorClient utils.ApiErrorClient) (events.APIGatewayProxyResponse, error) {
+	memberId := request.PathParameters["memberId"]
+
+	var responseBody []byte
+	switch request.HTTPMethod {
+	case http.MethodGet:
+		switch path {
+		case "/members":
+			var input member.ListInput
+			queryParams := request.QueryStringParameters
+			limitString, ok := queryParams["limit"]
+			if ok {
+				i, err := strconv.ParseInt(limitString, 10, 64)
+				if err != nil {
+					return errorClient.ClientError(400, errors.New("Invalid limit"))
+				}
+				input.Limit = aws.Int32(int32(i))
+			}
+
+			nextTokenStr, ok := queryParams["nextToken"]
+			if ok {
+				input.NextToken = &nextTokenStr
+			}
+
+			members, err := memberApi.List(input)
+			errorClient.SetLogger(memberApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(members)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		case fmt.Sprintf("/members/%s", memberId):
+			member, err := memberApi.Get(memberId)
+			errorClient.SetLogger(memberApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(member)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	case http.MethodPost:
+		switch path {
+		case "/members":
+			var requestBody member.Member
+			err := json.Unmarshal([]byte(request.Body), &requestBody)
+			if err != nil {
+				return errorClient.ClientError(400, err)
+			}
+
+			member, err := memberApi.CreateOrUpdate(requestBody)
+			errorClient.SetLogger(memberApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+
+			responseBody, err = json.Marshal(member)
+			if err != nil {
+				return errorClient.ClientError(500, err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusCreated,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	case http.MethodPut:
+		switch path {
+		case fmt.Sprintf("/members/%s", memberId):
+			var requestBody member.Member
+			err := json.Unmarshal([]byte(request.Body), &requestBody)
+			if err != nil {
+				return errorClient.ClientError(400, err)
+			}
+			requestBody.Id = memberId
+
+			member, err := memberApi.CreateOrUpdate(requestBody)
+			errorClient.SetLogger(memberApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+
+			responseBody, err = json.Marshal(member)
+			if err != nil {
+				return errorClient.ClientError(500, err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	case http.MethodDelete:
+		switch path {
+		case fmt.Sprintf("/members/%s", memberId):
+			err := memberApi.Delete(memberId)
+			errorClient.SetLogger(memberApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNoContent,
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	}
+
+	return errorClient.ClientError(400, errors.New("bad request"))
+}
@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go/aws"
@@ -18,6 +20,9 @@ import (
 func reservationCrud(ctx context.Context, request events.APIGatewayProxyRequest, path string, stage string,
 	reservationApi reservation.ReservationApiInterface, errorClient utils.ApiErrorClient) (events.APIGatewayProxyResponse, error) {
 	reservationId := request.PathParameters["reservationId"]
+	seriesId := request.PathParameters["seriesId"]
+	// Scope of an edit of an occurrence of a series: this (default), following or all
+	scope := request.QueryStringParameters["scope"]
 
 	var responseBody []byte
 	switch request.HTTPMethod {
@@ -43,6 +48,40 @@ func reservationCrud(ctx context.Context, request events.APIGatewayProxyRequest,
 				input.NextToken = &nextTokenStr
 			}
 
+			for param, filter := range map[string]**string{
+				"aircraft":        &input.Aircraft,
+				"resource":        &input.Resource,
+				"booker":          &input.Booker,
+				"pilot":           &input.Pilot,
+				"instructor":      &input.Instructor,
+				"instructorPlus":  &input.InstructorPlus,
+				"reservationType": &input.ReservationType,
+				"status":          &input.Status,
+			} {
+				value, ok := queryParams[param]
+				if ok {
+					*filter = aws.String(value)
+				}
+			}
+
+			startString, ok := queryParams["start"]
+			if ok {
+				start, err := parseTimeParameter(startString)
+				if err != nil {
+					return errorClient.ClientError(400, errors.New("Invalid start"))
+				}
+				input.Start = &start
+			}
+
+			endString, ok := queryParams["end"]
+			if ok {
+				end, err := parseTimeParameter(endString)
+				if err != nil {
+					return errorClient.ClientError(400, errors.New("Invalid end"))
+				}
+				input.End = &end
+			}
+
 			reservations, err := reservationApi.List(input)
 			errorClient.SetLogger(reservationApi.Logger())
 			if err != nil {
@@ -57,6 +96,42 @@ func reservationCrud(ctx context.Context, request events.APIGatewayProxyRequest,
 				Body:       string(responseBody),
 				Headers:    utils.ResponseHeaders(),
 			}, nil
+		case fmt.Sprintf("/reservations/series/%s", seriesId):
+			series, err := reservationApi.GetSeries(seriesId)
+			errorClient.SetLogger(reservationApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(series)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+		case fmt.Sprintf("/reservations/%s/history", reservationId):
+			var input reservation.HistoryInput
+			queryParams := request.QueryStringParameters
+			limitString, ok := queryParams["limit"]
+			if ok {
+				limit, err := strconv.ParseInt(limitString, 10, 32)
+				if err != nil {
+					return errorClient.ClientError(400, errors.New("Invalid limit"))
+				}
+				input.Limit = aws.Int32(int32(limit))
+			}
+
+			nextTokenStr, ok := queryParams["nextToken"]
+			if ok {
+				input.NextToken = &nextTokenStr
+			}
+
+			history, err := reservationApi.History(reservationId, input)
+			errorClient.SetLogger(reservationApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(history)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
 		case fmt.Sprintf("/reservations/%s", reservationId):
 			reservation, err := reservationApi.Get(reservationId)
 			errorClient.SetLogger(reservationApi.Logger())
@@ -67,6 +142,11 @@ func reservationCrud(ctx context.Context, request events.APIGatewayProxyRequest,
 			if err != nil {
 				return errorClient.AwsError(err)
 			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    versionHeaders(reservation.Version),
+			}, nil
 		}
 		return events.APIGatewayProxyResponse{
 			StatusCode: http.StatusOK,
@@ -94,16 +174,100 @@ func reservationCrud(ctx context.Context, request events.APIGatewayProxyRequest,
 				return errorClient.ClientError(500, err)
 			}
 
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusCreated,
+				Body:       string(responseBody),
+				Headers:    versionHeaders(reservation.Version),
+			}, nil
+		case "/reservations/series":
+			b := []byte(request.Body)
+			var requestBody reservation.SeriesInput
+			err := json.Unmarshal(b, &requestBody)
+			if err != nil {
+				return errorClient.ClientError(400, err)
+			}
+
+			series, err := reservationApi.CreateSeries(requestBody)
+			errorClient.SetLogger(reservationApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+
+			responseBody, err = json.Marshal(series)
+			if err != nil {
+				return errorClient.ClientError(500, err)
+			}
 			return events.APIGatewayProxyResponse{
 				StatusCode: http.StatusCreated,
 				Body:       string(responseBody),
 				Headers:    utils.ResponseHeaders(),
 			}, nil
+		case fmt.Sprintf("/reservations/%s/confirm", reservationId),
+			fmt.Sprintf("/reservations/%s/cancel", reservationId),
+			fmt.Sprintf("/reservations/%s/check-out", reservationId),
+			fmt.Sprintf("/reservations/%s/check-in", reservationId),
+			fmt.Sprintf("/reservations/%s/no-show", reservationId):
+			// The body is optional, only cancellations require a reason
+			var requestBody reservation.TransitionInput
+			if strings.TrimSpace(request.Body) != "" {
+				err := json.Unmarshal([]byte(request.Body), &requestBody)
+				if err != nil {
+					return errorClient.ClientError(400, err)
+				}
+			}
+			requestBody.Operation = path[strings.LastIndex(path, "/")+1:]
+
+			// If-Match takes precedence over the version of the body
+			version, err := ifMatchVersion(request)
+			if err != nil {
+				return errorClient.ClientError(400, err)
+			}
+			if version != 0 {
+				requestBody.Version = &version
+			}
+
+			if requestBody.Operation == string(reservation.OPERATION_CANCEL) && scope != "" && scope != reservation.SCOPE_THIS {
+				series, err := reservationApi.CancelSeries(reservationId, scope, requestBody)
+				errorClient.SetLogger(reservationApi.Logger())
+				if err != nil {
+					return errorClient.AwsError(err)
+				}
+				responseBody, err = json.Marshal(series)
+				if err != nil {
+					return errorClient.ClientError(500, err)
+				}
+				return events.APIGatewayProxyResponse{
+					StatusCode: http.StatusOK,
+					Body:       string(responseBody),
+					Headers:    utils.ResponseHeaders(),
+				}, nil
+			}
+
+			reservation, err := reservationApi.Transition(reservationId, requestBody)
+			errorClient.SetLogger(reservationApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+
+			responseBody, err = json.Marshal(reservation)
+			if err != nil {
+				return errorClient.ClientError(500, err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    versionHeaders(reservation.Version),
+			}, nil
 		}
 	case http.MethodDelete:
 		switch path {
 		case fmt.Sprintf("/reservations/%s", reservationId):
-			err := reservationApi.Delete(reservationId)
+			version, err := ifMatchVersion(request)
+			if err != nil {
+				return errorClient.ClientError(400, err)
+			}
+			err = reservationApi.Delete(reservationId, version)
+			errorClient.SetLogger(reservationApi.Logger())
 			if err != nil {
 				return errorClient.AwsError(err)
 			}
@@ -112,6 +276,58 @@ func reservationCrud(ctx context.Context, request events.APIGatewayProxyRequest,
 			StatusCode: http.StatusNoContent,
 			Headers:    utils.ResponseHeaders(),
 		}, nil
+	case http.MethodPatch:
+		switch path {
+		case fmt.Sprintf("/reservations/%s", reservationId):
+			b := []byte(request.Body)
+			var requestBody reservation.ReservationPatch
+			err := json.Unmarshal(b, &requestBody)
+			if err != nil {
+				return errorClient.ClientError(400, err)
+			}
+
+			// If-Match takes precedence over the version of the body
+			version, err := ifMatchVersion(request)
+			if err != nil {
+				return errorClient.ClientError(400, err)
+			}
+			if version != 0 {
+				requestBody.Version = &version
+			}
+
+			if scope != "" && scope != reservation.SCOPE_THIS {
+				series, err := reservationApi.PatchSeries(reservationId, scope, requestBody)
+				errorClient.SetLogger(reservationApi.Logger())
+				if err != nil {
+					return errorClient.AwsError(err)
+				}
+				responseBody, err = json.Marshal(series)
+				if err != nil {
+					return errorClient.ClientError(500, err)
+				}
+				return events.APIGatewayProxyResponse{
+					StatusCode: http.StatusOK,
+					Body:       string(responseBody),
+					Headers:    utils.ResponseHeaders(),
+				}, nil
+			}
+
+			reservation, err := reservationApi.Patch(reservationId, requestBody)
+			errorClient.SetLogger(reservationApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+
+			responseBody, err = json.Marshal(reservation)
+			if err != nil {
+				return errorClient.ClientError(500, err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    versionHeaders(reservation.Version),
+			}, nil
+		}
 	case http.MethodPut:
 		switch path {
 		case fmt.Sprintf("/reservations/%s", reservationId):
@@ -123,7 +339,17 @@ func reservationCrud(ctx context.Context, request events.APIGatewayProxyRequest,
 			}
 			requestBody.Id = reservationId
 
+			// If-Match takes precedence over the version of the body
+			version, err := ifMatchVersion(request)
+			if err != nil {
+				return errorClient.ClientError(400, err)
+			}
+			if version != 0 {
+				requestBody.Version = version
+			}
+
 			reservation, err := reservationApi.CreateOrUpdate(requestBody)
+			errorClient.SetLogger(reservationApi.Logger())
 			if err != nil {
 				return errorClient.AwsError(err)
 			}
@@ -135,7 +361,7 @@ func reservationCrud(ctx context.Context, request events.APIGatewayProxyRequest,
 			return events.APIGatewayProxyResponse{
 				StatusCode: http.StatusOK,
 				Body:       string(responseBody),
-				Headers:    utils.ResponseHeaders(),
+				Headers:    versionHeaders(reservation.Version),
 			}, nil
 		}
 		return events.APIGatewayProxyResponse{
@@ -147,3 +373,32 @@ func reservationCrud(ctx context.Context, request events.APIGatewayProxyRequest,
 
 	return errorClient.ClientError(400, errors.New("bad request"))
 }
+
+// parseTimeParameter parses a query parameter given either as a unix timestamp or as an RFC 3339 date
+func parseTimeParameter(value string) (time.Time, error) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// versionHeaders returns the response headers exposing the version of a reservation as its ETag
+func versionHeaders(version int) map[string]string {
+	headers := utils.ResponseHeaders()
+	headers["ETag"] = fmt.Sprintf("\"%d\"", version)
+	return headers
+}
+
+// ifMatchVersion returns the version required by the If-Match header of the request, 0 if any version matches
+func ifMatchVersion(request events.APIGatewayProxyRequest) (int, error) {
+	ifMatch := strings.TrimSpace(requestHeader(request, "If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), "\""))
+	if err != nil || version < 1 {
+		return 0, errors.New("Invalid If-Match")
+	}
+	return version, nil
+}
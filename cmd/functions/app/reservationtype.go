@@ -0,0 +1,140 @@
+package main
+
+import (
+	"aviator/reservationtype"
+	"aviator/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// reservationTypeCrud is a router to route API routes to the correct backend method
+func reservationTypeCrud(ctx context.Context, request events.APIGatewayProxyRequest, path string, stage string,
+	reservationTypeApi reservationtype.ReservationTypeApiInterface, errorClient utils.ApiErrorClient) (events.APIGatewayProxyResponse, error) {
+	name := request.PathParameters["name"]
+
+	var responseBody []byte
+	switch request.HTTPMethod {
+	case http.MethodGet:
+		switch path {
+		case "/reservation-types":
+			var input reservationtype.ListInput
+			queryParams := request.QueryStringParameters
+			limitString, ok := queryParams["limit"]
+			if ok {
+				i, err := strconv.ParseInt(limitString, 10, 64)
+				if err != nil {
+					return errorClient.ClientError(400, errors.New("Invalid limit"))
+				}
+				input.Limit = aws.Int32(int32(i))
+			}
+
+			nextTokenStr, ok := queryParams["nextToken"]
+			if ok {
+				input.NextToken = &nextTokenStr
+			}
+
+			reservationTypes, err := reservationTypeApi.List(input)
+			errorClient.SetLogger(reservationTypeApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(reservationTypes)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		case fmt.Sprintf("/reservation-types/%s", name):
+			reservationType, err := reservationTypeApi.Get(name)
+			errorClient.SetLogger(reservationTypeApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			responseBody, err = json.Marshal(reservationType)
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	case http.MethodPost:
+		switch path {
+		case "/reservation-types":
+			var requestBody reservationtype.ReservationType
+			err := json.Unmarshal([]byte(request.Body), &requestBody)
+			if err != nil {
+				return errorClient.ClientError(400, err)
+			}
+
+			reservationType, err := reservationTypeApi.Create(requestBody)
+			errorClient.SetLogger(reservationTypeApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+
+			responseBody, err = json.Marshal(reservationType)
+			if err != nil {
+				return errorClient.ClientError(500, err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusCreated,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	case http.MethodPut:
+		switch path {
+		case fmt.Sprintf("/reservation-types/%s", name):
+			var requestBody reservationtype.ReservationType
+			err := json.Unmarshal([]byte(request.Body), &requestBody)
+			if err != nil {
+				return errorClient.ClientError(400, err)
+			}
+			requestBody.Name = name
+
+			reservationType, err := reservationTypeApi.Update(requestBody)
+			errorClient.SetLogger(reservationTypeApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+
+			responseBody, err = json.Marshal(reservationType)
+			if err != nil {
+				return errorClient.ClientError(500, err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(responseBody),
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	case http.MethodDelete:
+		switch path {
+		case fmt.Sprintf("/reservation-types/%s", name):
+			err := reservationTypeApi.Delete(name)
+			errorClient.SetLogger(reservationTypeApi.Logger())
+			if err != nil {
+				return errorClient.AwsError(err)
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNoContent,
+				Headers:    utils.ResponseHeaders(),
+			}, nil
+		}
+	}
+
+	return errorClient.ClientError(400, errors.New("bad request"))
+}
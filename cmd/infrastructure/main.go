@@ -6,19 +6,32 @@ import (
 
 	"github.com/pulumi/pulumi-aws/sdk/v5/go/aws"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
 )
 
 func main() {
 	pulumi.Run(func(ctx *pulumi.Context) error {
+		conf := config.New(ctx, "")
+
 		databaseOut, err := database.Provision(ctx)
 		if err != nil {
 			return err
 		}
 
+		// Stacks without a JWKS document still deploy, but the Lambda rejects every request until jwksUrl is set
+		jwksUrl := conf.Get("jwksUrl")
+		if jwksUrl == "" {
+			ctx.Log.Warn("jwksUrl is not set, the API will reject every request", nil)
+		}
+
 		apiOut, err := api.Provision(ctx, api.Input{
 			OpenAPISpecPath:   "../../api.json",
 			DynamodbTableName: databaseOut.TableName,
 			DynamodbTableArn:  databaseOut.TableArn,
+			PlatformTenantId:  conf.Require("platformTenantId"),
+			JwksUrl:           jwksUrl,
+			JwtIssuer:         conf.Get("jwtIssuer"),
+			JwtAudience:       conf.Get("jwtAudience"),
 		})
 		if err != nil {
 			return err
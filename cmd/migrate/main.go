@@ -0,0 +1,68 @@
+/*
+Migrate rewrites the stored reservations of a club to the current key layout.
+
+Usage:
+
+	DYNAMODB_TABLE_NAME=aviator-table TENANT_ID=01HR9ZZNRFCKMAYNW3RY561QCP go run .
+*/
+package main
+
+import (
+	"aviator/constants"
+	"aviator/database"
+	"aviator/reservation"
+	"aviator/tenant"
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func main() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	conf, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		logger.Error("loading aws config failed", "error", err)
+		os.Exit(1)
+	}
+
+	databaseClient := database.NewFromConfig(
+		database.Config{
+			DynamoDbClient: dynamodb.NewFromConfig(conf),
+			TableName:      os.Getenv("DYNAMODB_TABLE_NAME"),
+		},
+	)
+
+	reservationClient := reservation.NewFromConfig(
+		reservation.Config{
+			Scope: tenant.Scope{
+				Logger:         logger,
+				DatabaseClient: *databaseClient,
+				TenantId:       os.Getenv("TENANT_ID"),
+				UserRole:       constants.ROLE_ADMIN,
+			},
+		},
+	)
+
+	total := 0
+	input := reservation.MigrateInput{Limit: aws.Int32(25)}
+	for {
+		output, err := reservationClient.Migrate(input)
+		if err != nil {
+			logger.Error("migration failed", "error", err, "migrated", total)
+			os.Exit(1)
+		}
+		total += output.Migrated
+
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	logger.Info("migration completed", "migrated", total)
+}
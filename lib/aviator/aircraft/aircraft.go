@@ -0,0 +1,311 @@
+/*
+Package aircraft provides methods for performing CRUD operations on the aircraft registry of a club.
+*/
+package aircraft
+
+import (
+	"aviator/constants"
+	"aviator/database"
+	"aviator/tenant"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const AIRCRAFT_PARTITION_KEY = "AIRCRAFT"
+
+// Aircraft can be reserved
+const STATUS_ACTIVE = "active"
+
+// Aircraft is kept in the registry but cannot be reserved anymore
+const STATUS_INACTIVE = "inactive"
+
+// The hourly rate includes the fuel
+const RATE_WET = "wet"
+
+// The hourly rate excludes the fuel, which is charged separately
+const RATE_DRY = "dry"
+
+type AircraftApiInterface interface {
+	Logger() *slog.Logger
+	SetLogger(logger *slog.Logger)
+	Create(input Aircraft) (*Aircraft, error)
+	Get(registration string) (*Aircraft, error)
+	List(input ListInput) (*ListOutput, error)
+	Update(input Aircraft) (*Aircraft, error)
+	Delete(registration string) error
+}
+
+type Config struct {
+	tenant.Scope
+}
+
+type Client struct {
+	Config
+}
+
+// Item used to store an aircraft
+type Aircraft struct {
+	// Registration, unique within a club: e.g. HB-KFQ
+	Registration string `json:"registration"`
+	// Aircraft type: e.g. DR40
+	AircraftType string `json:"aircraftType"`
+	// Number of seats, including the pilot
+	Seats int `json:"seats"`
+	// Rental price per flight hour: e.g. 230.5
+	HourlyRate float64 `json:"hourlyRate"`
+	// Either wet or dry, wet when empty
+	RateType string `json:"rateType"`
+	// Last Hobbs meter reading in hours, only moves forward with the flight logs: e.g. 1234.5
+	Hobbs float64 `json:"hobbs"`
+	// Last tachometer reading in hours, only moves forward with the flight logs: e.g. 987.6
+	Tach float64 `json:"tach"`
+	// Total flight time of the airframe in hours, increased by the Hobbs time of each flight log
+	TotalTime float64 `json:"totalTime"`
+	// Either active or inactive
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Database item to store the aircraft.
+type databaseItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key: e.g. AIRCRAFT#HB-KFQ
+	SK string
+
+	// Item type: aircraft
+	ItemType string
+	Aircraft
+}
+
+// Returns a new aircraft API client from the provided config.
+func NewFromConfig(c Config) *Client {
+	return &Client{Config: c}
+}
+
+func (c *Client) Logger() *slog.Logger {
+	return c.Config.Logger
+}
+
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.Config.Logger = logger
+}
+
+// Adds an aircraft to the registry
+func (c *Client) Create(input Aircraft) (*Aircraft, error) {
+	c.SetLogger(c.Logger().With("aircraft", input.Registration))
+	c.Logger().Info("creating aircraft")
+
+	if input.Status == "" {
+		input.Status = STATUS_ACTIVE
+	}
+
+	out, err := c.put(input, "attribute_not_exists(PK)")
+	if err != nil {
+		var conditionalCheckFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionalCheckFailed) {
+			return nil, AircraftAlreadyExistsError
+		}
+		return nil, err
+	}
+
+	c.Logger().Info("aircraft created")
+	return out, nil
+}
+
+// Replaces the stored data of an existing aircraft
+func (c *Client) Update(input Aircraft) (*Aircraft, error) {
+	c.SetLogger(c.Logger().With("aircraft", input.Registration))
+	c.Logger().Info("updating aircraft")
+
+	out, err := c.put(input, "attribute_exists(PK)")
+	if err != nil {
+		var conditionalCheckFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionalCheckFailed) {
+			return nil, AircraftNotFoundError
+		}
+		return nil, err
+	}
+
+	c.Logger().Info("aircraft updated")
+	return out, nil
+}
+
+func (c *Client) put(input Aircraft, conditionExpression string) (*Aircraft, error) {
+	if input.Status != STATUS_ACTIVE && input.Status != STATUS_INACTIVE {
+		return nil, AircraftInvalidStatusError
+	}
+	if input.Seats < 1 {
+		return nil, AircraftInvalidSeatsError
+	}
+	if input.HourlyRate < 0 {
+		return nil, AircraftInvalidHourlyRateError
+	}
+	if input.RateType == "" {
+		input.RateType = RATE_WET
+	}
+	if input.RateType != RATE_WET && input.RateType != RATE_DRY {
+		return nil, AircraftInvalidRateTypeError
+	}
+	if input.Hobbs < 0 || input.Tach < 0 || input.TotalTime < 0 {
+		return nil, AircraftInvalidMetersError
+	}
+
+	databaseItem := databaseItem{
+		PK:       c.clubPK(),
+		SK:       fmt.Sprintf("%s#%s", AIRCRAFT_PARTITION_KEY, input.Registration),
+		ItemType: "aircraft",
+		Aircraft: input,
+	}
+
+	out, err := c.DatabaseClient.Put(database.PutInput{
+		Item:                databaseItem,
+		ConditionExpression: aws.String(conditionExpression),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	input.CreatedAt = out.CreatedAt
+	input.UpdatedAt = out.UpdatedAt
+	return &input, nil
+}
+
+type ListInput struct {
+	NextToken *string
+	Limit     *int32
+}
+
+type ListOutput struct {
+	NextToken *string    `json:"nextToken"`
+	Results   []Aircraft `json:"results"`
+}
+
+// Returns stored data for all aircraft of the club.
+func (c *Client) List(input ListInput) (*ListOutput, error) {
+	c.Logger().Info("listing aircraft")
+
+	exclusiveStartKey, err := database.DecodeNextToken(input.NextToken, c.clubPK())
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.DatabaseClient.Query(&database.QueryInput{
+		Limit:                  input.Limit,
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: c.clubPK()},
+			":sk": &types.AttributeValueMemberS{Value: AIRCRAFT_PARTITION_KEY + "#"},
+		},
+		ExclusiveStartKey: exclusiveStartKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	aircraft := make([]Aircraft, 0)
+	err = attributevalue.UnmarshalListOfMaps(output.Items, &aircraft)
+	if err != nil {
+		return nil, err
+	}
+
+	nextToken, err := database.EncodeNextToken(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("aircraft listed", "count", len(aircraft), "isNextToken", nextToken != nil)
+	return &ListOutput{
+		NextToken: nextToken,
+		Results:   aircraft,
+	}, nil
+}
+
+// Returns stored data for an aircraft.
+func (c *Client) Get(registration string) (*Aircraft, error) {
+	c.SetLogger(c.Logger().With("aircraft", registration))
+	c.Logger().Info("retrieving aircraft")
+
+	output, err := c.DatabaseClient.Get(database.GetInput{
+		PK: c.clubPK(),
+		SK: fmt.Sprintf("%s#%s", AIRCRAFT_PARTITION_KEY, registration),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.Item == nil {
+		return nil, AircraftNotFoundError
+	}
+
+	aircraft := new(Aircraft)
+	err = attributevalue.UnmarshalMap(output.Item, aircraft)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("aircraft retrieved")
+	return aircraft, nil
+}
+
+// Builds the update recording the meter readings at the end of a flight, to be run inside the transaction writing the flight log.
+// The update only succeeds if the readings at the start of the flight are not lower than the stored ones,
+// so that meters only move forward even when flights are logged concurrently.
+func (c *Client) NewMeterUpdate(registration string, hobbsStart float64, hobbsEnd float64, tachStart float64, tachEnd float64) *types.Update {
+	return &types.Update{
+		TableName: aws.String(c.DatabaseClient.TableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: c.clubPK()},
+			"SK": &types.AttributeValueMemberS{Value: fmt.Sprintf("%s#%s", AIRCRAFT_PARTITION_KEY, registration)},
+		},
+		UpdateExpression: aws.String("SET #hobbs = :hobbsEnd, #tach = :tachEnd, " +
+			"#totalTime = if_not_exists(#totalTime, :zero) + :flightTime, #updatedAt = :now"),
+		ConditionExpression: aws.String("attribute_exists(PK) AND (attribute_not_exists(#hobbs) OR #hobbs <= :hobbsStart) " +
+			"AND (attribute_not_exists(#tach) OR #tach <= :tachStart)"),
+		ExpressionAttributeNames: map[string]string{
+			"#hobbs":     "Hobbs",
+			"#tach":      "Tach",
+			"#totalTime": "TotalTime",
+			"#updatedAt": "UpdatedAt",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":hobbsStart": &types.AttributeValueMemberN{Value: fmt.Sprint(hobbsStart)},
+			":hobbsEnd":   &types.AttributeValueMemberN{Value: fmt.Sprint(hobbsEnd)},
+			":tachStart":  &types.AttributeValueMemberN{Value: fmt.Sprint(tachStart)},
+			":tachEnd":    &types.AttributeValueMemberN{Value: fmt.Sprint(tachEnd)},
+			// Meters are read to the hundredth of an hour at most, rounding drops floating point noise
+			":flightTime": &types.AttributeValueMemberN{Value: fmt.Sprint(math.Round((hobbsEnd-hobbsStart)*100) / 100)},
+			":zero":       &types.AttributeValueMemberN{Value: "0"},
+			":now":        &types.AttributeValueMemberS{Value: time.Now().Format("2006-01-02T15:04:05.000Z")},
+		},
+	}
+}
+
+// Removes an aircraft from the registry. Deactivating it keeps its history.
+func (c *Client) Delete(registration string) error {
+	c.SetLogger(c.Logger().With("aircraft", registration))
+	c.Logger().Info("deleting aircraft")
+
+	_, err := c.DatabaseClient.Delete(&database.DeleteInput{
+		PK: c.clubPK(),
+		SK: fmt.Sprintf("%s#%s", AIRCRAFT_PARTITION_KEY, registration),
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Logger().Info("aircraft deleted")
+	return nil
+}
+
+// Returns the partition key of the tenant club owning the aircraft.
+func (c *Client) clubPK() string {
+	return fmt.Sprintf("%s#%s", constants.CLUB_PARTITION_KEY, c.TenantId)
+}
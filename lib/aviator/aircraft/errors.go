@@ -0,0 +1,66 @@
+package aircraft
+
+import "aviator/errors"
+
+var AircraftNotFoundError = errors.AviatorError{
+	Id: "aircraft_not_found",
+	Message: errors.Message{
+		EN: "The selected aircraft does not exist",
+		FR: "L'appareil sélectionné n'existe pas",
+	},
+	ApiError: 404,
+}
+
+var AircraftAlreadyExistsError = errors.AviatorError{
+	Id: "aircraft_already_exists",
+	Message: errors.Message{
+		EN: "An aircraft with this registration already exists",
+		FR: "Un appareil avec cette immatriculation existe déjà",
+	},
+	ApiError: 409,
+}
+
+var AircraftInvalidStatusError = errors.AviatorError{
+	Id: "aircraft_invalid_status",
+	Message: errors.Message{
+		EN: "The status of an aircraft must be active or inactive",
+		FR: "Le statut d'un appareil doit être actif ou inactif",
+	},
+	ApiError: 400,
+}
+
+var AircraftInvalidSeatsError = errors.AviatorError{
+	Id: "aircraft_invalid_seats",
+	Message: errors.Message{
+		EN: "An aircraft must have at least one seat",
+		FR: "Un appareil doit avoir au moins un siège",
+	},
+	ApiError: 400,
+}
+
+var AircraftInvalidHourlyRateError = errors.AviatorError{
+	Id: "aircraft_invalid_hourly_rate",
+	Message: errors.Message{
+		EN: "The hourly rate of an aircraft cannot be negative",
+		FR: "Le tarif horaire d'un appareil ne peut pas être négatif",
+	},
+	ApiError: 400,
+}
+
+var AircraftInvalidMetersError = errors.AviatorError{
+	Id: "aircraft_invalid_meters",
+	Message: errors.Message{
+		EN: "The meter readings and the total time of an aircraft cannot be negative",
+		FR: "Les relevés des compteurs et le temps total d'un appareil ne peuvent pas être négatifs",
+	},
+	ApiError: 400,
+}
+
+var AircraftInvalidRateTypeError = errors.AviatorError{
+	Id: "aircraft_invalid_rate_type",
+	Message: errors.Message{
+		EN: "The rate type of an aircraft must be wet or dry",
+		FR: "Le type de tarif d'un appareil doit être avec ou sans carburant",
+	},
+	ApiError: 400,
+}
@@ -0,0 +1,245 @@
+/*
+Package auth provides methods for authenticating the callers of the API with signed JSON Web Tokens.
+
+Tokens must be signed with RS256 or ES256 by one of the keys of the configured JWKS document.
+*/
+package auth
+
+import (
+	"aviator/club"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Claim holding the club of the caller when none is configured
+const DEFAULT_TENANT_CLAIM = "custom:tenantId"
+
+// Claim holding the role of the caller when none is configured
+const DEFAULT_ROLE_CLAIM = "custom:role"
+
+// Minimum delay between two fetches of the JWKS document when a token is signed by an unknown key
+const JWKS_REFRESH_INTERVAL = time.Minute
+
+type AuthApiInterface interface {
+	Logger() *slog.Logger
+	SetLogger(logger *slog.Logger)
+	Authenticate(authorization string) (*Identity, error)
+}
+
+type Config struct {
+	Logger *slog.Logger
+	// JWKS document read from the filesystem, takes precedence over the url: e.g. ./jwks.json
+	JwksFile string
+	// JWKS document endpoint: e.g. https://cognito-idp.eu-central-1.amazonaws.com/<pool>/.well-known/jwks.json
+	JwksUrl string
+	// Expected iss claim, not checked if empty
+	Issuer string
+	// Expected aud claim, not checked if empty
+	Audience    string
+	TenantClaim string
+	RoleClaim   string
+}
+
+type Client struct {
+	Config
+	mutex     sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// Authenticated caller of the API
+type Identity struct {
+	// Club of the caller: e.g. 01HR9ZZNRFCKMAYNW3RY561QCP
+	TenantId string
+	// Subject of the token: e.g. 01H55420KY47HRVVPK1Z3BSACK
+	UserId string
+	// Role of the caller within the club: e.g. pilot
+	UserRole string
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Returns a new auth API client from the provided config.
+func NewFromConfig(c Config) *Client {
+	if c.TenantClaim == "" {
+		c.TenantClaim = DEFAULT_TENANT_CLAIM
+	}
+	if c.RoleClaim == "" {
+		c.RoleClaim = DEFAULT_ROLE_CLAIM
+	}
+	return &Client{Config: c}
+}
+
+func (c *Client) Logger() *slog.Logger {
+	return c.Config.Logger
+}
+
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.Config.Logger = logger
+}
+
+// Verifies the bearer token of an Authorization header and returns the identity of the caller.
+func (c *Client) Authenticate(authorization string) (*Identity, error) {
+	scheme, token, found := strings.Cut(strings.TrimSpace(authorization), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return nil, MissingTokenError
+	}
+
+	claims, err := c.verify(strings.TrimSpace(token))
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{}
+	identity.UserId, _ = claims["sub"].(string)
+	identity.TenantId, _ = claims[c.TenantClaim].(string)
+	identity.UserRole, _ = claims[c.RoleClaim].(string)
+	if identity.UserId == "" || !club.ValidTenantId(identity.TenantId) {
+		c.Logger().Warn("token without valid subject or tenant")
+		return nil, InvalidTokenError
+	}
+
+	return identity, nil
+}
+
+// Checks the signature and validity period of a compact JWS token and returns its claims.
+func (c *Client) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, InvalidTokenError
+	}
+
+	var tokenHeader header
+	if err := decodeSegment(parts[0], &tokenHeader); err != nil {
+		return nil, InvalidTokenError
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, InvalidTokenError
+	}
+
+	key, err := c.key(tokenHeader.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !verifySignature(tokenHeader.Alg, key, digest[:], signature) {
+		c.Logger().Warn("invalid token signature", "alg", tokenHeader.Alg, "kid", tokenHeader.Kid)
+		return nil, InvalidTokenError
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, InvalidTokenError
+	}
+
+	now := time.Now()
+	expiresAt, ok := numericDate(claims["exp"])
+	if !ok {
+		return nil, InvalidTokenError
+	}
+	if !now.Before(expiresAt) {
+		return nil, ExpiredTokenError
+	}
+	if notBefore, ok := numericDate(claims["nbf"]); ok && now.Before(notBefore) {
+		return nil, InvalidTokenError
+	}
+	if c.Issuer != "" && claims["iss"] != c.Issuer {
+		return nil, InvalidTokenError
+	}
+	if c.Audience != "" && !hasAudience(claims["aud"], c.Audience) {
+		return nil, InvalidTokenError
+	}
+
+	return claims, nil
+}
+
+// Returns the public key with the given id, fetching the JWKS document again if the key is unknown.
+func (c *Client) key(kid string) (crypto.PublicKey, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+
+	if c.keys == nil || time.Since(c.fetchedAt) > JWKS_REFRESH_INTERVAL {
+		keys, err := c.loadKeys()
+		if err != nil {
+			// The caller cannot be authenticated without keys, the token is not at fault
+			return nil, err
+		}
+		c.keys = keys
+		c.fetchedAt = time.Now()
+	}
+
+	key, ok := c.keys[kid]
+	if !ok {
+		c.Logger().Warn("token signed by an unknown key", "kid", kid)
+		return nil, InvalidTokenError
+	}
+	return key, nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, digest []byte, signature []byte) bool {
+	switch alg {
+	case "RS256":
+		publicKey, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest, signature) == nil
+	case "ES256":
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		// JWS ECDSA signatures are the concatenation of r and s, 32 bytes each for P-256
+		if !ok || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(publicKey, digest, r, s)
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	bytes, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, v)
+}
+
+func numericDate(claim interface{}) (time.Time, bool) {
+	seconds, ok := claim.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+// The aud claim is either a single string or an array of strings.
+func hasAudience(claim interface{}, audience string) bool {
+	switch aud := claim.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, value := range aud {
+			if value == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package auth
+
+import "aviator/errors"
+
+var MissingTokenError = errors.AviatorError{
+	Id: "auth_missing_token",
+	Message: errors.Message{
+		EN: "You must be signed in to perform this action",
+		FR: "Vous devez être connecté pour effectuer cette action",
+	},
+	ApiError: 401,
+}
+
+var InvalidTokenError = errors.AviatorError{
+	Id: "auth_invalid_token",
+	Message: errors.Message{
+		EN: "The provided access token is invalid",
+		FR: "Le jeton d'accès fourni n'est pas valide",
+	},
+	ApiError: 401,
+}
+
+var ExpiredTokenError = errors.AviatorError{
+	Id: "auth_expired_token",
+	Message: errors.Message{
+		EN: "The provided access token has expired",
+		FR: "Le jeton d'accès fourni a expiré",
+	},
+	ApiError: 401,
+}
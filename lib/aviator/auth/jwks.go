@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"time"
+)
+
+// JSON Web Key as published in a JWKS document (RFC 7517)
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA modulus and exponent
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC curve and coordinates
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// Loads the JWKS document from a file if configured, otherwise from its URL.
+// Keys that are not RSA or P-256 EC signing keys are ignored.
+func (c *Client) loadKeys() (map[string]crypto.PublicKey, error) {
+	var document []byte
+	var err error
+	if c.JwksFile != "" {
+		document, err = os.ReadFile(c.JwksFile)
+	} else if c.JwksUrl != "" {
+		document, err = fetch(c.JwksUrl)
+	} else {
+		err = fmt.Errorf("no JWKS file or url configured")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keySet jsonWebKeySet
+	err = json.Unmarshal(document, &keySet)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, key := range keySet.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.publicKey()
+		if err != nil {
+			c.Logger().Warn("ignoring invalid JWKS key", "kid", key.Kid, "error", err)
+			continue
+		}
+		keys[key.Kid] = publicKey
+	}
+	return keys, nil
+}
+
+func fetch(url string) ([]byte, error) {
+	httpClient := http.Client{Timeout: 5 * time.Second}
+	response, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching JWKS from %s: %s", url, response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !publicKey.Curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve %s", k.Crv)
+		}
+		return publicKey, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(bytes), nil
+}
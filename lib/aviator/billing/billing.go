@@ -0,0 +1,301 @@
+/*
+Package billing provides methods for charging the flights of the members of a club and reading their statements.
+Charges are computed from the flight log of a completed reservation and are never modified once stored.
+*/
+package billing
+
+import (
+	"aviator/aircraft"
+	"aviator/club"
+	"aviator/constants"
+	"aviator/database"
+	"aviator/flightlog"
+	"aviator/reservationtype"
+	"aviator/tenant"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const CHARGE_PARTITION_KEY = "CHARGE"
+
+// Currency of all amounts
+const CURRENCY = "CHF"
+
+// Format of the UTC times used in keys, sortable as strings
+const TIME_KEY_FORMAT = "2006-01-02T15:04:05Z"
+
+// Flight time at the hourly rate of the aircraft
+const KIND_AIRCRAFT = "aircraft"
+
+// Block time at the instructor hourly rate of the reservation type
+const KIND_INSTRUCTION = "instruction"
+
+// Landings at the landing fee of the club
+const KIND_LANDING = "landing"
+
+// Fuel uplifted at the club on a dry rate
+const KIND_FUEL = "fuel"
+
+// Fuel paid by the pilot on a wet rate, credited back
+const KIND_FUEL_CREDIT = "fuel-credit"
+
+type BillingApiInterface interface {
+	Logger() *slog.Logger
+	SetLogger(logger *slog.Logger)
+	Statement(memberId string, input StatementInput) (*Statement, error)
+}
+
+type Config struct {
+	tenant.Scope
+}
+
+type Client struct {
+	Config
+}
+
+// Item used to store a charge line of a member
+type Charge struct {
+	// Member Id of the charged member: e.g. 01H55420KY47HRVVPK1Z3BSACK
+	MemberId string `json:"memberId"`
+	// Id of the charged reservation: e.g. 01HRC8J5Q2W7E4R6T8Y0U1I3OP
+	ReservationId string `json:"reservationId"`
+	// Registration of the flown aircraft: e.g. HB-KFQ
+	Aircraft string `json:"aircraft"`
+	// Either aircraft, instruction, landing, fuel or fuel-credit
+	Kind string `json:"kind"`
+	// Charged hours, landings or litres: e.g. 1.2
+	Quantity float64 `json:"quantity"`
+	// Price of one hour, landing or litre: e.g. 230.5
+	UnitPrice float64 `json:"unitPrice"`
+	// Amount in centimes, negative for credits: e.g. 27660
+	Amount int64 `json:"amount"`
+	// Date of the flight, the block off time
+	Date      time.Time `json:"date"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Database item to store the charge.
+type databaseItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key, ordered by date: e.g. CHARGE#01H55420KY47HRVVPK1Z3BSACK#2024-04-07T16:05:00Z#01HRC8J5Q2W7E4R6T8Y0U1I3OP#aircraft
+	SK string
+
+	// Item type: charge
+	ItemType string
+	Charge
+}
+
+// Returns a new billing API client from the provided config.
+func NewFromConfig(c Config) *Client {
+	return &Client{Config: c}
+}
+
+func (c *Client) Logger() *slog.Logger {
+	return c.Config.Logger
+}
+
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.Config.Logger = logger
+}
+
+// Returns the price of a quantity in centimes.
+func amount(quantity float64, unitPrice float64) int64 {
+	return int64(math.Round(quantity * unitPrice * 100))
+}
+
+// Rounds hours to the hundredth, the precision of the meters.
+func roundHours(hours float64) float64 {
+	return math.Round(hours*100) / 100
+}
+
+// Computes the charges of the pilot for a flight log, from the rates of the aircraft, of the reservation type and of the club.
+// Charges with a zero amount are left out.
+func (c *Client) Compute(flightLog flightlog.FlightLog, reservationType string) ([]Charge, error) {
+	aircraftClient := aircraft.NewFromConfig(aircraft.Config{Scope: c.Scope})
+	flownAircraft, err := aircraftClient.Get(flightLog.Aircraft)
+	if err != nil {
+		return nil, err
+	}
+
+	instructorRate := 0.0
+	if flightLog.Instructor != nil {
+		reservationTypeClient := reservationtype.NewFromConfig(reservationtype.Config{Scope: c.Scope})
+		flownType, err := reservationTypeClient.Get(reservationType)
+		if err != nil && !errors.Is(err, reservationtype.ReservationTypeNotFoundError) {
+			return nil, err
+		}
+		if flownType != nil {
+			instructorRate = flownType.InstructorHourlyRate
+		}
+	}
+
+	clubClient := club.NewFromConfig(club.Config{Scope: c.Scope})
+	landingFee, fuelPrice := 0.0, 0.0
+	flightClub, err := clubClient.Get(c.TenantId)
+	if err != nil && !errors.Is(err, club.ClubNotFoundError) {
+		return nil, err
+	}
+	if flightClub != nil {
+		landingFee, fuelPrice = flightClub.LandingFee, flightClub.FuelPrice
+	}
+	return computeCharges(flightLog, *flownAircraft, instructorRate, landingFee, fuelPrice), nil
+}
+
+// Returns the charges of the pilot for a flight log at the given rates, leaving out the ones with a zero amount.
+// The aircraft is charged by flight time and the instructor by block time. Fuel is charged on a dry rate
+// unless paid by the pilot, and credited back on a wet rate when paid by the pilot.
+func computeCharges(flightLog flightlog.FlightLog, flownAircraft aircraft.Aircraft, instructorRate float64,
+	landingFee float64, fuelPrice float64) []Charge {
+	charge := func(kind string, quantity float64, unitPrice float64) Charge {
+		return Charge{
+			MemberId:      flightLog.Pilot,
+			ReservationId: flightLog.ReservationId,
+			Aircraft:      flightLog.Aircraft,
+			Kind:          kind,
+			Quantity:      quantity,
+			UnitPrice:     unitPrice,
+			Amount:        amount(quantity, unitPrice),
+			Date:          flightLog.BlockOff,
+		}
+	}
+	candidates := []Charge{
+		charge(KIND_AIRCRAFT, roundHours(flightLog.FlightTime()), flownAircraft.HourlyRate),
+		charge(KIND_LANDING, float64(flightLog.Landings), landingFee),
+	}
+	if flightLog.Instructor != nil {
+		candidates = append(candidates, charge(KIND_INSTRUCTION, roundHours(flightLog.BlockOn.Sub(flightLog.BlockOff).Hours()), instructorRate))
+	}
+	if flownAircraft.RateType == aircraft.RATE_DRY && !flightLog.FuelPaidByPilot {
+		candidates = append(candidates, charge(KIND_FUEL, flightLog.FuelUplift, fuelPrice))
+	}
+	if flownAircraft.RateType != aircraft.RATE_DRY && flightLog.FuelPaidByPilot {
+		fuelCredit := charge(KIND_FUEL_CREDIT, flightLog.FuelUplift, fuelPrice)
+		fuelCredit.Amount = -fuelCredit.Amount
+		candidates = append(candidates, fuelCredit)
+	}
+
+	charges := make([]Charge, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.Amount != 0 {
+			charges = append(charges, candidate)
+		}
+	}
+	return charges
+}
+
+// Builds the puts of new charges, to be run inside the transaction checking the reservation in.
+// Charges are immutable: a charge can only be written once.
+func (c *Client) NewPuts(charges []Charge) ([]types.TransactWriteItem, error) {
+	now := time.Now().UTC()
+	transactItems := make([]types.TransactWriteItem, 0, len(charges))
+	for _, charge := range charges {
+		charge.CreatedAt = now
+		item, err := attributevalue.MarshalMap(databaseItem{
+			PK: c.clubPK(),
+			SK: fmt.Sprintf("%s#%s#%s#%s#%s", CHARGE_PARTITION_KEY, charge.MemberId,
+				charge.Date.UTC().Format(TIME_KEY_FORMAT), charge.ReservationId, charge.Kind),
+			ItemType: "charge",
+			Charge:   charge,
+		})
+		if err != nil {
+			return nil, err
+		}
+		transactItems = append(transactItems, types.TransactWriteItem{
+			Put: &types.Put{
+				TableName:           aws.String(c.DatabaseClient.TableName),
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(PK)"),
+			},
+		})
+	}
+	return transactItems, nil
+}
+
+type StatementInput struct {
+	// Only return charges of flights between Start and End, both are required
+	Start time.Time
+	End   time.Time
+}
+
+// Charges of a member over a date range
+type Statement struct {
+	MemberId string    `json:"memberId"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Currency string    `json:"currency"`
+	Charges  []Charge  `json:"charges"`
+	// Sum of the amounts of the charges in centimes
+	Total int64 `json:"total"`
+}
+
+// Returns the charges of a member for the flights between the start and the end of the input, ordered by date.
+// Admins can read every statement, other members only their own.
+func (c *Client) Statement(memberId string, input StatementInput) (*Statement, error) {
+	c.SetLogger(c.Logger().With("member", memberId))
+	c.Logger().Info("computing statement")
+
+	if c.UserRole != constants.ROLE_ADMIN && memberId != c.UserId {
+		return nil, BillingAccessDenyError
+	}
+	if input.Start.IsZero() || input.End.IsZero() || input.End.Before(input.Start) {
+		return nil, BillingInvalidRangeError
+	}
+
+	statement := &Statement{
+		MemberId: memberId,
+		Start:    input.Start,
+		End:      input.End,
+		Currency: CURRENCY,
+		Charges:  make([]Charge, 0),
+	}
+	queryInput := database.QueryInput{
+		KeyConditionExpression: aws.String("PK = :pk AND SK BETWEEN :skStart AND :skEnd"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: c.clubPK()},
+			":skStart": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("%s#%s#%s", CHARGE_PARTITION_KEY, memberId, input.Start.UTC().Format(TIME_KEY_FORMAT)),
+			},
+			// "~" sorts after the "#<reservation>#<kind>" suffix, which makes the end of the range inclusive
+			":skEnd": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("%s#%s#%s~", CHARGE_PARTITION_KEY, memberId, input.End.UTC().Format(TIME_KEY_FORMAT)),
+			},
+		},
+	}
+	for {
+		output, err := c.DatabaseClient.Query(&queryInput)
+		if err != nil {
+			return nil, err
+		}
+
+		charges := make([]Charge, 0, len(output.Items))
+		err = attributevalue.UnmarshalListOfMaps(output.Items, &charges)
+		if err != nil {
+			return nil, err
+		}
+		for _, charge := range charges {
+			statement.Total += charge.Amount
+		}
+		statement.Charges = append(statement.Charges, charges...)
+
+		if len(output.LastEvaluatedKey) == 0 {
+			break
+		}
+		queryInput.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+
+	c.Logger().Info("statement computed", "count", len(statement.Charges), "total", statement.Total)
+	return statement, nil
+}
+
+// Returns the partition key of the tenant club owning the charges.
+func (c *Client) clubPK() string {
+	return fmt.Sprintf("%s#%s", constants.CLUB_PARTITION_KEY, c.TenantId)
+}
@@ -0,0 +1,160 @@
+package billing
+
+import (
+	"aviator/aircraft"
+	"aviator/flightlog"
+	"testing"
+	"time"
+)
+
+func TestAmount(t *testing.T) {
+	tests := []struct {
+		quantity  float64
+		unitPrice float64
+		want      int64
+	}{
+		{1.2, 230.5, 27660},
+		{3, 15, 4500},
+		{0.05, 1.9, 10},
+		{42.5, 0, 0},
+	}
+	for _, test := range tests {
+		if got := amount(test.quantity, test.unitPrice); got != test.want {
+			t.Errorf("amount(%v, %v) = %d, want %d", test.quantity, test.unitPrice, got, test.want)
+		}
+	}
+}
+
+func TestRoundHours(t *testing.T) {
+	tests := []struct {
+		hours float64
+		want  float64
+	}{
+		// Meter differences are not exact in floating point
+		{1235.73 - 1234.5, 1.23},
+		{1.0049, 1},
+		{1.005001, 1.01},
+		{0, 0},
+	}
+	for _, test := range tests {
+		if got := roundHours(test.hours); got != test.want {
+			t.Errorf("roundHours(%v) = %v, want %v", test.hours, got, test.want)
+		}
+	}
+}
+
+func TestComputeCharges(t *testing.T) {
+	instructor := "01HRB3T7C6M8N2Q4W9X5Y1Z0AB"
+	blockOff := time.Date(2024, 4, 7, 14, 50, 0, 0, time.UTC)
+	newFlightLog := func(landings int, fuelUplift float64, fuelPaidByPilot bool, instructor *string) flightlog.FlightLog {
+		return flightlog.FlightLog{
+			ReservationId:   "01HRC8J5Q2W7E4R6T8Y0U1I3OP",
+			Aircraft:        "HB-KFQ",
+			Pilot:           "01H55420KY47HRVVPK1Z3BSACK",
+			Instructor:      instructor,
+			HobbsStart:      1234.5,
+			HobbsEnd:        1235.73,
+			BlockOff:        blockOff,
+			BlockOn:         blockOff.Add(90 * time.Minute),
+			Landings:        landings,
+			FuelUplift:      fuelUplift,
+			FuelPaidByPilot: fuelPaidByPilot,
+		}
+	}
+	wet := aircraft.Aircraft{Registration: "HB-KFQ", HourlyRate: 200, RateType: aircraft.RATE_WET}
+	dry := aircraft.Aircraft{Registration: "HB-KFQ", HourlyRate: 160, RateType: aircraft.RATE_DRY}
+
+	tests := []struct {
+		name           string
+		flightLog      flightlog.FlightLog
+		aircraft       aircraft.Aircraft
+		instructorRate float64
+		landingFee     float64
+		want           []Charge
+	}{
+		{
+			name:       "wet rate, fuel paid by the club",
+			flightLog:  newFlightLog(3, 40, false, nil),
+			aircraft:   wet,
+			landingFee: 15,
+			want: []Charge{
+				{Kind: KIND_AIRCRAFT, Quantity: 1.23, UnitPrice: 200, Amount: 24600},
+				{Kind: KIND_LANDING, Quantity: 3, UnitPrice: 15, Amount: 4500},
+			},
+		},
+		{
+			name:       "wet rate, fuel paid by the pilot is credited back",
+			flightLog:  newFlightLog(1, 40, true, nil),
+			aircraft:   wet,
+			landingFee: 15,
+			want: []Charge{
+				{Kind: KIND_AIRCRAFT, Quantity: 1.23, UnitPrice: 200, Amount: 24600},
+				{Kind: KIND_LANDING, Quantity: 1, UnitPrice: 15, Amount: 1500},
+				{Kind: KIND_FUEL_CREDIT, Quantity: 40, UnitPrice: 2.5, Amount: -10000},
+			},
+		},
+		{
+			name:       "dry rate, fuel paid by the club is charged",
+			flightLog:  newFlightLog(1, 40, false, nil),
+			aircraft:   dry,
+			landingFee: 15,
+			want: []Charge{
+				{Kind: KIND_AIRCRAFT, Quantity: 1.23, UnitPrice: 160, Amount: 19680},
+				{Kind: KIND_LANDING, Quantity: 1, UnitPrice: 15, Amount: 1500},
+				{Kind: KIND_FUEL, Quantity: 40, UnitPrice: 2.5, Amount: 10000},
+			},
+		},
+		{
+			name:       "dry rate, fuel paid by the pilot",
+			flightLog:  newFlightLog(1, 40, true, nil),
+			aircraft:   dry,
+			landingFee: 15,
+			want: []Charge{
+				{Kind: KIND_AIRCRAFT, Quantity: 1.23, UnitPrice: 160, Amount: 19680},
+				{Kind: KIND_LANDING, Quantity: 1, UnitPrice: 15, Amount: 1500},
+			},
+		},
+		{
+			// The instructor is charged on the block time, 1.5 hours
+			name:           "with an instructor",
+			flightLog:      newFlightLog(2, 0, false, &instructor),
+			aircraft:       wet,
+			instructorRate: 80,
+			landingFee:     15,
+			want: []Charge{
+				{Kind: KIND_AIRCRAFT, Quantity: 1.23, UnitPrice: 200, Amount: 24600},
+				{Kind: KIND_LANDING, Quantity: 2, UnitPrice: 15, Amount: 3000},
+				{Kind: KIND_INSTRUCTION, Quantity: 1.5, UnitPrice: 80, Amount: 12000},
+			},
+		},
+		{
+			// No landing fee, no instructor rate and no fuel uplift
+			name:      "zero amounts are left out",
+			flightLog: newFlightLog(2, 0, false, &instructor),
+			aircraft:  dry,
+			want: []Charge{
+				{Kind: KIND_AIRCRAFT, Quantity: 1.23, UnitPrice: 160, Amount: 19680},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		got := computeCharges(test.flightLog, test.aircraft, test.instructorRate, test.landingFee, 2.5)
+		if len(got) != len(test.want) {
+			t.Errorf("%s: %d charges %+v, want %d", test.name, len(got), got, len(test.want))
+			continue
+		}
+		for i, charge := range got {
+			want := test.want[i]
+			if charge.Kind != want.Kind || charge.Quantity != want.Quantity || charge.UnitPrice != want.UnitPrice ||
+				charge.Amount != want.Amount {
+				t.Errorf("%s: charge %d = %s %v x %v = %d, want %s %v x %v = %d", test.name, i, charge.Kind,
+					charge.Quantity, charge.UnitPrice, charge.Amount, want.Kind, want.Quantity, want.UnitPrice, want.Amount)
+			}
+			if charge.MemberId != test.flightLog.Pilot || charge.ReservationId != test.flightLog.ReservationId ||
+				charge.Aircraft != test.flightLog.Aircraft || !charge.Date.Equal(blockOff) {
+				t.Errorf("%s: charge %d is not charged to the pilot of the flight: %+v", test.name, i, charge)
+			}
+		}
+	}
+}
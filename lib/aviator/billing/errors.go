@@ -0,0 +1,21 @@
+package billing
+
+import "aviator/errors"
+
+var BillingAccessDenyError = errors.AviatorError{
+	Id: "billing_access_deny",
+	Message: errors.Message{
+		EN: "Only admins can see the statements of other members",
+		FR: "Seuls les administrateurs peuvent voir les relevés des autres membres",
+	},
+	ApiError: 401,
+}
+
+var BillingInvalidRangeError = errors.AviatorError{
+	Id: "billing_invalid_range",
+	Message: errors.Message{
+		EN: "A statement requires a start and an end, and the start must be before the end",
+		FR: "Un relevé requiert un début et une fin, et le début doit précéder la fin",
+	},
+	ApiError: 400,
+}
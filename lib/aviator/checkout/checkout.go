@@ -0,0 +1,285 @@
+/*
+Package checkout provides methods for granting and revoking the aircraft checkouts of the members of a club.
+A pilot must be checked out on an aircraft, or on its aircraft type, before flying it without an instructor.
+*/
+package checkout
+
+import (
+	"aviator/aircraft"
+	"aviator/constants"
+	"aviator/database"
+	"aviator/member"
+	"aviator/tenant"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const CHECKOUT_PARTITION_KEY = "CHECKOUT"
+
+// Checkout targets, used in the sort keys
+const TARGET_AIRCRAFT = "AIRCRAFT"
+const TARGET_AIRCRAFT_TYPE = "AIRCRAFT_TYPE"
+
+type CheckoutApiInterface interface {
+	Logger() *slog.Logger
+	SetLogger(logger *slog.Logger)
+	Grant(input Checkout) (*Checkout, error)
+	List(memberId string, input ListInput) (*ListOutput, error)
+	Revoke(memberId string, target string, value string) error
+}
+
+type Config struct {
+	tenant.Scope
+}
+
+type Client struct {
+	Config
+}
+
+// Item used to store the checkout of a member on an aircraft or on an aircraft type
+type Checkout struct {
+	// Member Id of the checked out pilot: e.g. 01H55420KY47HRVVPK1Z3BSACK
+	MemberId string `json:"memberId"`
+	// Registration of the aircraft, empty for a checkout on an aircraft type: e.g. HB-KFQ
+	Aircraft string `dynamodbav:",omitempty" json:"aircraft,omitempty"`
+	// Aircraft type, empty for a checkout on a single aircraft: e.g. DR40
+	AircraftType string `dynamodbav:",omitempty" json:"aircraftType,omitempty"`
+	// Member Id of the instructor who granted the checkout, set from the authenticated caller
+	GrantedBy string `json:"grantedBy"`
+	// Date of the checkout, defaults to the time it is granted
+	GrantedAt time.Time `json:"grantedAt"`
+	// End of validity of the checkout (if any)
+	ExpiresAt *time.Time `dynamodbav:",omitempty" json:"expiresAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// Database item to store the checkout.
+type databaseItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key: e.g. CHECKOUT#01H55420KY47HRVVPK1Z3BSACK#AIRCRAFT#HB-KFQ or CHECKOUT#01H55420KY47HRVVPK1Z3BSACK#AIRCRAFT_TYPE#DR40
+	SK string
+
+	// Item type: checkout
+	ItemType string
+	Checkout
+}
+
+// Returns a new checkout API client from the provided config.
+func NewFromConfig(c Config) *Client {
+	return &Client{Config: c}
+}
+
+func (c *Client) Logger() *slog.Logger {
+	return c.Config.Logger
+}
+
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.Config.Logger = logger
+}
+
+// Returns true if the checkout is valid until the given time.
+func (ch Checkout) ValidUntil(t time.Time) bool {
+	return ch.ExpiresAt == nil || !ch.ExpiresAt.Before(t)
+}
+
+func (c *Client) checkoutSK(memberId string, target string, value string) string {
+	return fmt.Sprintf("%s#%s#%s#%s", CHECKOUT_PARTITION_KEY, memberId, target, value)
+}
+
+func (c *Client) canManage() bool {
+	return c.UserRole == constants.ROLE_ADMIN || c.UserRole == constants.ROLE_INSTRUCTOR
+}
+
+// Grants a checkout to a member, replacing any previous checkout on the same aircraft or aircraft type.
+// Only instructors and admins can grant checkouts.
+func (c *Client) Grant(input Checkout) (*Checkout, error) {
+	c.SetLogger(c.Logger().With("member", input.MemberId, "aircraft", input.Aircraft, "aircraftType", input.AircraftType))
+	c.Logger().Info("granting checkout")
+
+	if !c.canManage() {
+		return nil, CheckoutAccessDenyError
+	}
+	if (input.Aircraft == "") == (input.AircraftType == "") {
+		return nil, CheckoutInvalidTargetError
+	}
+	target, value := TARGET_AIRCRAFT, input.Aircraft
+	if input.AircraftType != "" {
+		target, value = TARGET_AIRCRAFT_TYPE, input.AircraftType
+	}
+
+	input.GrantedBy = c.UserId
+	if input.GrantedAt.IsZero() {
+		input.GrantedAt = time.Now().UTC()
+	}
+	if input.ExpiresAt != nil && input.ExpiresAt.Before(input.GrantedAt) {
+		return nil, CheckoutInvalidExpiryError
+	}
+
+	err := c.validate(input)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := c.DatabaseClient.Put(database.PutInput{
+		Item: databaseItem{
+			PK:       c.clubPK(),
+			SK:       c.checkoutSK(input.MemberId, target, value),
+			ItemType: "checkout",
+			Checkout: input,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	input.CreatedAt = out.CreatedAt
+	input.UpdatedAt = out.UpdatedAt
+	c.Logger().Info("checkout granted")
+	return &input, nil
+}
+
+// Checks that the member and the aircraft exist.
+func (c *Client) validate(input Checkout) error {
+	memberClient := member.NewFromConfig(member.Config{Scope: c.Scope})
+	_, err := memberClient.Get(input.MemberId)
+	if errors.Is(err, member.MemberNotFoundError) {
+		return CheckoutInvalidMemberError
+	}
+	if err != nil {
+		return err
+	}
+
+	if input.Aircraft == "" {
+		return nil
+	}
+	aircraftClient := aircraft.NewFromConfig(aircraft.Config{Scope: c.Scope})
+	_, err = aircraftClient.Get(input.Aircraft)
+	if errors.Is(err, aircraft.AircraftNotFoundError) {
+		return CheckoutInvalidAircraftError
+	}
+	return err
+}
+
+type ListInput struct {
+	NextToken *string
+	Limit     *int32
+}
+
+type ListOutput struct {
+	NextToken *string    `json:"nextToken"`
+	Results   []Checkout `json:"results"`
+}
+
+// Returns the checkouts of a member, including the expired ones.
+// Pilots and guests can only list their own checkouts.
+func (c *Client) List(memberId string, input ListInput) (*ListOutput, error) {
+	c.SetLogger(c.Logger().With("member", memberId))
+	c.Logger().Info("listing checkouts")
+
+	if !c.canManage() && memberId != c.UserId {
+		return nil, CheckoutAccessDenyError
+	}
+
+	exclusiveStartKey, err := database.DecodeNextToken(input.NextToken, c.clubPK())
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.DatabaseClient.Query(&database.QueryInput{
+		Limit:                  input.Limit,
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: c.clubPK()},
+			":sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("%s#%s#", CHECKOUT_PARTITION_KEY, memberId)},
+		},
+		ExclusiveStartKey: exclusiveStartKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	checkouts := make([]Checkout, 0)
+	err = attributevalue.UnmarshalListOfMaps(output.Items, &checkouts)
+	if err != nil {
+		return nil, err
+	}
+
+	nextToken, err := database.EncodeNextToken(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("checkouts listed", "count", len(checkouts), "isNextToken", nextToken != nil)
+	return &ListOutput{
+		NextToken: nextToken,
+		Results:   checkouts,
+	}, nil
+}
+
+// Revokes the checkout of a member on an aircraft (TARGET_AIRCRAFT) or on an aircraft type (TARGET_AIRCRAFT_TYPE).
+// Only instructors and admins can revoke checkouts.
+func (c *Client) Revoke(memberId string, target string, value string) error {
+	c.SetLogger(c.Logger().With("member", memberId, "target", target, "value", value))
+	c.Logger().Info("revoking checkout")
+
+	if !c.canManage() {
+		return CheckoutAccessDenyError
+	}
+	if target != TARGET_AIRCRAFT && target != TARGET_AIRCRAFT_TYPE {
+		return CheckoutInvalidTargetError
+	}
+
+	_, err := c.DatabaseClient.Delete(&database.DeleteInput{
+		PK: c.clubPK(),
+		SK: c.checkoutSK(memberId, target, value),
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Logger().Info("checkout revoked")
+	return nil
+}
+
+// Returns true if the member holds a checkout on the aircraft or on its type, valid until the given time.
+// Used to check reservations, so it is available to every caller.
+func (c *Client) IsCheckedOut(memberId string, reservedAircraft aircraft.Aircraft, until time.Time) (bool, error) {
+	for _, sk := range []string{
+		c.checkoutSK(memberId, TARGET_AIRCRAFT, reservedAircraft.Registration),
+		c.checkoutSK(memberId, TARGET_AIRCRAFT_TYPE, reservedAircraft.AircraftType),
+	} {
+		output, err := c.DatabaseClient.Get(database.GetInput{
+			PK: c.clubPK(),
+			SK: sk,
+		})
+		if err != nil {
+			return false, err
+		}
+		if output.Item == nil {
+			continue
+		}
+
+		checkout := new(Checkout)
+		err = attributevalue.UnmarshalMap(output.Item, checkout)
+		if err != nil {
+			return false, err
+		}
+		if checkout.ValidUntil(until) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Returns the partition key of the tenant club owning the checkout.
+func (c *Client) clubPK() string {
+	return fmt.Sprintf("%s#%s", constants.CLUB_PARTITION_KEY, c.TenantId)
+}
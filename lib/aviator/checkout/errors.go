@@ -0,0 +1,48 @@
+package checkout
+
+import "aviator/errors"
+
+var CheckoutAccessDenyError = errors.AviatorError{
+	Id: "checkout_access_deny",
+	Message: errors.Message{
+		EN: "Only instructors can grant or revoke aircraft checkouts, and pilots can only see their own",
+		FR: "Seuls les instructeurs peuvent accorder ou retirer un lâcher, et les pilotes ne peuvent voir que les leurs",
+	},
+	ApiError: 401,
+}
+
+var CheckoutInvalidTargetError = errors.AviatorError{
+	Id: "checkout_invalid_target",
+	Message: errors.Message{
+		EN: "A checkout is granted either on an aircraft or on an aircraft type",
+		FR: "Un lâcher est accordé soit sur un appareil soit sur un type d'appareil",
+	},
+	ApiError: 400,
+}
+
+var CheckoutInvalidMemberError = errors.AviatorError{
+	Id: "checkout_invalid_member",
+	Message: errors.Message{
+		EN: "The selected member does not exist",
+		FR: "Le membre sélectionné n'existe pas",
+	},
+	ApiError: 400,
+}
+
+var CheckoutInvalidAircraftError = errors.AviatorError{
+	Id: "checkout_invalid_aircraft",
+	Message: errors.Message{
+		EN: "The selected aircraft does not exist",
+		FR: "L'appareil sélectionné n'existe pas",
+	},
+	ApiError: 400,
+}
+
+var CheckoutInvalidExpiryError = errors.AviatorError{
+	Id: "checkout_invalid_expiry",
+	Message: errors.Message{
+		EN: "A checkout cannot expire before it is granted",
+		FR: "Un lâcher ne peut pas expirer avant d'être accordé",
+	},
+	ApiError: 400,
+}
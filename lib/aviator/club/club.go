@@ -0,0 +1,388 @@
+/*
+Package club provides methods for creating and configuring the clubs (tenants) of the platform.
+*/
+package club
+
+import (
+	"aviator/constants"
+	"aviator/database"
+	"aviator/member"
+	"aviator/tenant"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/oklog/ulid/v2"
+)
+
+// Granularity of the free slots of the club, in minutes, when it is not configured
+const DEFAULT_SLOT_MINUTES = 15
+
+// Format of the opening and closing times, in the timezone of the club
+const OPENING_TIME_FORMAT = "15:04"
+
+type ClubApiInterface interface {
+	Logger() *slog.Logger
+	SetLogger(logger *slog.Logger)
+	CreateOrUpdate(input Club) (*Club, error)
+	Get(clubId string) (*Club, error)
+	List(input ListInput) (*ListOutput, error)
+}
+
+type Config struct {
+	tenant.Scope
+	// Tenant operating the platform, the only one allowed to create and list clubs
+	PlatformTenantId string
+}
+
+type Client struct {
+	Config
+}
+
+// Time range during which the airfield is open on a day of the week
+type OpeningHours struct {
+	// Day of the week, 0 for Sunday: e.g. 1
+	Weekday time.Weekday `json:"weekday"`
+	// Opening time in the timezone of the club: e.g. 08:00
+	Open string `json:"open"`
+	// Closing time in the timezone of the club, 24:00 for midnight: e.g. 20:30
+	Close string `json:"close"`
+}
+
+// Item used to store a club
+type Club struct {
+	// Club Id, used as tenant Id: e.g. 01HR9ZZNRFCKMAYNW3RY561QCP
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// IANA timezone of the airfield: e.g. Europe/Zurich
+	Timezone string `json:"timezone"`
+	// Language of the messages returned to members: en or fr
+	Language string `json:"language"`
+	// Weekly opening hours of the airfield, always open when empty
+	OpeningHours []OpeningHours `json:"openingHours"`
+	// Granularity of the free slots in minutes, must divide a day: e.g. 30
+	SlotMinutes int `json:"slotMinutes"`
+	// Fee charged per landing: e.g. 15
+	LandingFee float64 `json:"landingFee"`
+	// Price of a litre of fuel, charged on dry rates and credited on wet rates when the pilot paid the fuel: e.g. 2.85
+	FuelPrice float64 `json:"fuelPrice"`
+	// Refuse new bookings of an aircraft whose estimated cost exceeds the prepaid balance of the pilot
+	PrepaidBookings bool `json:"prepaidBookings"`
+	// QR-IBAN of the account receiving the payments of the invoices: e.g. CH4431999123000889012
+	QrIban string `json:"qrIban"`
+	// Postal address of the club, printed on invoices as the creditor (if any)
+	Address   *member.Address `dynamodbav:",omitempty" json:"address"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// Database item to store the club, in the partition of the club itself.
+type databaseItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	SK string
+	// GSI1 primary key grouping all clubs: CLUB
+	GSI1PK string
+	// GSI1 sort key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	GSI1SK string
+
+	// Item type: club
+	ItemType string
+	GSIData  gsiData
+	Club
+}
+
+// Club attributes projected into GSI1
+type gsiData struct {
+	Name     string
+	Timezone string
+	Language string
+}
+
+// Returns a new club API client from the provided config.
+func NewFromConfig(c Config) *Client {
+	return &Client{Config: c}
+}
+
+func (c *Client) Logger() *slog.Logger {
+	return c.Config.Logger
+}
+
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.Config.Logger = logger
+}
+
+// Returns true if the IBAN is a valid Swiss or Liechtenstein QR-IBAN: its check digits are valid
+// and its institution identification is between 30000 and 31999.
+func ValidQrIban(iban string) bool {
+	if len(iban) != 21 || (iban[:2] != "CH" && iban[:2] != "LI") {
+		return false
+	}
+	iid, err := strconv.Atoi(iban[4:9])
+	if err != nil || iid < 30000 || iid > 31999 {
+		return false
+	}
+
+	// ISO 13616 check: the IBAN rotated by 4 characters, with letters as numbers from 10, must be 1 modulo 97
+	remainder := 0
+	for _, r := range iban[4:] + iban[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			remainder = (remainder*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			remainder = (remainder*100 + int(r-'A') + 10) % 97
+		default:
+			return false
+		}
+	}
+	return remainder == 1
+}
+
+// Returns the opening and closing times as durations since the start of the day.
+func (h OpeningHours) Times() (time.Duration, time.Duration, error) {
+	open, err := time.Parse(OPENING_TIME_FORMAT, h.Open)
+	if err != nil {
+		return 0, 0, err
+	}
+	closing := 24 * time.Hour
+	if h.Close != "24:00" {
+		closingTime, err := time.Parse(OPENING_TIME_FORMAT, h.Close)
+		if err != nil {
+			return 0, 0, err
+		}
+		closing = time.Duration(closingTime.Hour())*time.Hour + time.Duration(closingTime.Minute())*time.Minute
+	}
+	return time.Duration(open.Hour())*time.Hour + time.Duration(open.Minute())*time.Minute, closing, nil
+}
+
+func validOpeningHours(openingHours []OpeningHours) bool {
+	for _, hours := range openingHours {
+		if hours.Weekday < time.Sunday || hours.Weekday > time.Saturday {
+			return false
+		}
+		open, closing, err := hours.Times()
+		if err != nil || open >= closing {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true if the tenant Id is a valid club Id, which guarantees it cannot alter the keys built from it.
+func ValidTenantId(tenantId string) bool {
+	_, err := ulid.ParseStrict(tenantId)
+	return err == nil
+}
+
+// Create or update a club.
+// Clubs are created by the admins of the platform tenant, and configured by them or by the admins of the club itself.
+func (c *Client) CreateOrUpdate(input Club) (*Club, error) {
+	newClub := input.Id == ""
+	conditionExpression := "attribute_exists(PK)"
+	if newClub {
+		if !c.isPlatform() {
+			return nil, ClubAccessDenyError
+		}
+		input.Id = ulid.Make().String()
+		conditionExpression = "attribute_not_exists(PK)"
+		c.SetLogger(c.Logger().With("club", input.Id))
+		c.Logger().Info("creating club")
+	} else {
+		if !c.canManage(input.Id) {
+			return nil, ClubAccessDenyError
+		}
+		c.SetLogger(c.Logger().With("club", input.Id))
+		c.Logger().Info("updating club")
+	}
+
+	if _, err := time.LoadLocation(input.Timezone); input.Timezone == "" || err != nil {
+		return nil, ClubInvalidTimezoneError
+	}
+	if input.Language == "" {
+		input.Language = "en"
+	}
+	if input.Language != "en" && input.Language != "fr" {
+		return nil, ClubInvalidLanguageError
+	}
+	if input.OpeningHours == nil {
+		input.OpeningHours = make([]OpeningHours, 0)
+	}
+	if !validOpeningHours(input.OpeningHours) {
+		return nil, ClubInvalidOpeningHoursError
+	}
+	if input.SlotMinutes == 0 {
+		input.SlotMinutes = DEFAULT_SLOT_MINUTES
+	}
+	if input.SlotMinutes < 0 || (24*60)%input.SlotMinutes != 0 {
+		return nil, ClubInvalidSlotMinutesError
+	}
+	if input.LandingFee < 0 || input.FuelPrice < 0 {
+		return nil, ClubInvalidFeesError
+	}
+	if input.QrIban != "" && !ValidQrIban(input.QrIban) {
+		return nil, ClubInvalidQrIbanError
+	}
+	if input.Address != nil && !input.Address.Valid() {
+		return nil, ClubInvalidAddressError
+	}
+
+	pk := fmt.Sprintf("%s#%s", constants.CLUB_PARTITION_KEY, input.Id)
+	databaseItem := databaseItem{
+		PK:       pk,
+		SK:       pk,
+		GSI1PK:   constants.CLUB_PARTITION_KEY,
+		GSI1SK:   pk,
+		ItemType: "club",
+		GSIData: gsiData{
+			Name:     input.Name,
+			Timezone: input.Timezone,
+			Language: input.Language,
+		},
+		Club: input,
+	}
+
+	out, err := c.DatabaseClient.Put(database.PutInput{
+		Item:                databaseItem,
+		ConditionExpression: aws.String(conditionExpression),
+	})
+	if err != nil {
+		var conditionalCheckFailed *types.ConditionalCheckFailedException
+		if !newClub && errors.As(err, &conditionalCheckFailed) {
+			return nil, ClubNotFoundError
+		}
+		return nil, err
+	}
+
+	input.CreatedAt = out.CreatedAt
+	input.UpdatedAt = out.UpdatedAt
+
+	if newClub {
+		c.Logger().Info("club created")
+	} else {
+		c.Logger().Info("club updated")
+	}
+
+	return &input, nil
+}
+
+type ListInput struct {
+	NextToken *string
+	Limit     *int32
+}
+
+type ListOutput struct {
+	NextToken *string `json:"nextToken"`
+	Results   []Club  `json:"results"`
+}
+
+// Returns stored data for all clubs of the platform.
+func (c *Client) List(input ListInput) (*ListOutput, error) {
+	c.Logger().Info("listing clubs")
+
+	if !c.isPlatform() {
+		return nil, ClubAccessDenyError
+	}
+
+	exclusiveStartKey, err := database.DecodeNextToken(input.NextToken, constants.CLUB_PARTITION_KEY)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.DatabaseClient.Query(&database.QueryInput{
+		Index:                  aws.String("GSI1"),
+		Limit:                  input.Limit,
+		KeyConditionExpression: aws.String("GSI1PK = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: constants.CLUB_PARTITION_KEY},
+		},
+		ExclusiveStartKey: exclusiveStartKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	clubs := make([]Club, 0)
+	for _, item := range output.Items {
+		var indexItem struct {
+			Id        string
+			CreatedAt time.Time
+			UpdatedAt time.Time
+			GSIData   gsiData
+		}
+		err := attributevalue.UnmarshalMap(item, &indexItem)
+		if err != nil {
+			return nil, err
+		}
+		clubs = append(clubs, Club{
+			Id:        indexItem.Id,
+			Name:      indexItem.GSIData.Name,
+			Timezone:  indexItem.GSIData.Timezone,
+			Language:  indexItem.GSIData.Language,
+			CreatedAt: indexItem.CreatedAt,
+			UpdatedAt: indexItem.UpdatedAt,
+		})
+	}
+
+	nextToken, err := database.EncodeNextToken(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("clubs listed", "count", len(clubs), "isNextToken", nextToken != nil)
+	return &ListOutput{
+		NextToken: nextToken,
+		Results:   clubs,
+	}, nil
+}
+
+// Returns stored data for a club.
+func (c *Client) Get(clubId string) (*Club, error) {
+	c.SetLogger(c.Logger().With("club", clubId))
+	c.Logger().Info("retrieving club")
+
+	if !c.canRead(clubId) {
+		return nil, ClubAccessDenyError
+	}
+
+	pk := fmt.Sprintf("%s#%s", constants.CLUB_PARTITION_KEY, clubId)
+	output, err := c.DatabaseClient.Get(database.GetInput{
+		PK: pk,
+		SK: pk,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.Item == nil {
+		return nil, ClubNotFoundError
+	}
+
+	club := new(Club)
+	err = attributevalue.UnmarshalMap(output.Item, club)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("club retrieved")
+	return club, nil
+}
+
+// Returns true if the caller is an admin of the platform tenant.
+func (c *Client) isPlatform() bool {
+	return c.PlatformTenantId != "" && c.TenantId == c.PlatformTenantId && c.UserRole == constants.ROLE_ADMIN
+}
+
+// A club can only be managed by its admins or by the admins of the platform.
+func (c *Client) canManage(clubId string) bool {
+	return (c.TenantId == clubId && c.UserRole == constants.ROLE_ADMIN) || c.isPlatform()
+}
+
+// A club can be read by all its members, its settings apply to their reservations and charges.
+func (c *Client) canRead(clubId string) bool {
+	return c.TenantId == clubId || c.isPlatform()
+}
@@ -0,0 +1,30 @@
+package club
+
+import "testing"
+
+func TestValidQrIban(t *testing.T) {
+	tests := []struct {
+		name string
+		iban string
+		want bool
+	}{
+		// QR-IBAN of the examples of the Swiss Implementation Guidelines for the QR-bill
+		{"Swiss QR-IBAN", "CH4431999123000889012", true},
+		{"Liechtenstein QR-IBAN", "LI5731999123000889012", true},
+		// Regular IBAN of the same account, its institution identification is not a QR-IID
+		{"regular IBAN", "CH5800791123000889012", false},
+		{"regular IBAN of another bank", "CH9300762011623852957", false},
+		{"wrong check digits", "CH4531999123000889012", false},
+		{"mistyped account number", "CH4431999123000889013", false},
+		{"not a Swiss or Liechtenstein IBAN", "DE4431999123000889012", false},
+		{"printed with spaces", "CH44 3199 9123 0008 8901 2", false},
+		{"lower case", "ch4431999123000889012", false},
+		{"too short", "CH443199912300088901", false},
+		{"empty", "", false},
+	}
+	for _, test := range tests {
+		if got := ValidQrIban(test.iban); got != test.want {
+			t.Errorf("%s: ValidQrIban(%q) = %v, want %v", test.name, test.iban, got, test.want)
+		}
+	}
+}
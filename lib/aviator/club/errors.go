@@ -0,0 +1,84 @@
+package club
+
+import "aviator/errors"
+
+var ClubNotFoundError = errors.AviatorError{
+	Id: "club_not_found",
+	Message: errors.Message{
+		EN: "The selected club does not exist",
+		FR: "Le club sélectionné n'existe pas",
+	},
+	ApiError: 404,
+}
+
+var ClubAccessDenyError = errors.AviatorError{
+	Id: "club_access_deny",
+	Message: errors.Message{
+		EN: "You do not have permission to manage this club",
+		FR: "Vous n'avez pas l'autorisation de gérer ce club",
+	},
+	ApiError: 401,
+}
+
+var ClubInvalidTimezoneError = errors.AviatorError{
+	Id: "club_invalid_timezone",
+	Message: errors.Message{
+		EN: "The timezone of the club is invalid",
+		FR: "Le fuseau horaire du club n'est pas valide",
+	},
+	ApiError: 400,
+}
+
+var ClubInvalidLanguageError = errors.AviatorError{
+	Id: "club_invalid_language",
+	Message: errors.Message{
+		EN: "The language of the club must be en or fr",
+		FR: "La langue du club doit être en ou fr",
+	},
+	ApiError: 400,
+}
+
+var ClubInvalidOpeningHoursError = errors.AviatorError{
+	Id: "club_invalid_opening_hours",
+	Message: errors.Message{
+		EN: "The opening hours of the club must be on a weekday from 0 to 6 and open before they close, formatted as 08:00",
+		FR: "Les heures d'ouverture du club doivent être sur un jour de 0 à 6 et ouvrir avant de fermer, au format 08:00",
+	},
+	ApiError: 400,
+}
+
+var ClubInvalidSlotMinutesError = errors.AviatorError{
+	Id: "club_invalid_slot_minutes",
+	Message: errors.Message{
+		EN: "The slot duration of the club must be a positive number of minutes dividing a day",
+		FR: "La durée des créneaux du club doit être un nombre positif de minutes divisant une journée",
+	},
+	ApiError: 400,
+}
+
+var ClubInvalidFeesError = errors.AviatorError{
+	Id: "club_invalid_fees",
+	Message: errors.Message{
+		EN: "The landing fee and the fuel price cannot be negative",
+		FR: "La taxe d'atterrissage et le prix du carburant ne peuvent pas être négatifs",
+	},
+	ApiError: 400,
+}
+
+var ClubInvalidQrIbanError = errors.AviatorError{
+	Id: "club_invalid_qr_iban",
+	Message: errors.Message{
+		EN: "The QR-IBAN must be a valid Swiss or Liechtenstein QR-IBAN without spaces",
+		FR: "Le QR-IBAN doit être un QR-IBAN suisse ou liechtensteinois valide sans espaces",
+	},
+	ApiError: 400,
+}
+
+var ClubInvalidAddressError = errors.AviatorError{
+	Id: "club_invalid_address",
+	Message: errors.Message{
+		EN: "An address requires a postal code, a town and a two-letter country code",
+		FR: "Une adresse requiert un code postal, une localité et un code pays à deux lettres",
+	},
+	ApiError: 400,
+}
@@ -1,4 +1,12 @@
 package constants
 
+const CLUB_PARTITION_KEY = "CLUB"
+
 const MEASUREMENT_PARTITION_KEY = "MEASUREMENT"
 const STATION_PARTITION_KEY = "STATION"
+
+// Roles of the members of a club, set in the role claim of their token
+const ROLE_ADMIN = "admin"
+const ROLE_INSTRUCTOR = "instructor"
+const ROLE_PILOT = "pilot"
+const ROLE_GUEST = "guest"
@@ -5,14 +5,20 @@ package database
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
 )
 
 type DynamoDbAPI interface {
@@ -53,12 +59,17 @@ func (c Client) TransactWriteItems(params dynamodb.TransactWriteItemsInput) (*Tr
 	timestamps := make([]Timestamp, 0)
 	for i := 0; i < len(params.TransactItems); i++ {
 		if params.TransactItems[i].Put != nil {
+			// Timestamps already set on the item are kept
 			currentTime := time.Now()
-			params.TransactItems[i].Put.Item["CreatedAt"] = &types.AttributeValueMemberS{
-				Value: currentTime.Format("2006-01-02T15:04:05.000Z"),
+			if _, ok := params.TransactItems[i].Put.Item["CreatedAt"]; !ok {
+				params.TransactItems[i].Put.Item["CreatedAt"] = &types.AttributeValueMemberS{
+					Value: currentTime.Format("2006-01-02T15:04:05.000Z"),
+				}
 			}
-			params.TransactItems[i].Put.Item["UpdatedAt"] = &types.AttributeValueMemberS{
-				Value: currentTime.Format("2006-01-02T15:04:05.000Z"),
+			if _, ok := params.TransactItems[i].Put.Item["UpdatedAt"]; !ok {
+				params.TransactItems[i].Put.Item["UpdatedAt"] = &types.AttributeValueMemberS{
+					Value: currentTime.Format("2006-01-02T15:04:05.000Z"),
+				}
 			}
 			timestamps = append(timestamps, Timestamp{
 				CreatedAt: currentTime,
@@ -73,6 +84,20 @@ func (c Client) TransactWriteItems(params dynamodb.TransactWriteItemsInput) (*Tr
 	}, err
 }
 
+// Returns true if a transaction was cancelled because one of its conditions failed.
+func IsConditionalCheckFailure(err error) bool {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		return false
+	}
+	for _, reason := range canceled.CancellationReasons {
+		if reason.Code != nil && *reason.Code == "ConditionalCheckFailed" {
+			return true
+		}
+	}
+	return false
+}
+
 type BatchWriteInput struct {
 	Items []map[string]types.AttributeValue
 }
@@ -96,13 +121,36 @@ func (c Client) BatchWriteItem(input BatchWriteInput) error {
 		})
 	}
 
-	requestMap := make(map[string][]types.WriteRequest)
-	requestMap[c.TableName] = requests
+	return c.batchWrite(requests)
+}
 
-	_, err := c.DynamoDbClient.BatchWriteItem(context.TODO(), &dynamodb.BatchWriteItemInput{
-		RequestItems: requestMap,
-	})
-	return err
+// Number of times a batch write is sent before giving up on the requests DynamoDB left unprocessed
+const MAX_BATCH_WRITE_ATTEMPTS = 5
+
+// Delay before resending the unprocessed requests of a batch write, doubled after every attempt
+const BATCH_WRITE_BACKOFF = 50 * time.Millisecond
+
+// Sends the write requests of a batch, resending the ones DynamoDB left unprocessed with an exponential backoff.
+func (c Client) batchWrite(requests []types.WriteRequest) error {
+	requestMap := map[string][]types.WriteRequest{c.TableName: requests}
+	backoff := BATCH_WRITE_BACKOFF
+	for attempt := 1; ; attempt++ {
+		output, err := c.DynamoDbClient.BatchWriteItem(context.TODO(), &dynamodb.BatchWriteItemInput{
+			RequestItems: requestMap,
+		})
+		if err != nil {
+			return err
+		}
+		if len(output.UnprocessedItems) == 0 {
+			return nil
+		}
+		if attempt == MAX_BATCH_WRITE_ATTEMPTS {
+			return BatchWriteIncompleteError
+		}
+		requestMap = output.UnprocessedItems
+		time.Sleep(backoff)
+		backoff *= 2
+	}
 }
 
 type BatchDeleteInput struct {
@@ -124,57 +172,177 @@ func (c Client) BatchDeleteItem(input BatchDeleteInput) error {
 		})
 	}
 
-	requestMap := make(map[string][]types.WriteRequest)
-	requestMap[c.TableName] = requests
-
-	_, err := c.DynamoDbClient.BatchWriteItem(context.TODO(), &dynamodb.BatchWriteItemInput{
-		RequestItems: requestMap,
-	})
-	return err
+	return c.batchWrite(requests)
 }
 
+// Attribute holding the version of an item, incremented on every write
+const VERSION_ATTRIBUTE = "Version"
+
 type PutInput struct {
 	Item                     any
 	ConditionExpression      *string
 	ExpressionAttributeNames map[string]string
+	// Version of the stored item the put replaces, 0 if there is none.
+	// When set, the item is written with the next version and only if the stored version still matches.
+	ExpectedVersion *int
 }
 
 type PutOutput struct {
-	Ouput     *dynamodb.PutItemOutput
+	Ouput     *dynamodb.UpdateItemOutput
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	// Version of the written item, 0 if no version was expected
+	Version int
 }
 
-func (c Client) Put(input PutInput) (*PutOutput, error) {
+// Builds an update replacing all attributes of an item like a put would, but keeping the creation time of an existing item.
+// Attributes declared by the item type but left empty are removed. Returns the update time set on the item.
+func (c Client) NewReplace(input PutInput) (*types.Update, time.Time, error) {
+	currentTime := time.Now()
 	item, err := attributevalue.MarshalMap(input.Item)
 	if err != nil {
-		return nil, err
+		return nil, currentTime, err
 	}
 
-	currentTime := time.Now()
-	item["CreatedAt"] = &types.AttributeValueMemberS{
-		Value: currentTime.Format("2006-01-02T15:04:05.000Z"),
+	names := make(map[string]string)
+	for placeholder, name := range input.ExpressionAttributeNames {
+		names[placeholder] = name
 	}
-	item["UpdatedAt"] = &types.AttributeValueMemberS{
-		Value: currentTime.Format("2006-01-02T15:04:05.000Z"),
+	values := map[string]types.AttributeValue{
+		":now": &types.AttributeValueMemberS{Value: currentTime.Format("2006-01-02T15:04:05.000Z")},
+	}
+	names["#createdAt"] = "CreatedAt"
+	names["#updatedAt"] = "UpdatedAt"
+	sets := []string{"#createdAt = if_not_exists(#createdAt, :now)", "#updatedAt = :now"}
+	conditionExpression := input.ConditionExpression
+
+	managed := map[string]bool{"PK": true, "SK": true, "CreatedAt": true, "UpdatedAt": true}
+	if input.ExpectedVersion != nil {
+		managed[VERSION_ATTRIBUTE] = true
+		names["#version"] = VERSION_ATTRIBUTE
+		values[":nextVersion"] = &types.AttributeValueMemberN{Value: fmt.Sprint(*input.ExpectedVersion + 1)}
+		sets = append(sets, "#version = :nextVersion")
+
+		versionCondition := "attribute_not_exists(#version)"
+		if *input.ExpectedVersion != 0 {
+			versionCondition = "#version = :expectedVersion"
+			values[":expectedVersion"] = &types.AttributeValueMemberN{Value: fmt.Sprint(*input.ExpectedVersion)}
+		}
+		if conditionExpression == nil {
+			conditionExpression = aws.String(versionCondition)
+		} else {
+			conditionExpression = aws.String(fmt.Sprintf("(%s) AND %s", *conditionExpression, versionCondition))
+		}
 	}
 
-	output, err := c.DynamoDbClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName:                &c.TableName,
-		Item:                     item,
-		ConditionExpression:      input.ConditionExpression,
-		ExpressionAttributeNames: input.ExpressionAttributeNames,
+	attributes := make([]string, 0, len(item))
+	for name := range item {
+		if !managed[name] {
+			attributes = append(attributes, name)
+		}
+	}
+	sort.Strings(attributes)
+	for i, name := range attributes {
+		names[fmt.Sprintf("#a%d", i)] = name
+		values[fmt.Sprintf(":a%d", i)] = item[name]
+		sets = append(sets, fmt.Sprintf("#a%d = :a%d", i, i))
+	}
+	updateExpression := "SET " + strings.Join(sets, ", ")
+
+	removes := make([]string, 0)
+	for i, name := range attributeNames(reflect.TypeOf(input.Item)) {
+		if _, ok := item[name]; ok || managed[name] {
+			continue
+		}
+		names[fmt.Sprintf("#r%d", i)] = name
+		removes = append(removes, fmt.Sprintf("#r%d", i))
+	}
+	if len(removes) > 0 {
+		updateExpression += " REMOVE " + strings.Join(removes, ", ")
+	}
+
+	return &types.Update{
+		TableName: &c.TableName,
+		Key: map[string]types.AttributeValue{
+			"PK": item["PK"],
+			"SK": item["SK"],
+		},
+		UpdateExpression:          aws.String(updateExpression),
+		ConditionExpression:       conditionExpression,
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	}, currentTime, nil
+}
+
+// Returns the names of the attributes declared by an item type, including the ones of its embedded structs.
+func attributeNames(itemType reflect.Type) []string {
+	if itemType.Kind() == reflect.Pointer {
+		itemType = itemType.Elem()
+	}
+	if itemType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	names := make([]string, 0)
+	for i := 0; i < itemType.NumField(); i++ {
+		field := itemType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("dynamodbav"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			names = append(names, attributeNames(field.Type)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// Writes an item, replacing the stored one while keeping its creation time.
+func (c Client) Put(input PutInput) (*PutOutput, error) {
+	update, updatedAt, err := c.NewReplace(input)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.DynamoDbClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:                 update.TableName,
+		Key:                       update.Key,
+		UpdateExpression:          update.UpdateExpression,
+		ConditionExpression:       update.ConditionExpression,
+		ExpressionAttributeNames:  update.ExpressionAttributeNames,
+		ExpressionAttributeValues: update.ExpressionAttributeValues,
+		ReturnValues:              types.ReturnValueAllNew,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	var written struct {
+		CreatedAt time.Time
+		Version   int
+	}
+	err = attributevalue.UnmarshalMap(output.Attributes, &written)
 	return &PutOutput{
 		Ouput:     output,
-		CreatedAt: currentTime,
-		UpdatedAt: currentTime,
+		CreatedAt: written.CreatedAt,
+		UpdatedAt: updatedAt,
+		Version:   written.Version,
 	}, err
 }
 
 type GetInput struct {
 	PK string
 	SK string
+	// Use a strongly consistent read
+	ConsistentRead bool `dynamodbav:"-"`
 }
 
 func (c Client) Get(input GetInput) (*dynamodb.GetItemOutput, error) {
@@ -183,8 +351,9 @@ func (c Client) Get(input GetInput) (*dynamodb.GetItemOutput, error) {
 		return nil, err
 	}
 	output, err := c.DynamoDbClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
-		TableName: &c.TableName,
-		Key:       keyItem,
+		TableName:      &c.TableName,
+		Key:            keyItem,
+		ConsistentRead: &input.ConsistentRead,
 	})
 	return output, err
 }
@@ -194,6 +363,8 @@ type UpdateInput struct {
 	SK                  string
 	Item                any
 	ConditionExpression *string
+	// Version the stored item must have for the update to succeed, not checked if nil
+	ExpectedVersion *int
 }
 
 func UpdateExpression(input any, setNil bool) (expression.Expression, time.Time, error) {
@@ -237,6 +408,7 @@ func UpdateExpression(input any, setNil bool) (expression.Expression, time.Time,
 	}
 	currentTime := time.Now()
 	upd = upd.Set(expression.Name("UpdatedAt"), expression.Value(currentTime.Format("2006-01-02T15:04:05.000Z")))
+	upd = upd.Add(expression.Name(VERSION_ATTRIBUTE), expression.Value(1))
 
 	expr, err := expression.NewBuilder().WithUpdate(upd).Build()
 	return expr, currentTime, err
@@ -245,15 +417,35 @@ func UpdateExpression(input any, setNil bool) (expression.Expression, time.Time,
 type UpdateOutput struct {
 	Ouput     *dynamodb.UpdateItemOutput
 	UpdatedAt time.Time
+	// Version of the updated item
+	Version int
 }
 
-func (c Client) Update(input UpdateInput) (*UpdateOutput, error) {
+// Builds the update of an item from the non nil fields of the input item, to be run alone by Update or inside a transaction.
+// Returns the update time set on the item.
+func (c Client) NewUpdate(input UpdateInput) (*types.Update, time.Time, error) {
 	expr, updatedAt, err := UpdateExpression(input.Item, false)
 	if err != nil {
-		return nil, err
+		return nil, updatedAt, err
 	}
 
-	output, err := c.DynamoDbClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+	conditionExpression := input.ConditionExpression
+	expressionAttributeValues := expr.Values()
+	if input.ExpectedVersion != nil {
+		versionCondition := fmt.Sprintf("%s = :expectedVersion", VERSION_ATTRIBUTE)
+		if *input.ExpectedVersion == 0 {
+			versionCondition = fmt.Sprintf("attribute_not_exists(%s)", VERSION_ATTRIBUTE)
+		} else {
+			expressionAttributeValues[":expectedVersion"] = &types.AttributeValueMemberN{Value: fmt.Sprint(*input.ExpectedVersion)}
+		}
+		if conditionExpression == nil {
+			conditionExpression = aws.String(versionCondition)
+		} else {
+			conditionExpression = aws.String(fmt.Sprintf("(%s) AND %s", *conditionExpression, versionCondition))
+		}
+	}
+
+	return &types.Update{
 		TableName: &c.TableName,
 		Key: map[string]types.AttributeValue{
 			"PK": &types.AttributeValueMemberS{
@@ -265,12 +457,38 @@ func (c Client) Update(input UpdateInput) (*UpdateOutput, error) {
 		},
 		UpdateExpression:          expr.Update(),
 		ExpressionAttributeNames:  expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-		ConditionExpression:       input.ConditionExpression,
+		ExpressionAttributeValues: expressionAttributeValues,
+		ConditionExpression:       conditionExpression,
+	}, updatedAt, nil
+}
+
+func (c Client) Update(input UpdateInput) (*UpdateOutput, error) {
+	update, updatedAt, err := c.NewUpdate(input)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.DynamoDbClient.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:                 update.TableName,
+		Key:                       update.Key,
+		UpdateExpression:          update.UpdateExpression,
+		ExpressionAttributeNames:  update.ExpressionAttributeNames,
+		ExpressionAttributeValues: update.ExpressionAttributeValues,
+		ConditionExpression:       update.ConditionExpression,
+		ReturnValues:              types.ReturnValueUpdatedNew,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	var updated struct {
+		Version int
+	}
+	err = attributevalue.UnmarshalMap(output.Attributes, &updated)
 	return &UpdateOutput{
 		Ouput:     output,
 		UpdatedAt: updatedAt,
+		Version:   updated.Version,
 	}, err
 }
 
@@ -303,6 +521,58 @@ func (c Client) Query(queryInput *QueryInput) (*dynamodb.QueryOutput, error) {
 	return queryOutput, err
 }
 
+// Key of the last evaluated item of a query, as serialized in pagination tokens
+type ExclusiveStartKey struct {
+	PK     string `json:"PK"`
+	SK     string `json:"SK"`
+	GSI1PK string `dynamodbav:",omitempty" json:"GSI1PK,omitempty"`
+	GSI1SK string `dynamodbav:",omitempty" json:"GSI1SK,omitempty"`
+}
+
+// Decodes a pagination token into the key to start the query from.
+// Tokens pointing outside of the given table or GSI1 partition are rejected, so a client cannot page into another tenant's data.
+func DecodeNextToken(token *string, partitionKey string) (map[string]types.AttributeValue, error) {
+	if token == nil {
+		return nil, nil
+	}
+
+	nextTokenData, err := base64.StdEncoding.DecodeString(*token)
+	if err != nil {
+		return nil, err
+	}
+
+	var nextToken ExclusiveStartKey
+	err = json.Unmarshal([]byte(nextTokenData), &nextToken)
+	if err != nil {
+		return nil, err
+	}
+	if nextToken.PK != partitionKey && nextToken.GSI1PK != partitionKey {
+		return nil, InvalidNextTokenError
+	}
+
+	return attributevalue.MarshalMap(nextToken)
+}
+
+// Encodes the last key evaluated by a query into a pagination token, or nil if there are no more results.
+func EncodeNextToken(lastEvaluatedKey map[string]types.AttributeValue) (*string, error) {
+	if len(lastEvaluatedKey) == 0 {
+		return nil, nil
+	}
+
+	token := new(ExclusiveStartKey)
+	err := attributevalue.UnmarshalMap(lastEvaluatedKey, token)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonOut, err := json.Marshal(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return aws.String(string(base64.StdEncoding.EncodeToString(jsonOut))), nil
+}
+
 type DeleteInput struct {
 	PK string
 	SK string
@@ -0,0 +1,21 @@
+package database
+
+import "aviator/errors"
+
+var InvalidNextTokenError = errors.AviatorError{
+	Id: "invalid_next_token",
+	Message: errors.Message{
+		EN: "The provided next page token is invalid",
+		FR: "Le jeton de page suivante fourni n'est pas valide",
+	},
+	ApiError: 400,
+}
+
+var BatchWriteIncompleteError = errors.AviatorError{
+	Id: "batch_write_incomplete",
+	Message: errors.Message{
+		EN: "Some items could not be written because the database is busy, please retry",
+		FR: "Certains éléments n'ont pas pu être enregistrés car la base de données est surchargée, veuillez réessayer",
+	},
+	ApiError: 503,
+}
@@ -0,0 +1,66 @@
+package flightlog
+
+import "aviator/errors"
+
+var FlightLogNotFoundError = errors.AviatorError{
+	Id: "flight_log_not_found",
+	Message: errors.Message{
+		EN: "The selected reservation has no flight log",
+		FR: "La réservation sélectionnée n'a pas de carnet de vol",
+	},
+	ApiError: 404,
+}
+
+var FlightLogAccessDenyError = errors.AviatorError{
+	Id: "flight_log_access_deny",
+	Message: errors.Message{
+		EN: "Only instructors can see all flight logs, and pilots can only see their own",
+		FR: "Seuls les instructeurs peuvent voir tous les carnets de vol, et les pilotes ne peuvent voir que les leurs",
+	},
+	ApiError: 401,
+}
+
+var FlightLogInvalidMetersError = errors.AviatorError{
+	Id: "flight_log_invalid_meters",
+	Message: errors.Message{
+		EN: "The Hobbs and tachometer readings cannot be negative or decrease during a flight",
+		FR: "Les relevés du Hobbs et du tachymètre ne peuvent pas être négatifs ou diminuer pendant un vol",
+	},
+	ApiError: 400,
+}
+
+var FlightLogInvalidBlockTimesError = errors.AviatorError{
+	Id: "flight_log_invalid_block_times",
+	Message: errors.Message{
+		EN: "The block off time must be before the block on time",
+		FR: "L'heure de départ bloc doit précéder l'heure d'arrivée bloc",
+	},
+	ApiError: 400,
+}
+
+var FlightLogInvalidLandingsError = errors.AviatorError{
+	Id: "flight_log_invalid_landings",
+	Message: errors.Message{
+		EN: "The number of landings cannot be negative",
+		FR: "Le nombre d'atterrissages ne peut pas être négatif",
+	},
+	ApiError: 400,
+}
+
+var FlightLogInvalidFuelUpliftError = errors.AviatorError{
+	Id: "flight_log_invalid_fuel_uplift",
+	Message: errors.Message{
+		EN: "The fuel uplift cannot be negative",
+		FR: "Le carburant ajouté ne peut pas être négatif",
+	},
+	ApiError: 400,
+}
+
+var FlightLogInvalidAirportError = errors.AviatorError{
+	Id: "flight_log_invalid_airport",
+	Message: errors.Message{
+		EN: "Airports must be given by their four-letter ICAO code",
+		FR: "Les aérodromes doivent être indiqués par leur code OACI à quatre lettres",
+	},
+	ApiError: 400,
+}
@@ -0,0 +1,267 @@
+/*
+Package flightlog provides methods for reading the flight logs of a club.
+A flight log records what was actually flown during a reservation, it is written when the pilot checks the aircraft in.
+*/
+package flightlog
+
+import (
+	"aviator/aircraft"
+	"aviator/constants"
+	"aviator/database"
+	"aviator/tenant"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const FLIGHT_LOG_PARTITION_KEY = "FLIGHTLOG"
+
+// Format of the UTC times used in keys, sortable as strings
+const TIME_KEY_FORMAT = "2006-01-02T15:04:05Z"
+
+// ICAO location indicator of an airport: e.g. LSGE
+var airportPattern = regexp.MustCompile(`^[A-Z]{4}$`)
+
+type FlightLogApiInterface interface {
+	Logger() *slog.Logger
+	SetLogger(logger *slog.Logger)
+	Get(reservationId string) (*FlightLog, error)
+	List(registration string, input ListInput) (*ListOutput, error)
+}
+
+type Config struct {
+	tenant.Scope
+}
+
+type Client struct {
+	Config
+}
+
+// Item used to store the flight log of a reservation
+type FlightLog struct {
+	// Id of the checked in reservation, set from the reservation: e.g. 01H55420KY47HRVVPK1Z3BSACK
+	ReservationId string `json:"reservationId"`
+	// Registration of the flown aircraft, set from the reservation: e.g. HB-KFQ
+	Aircraft string `json:"aircraft"`
+	// Member Id of the pilot, set from the reservation
+	Pilot string `json:"pilot"`
+	// Member Id of the instructor, set from the reservation (if any)
+	Instructor *string `dynamodbav:",omitempty" json:"instructor,omitempty"`
+	// Hobbs meter readings in hours: e.g. 1234.5
+	HobbsStart float64 `json:"hobbsStart"`
+	HobbsEnd   float64 `json:"hobbsEnd"`
+	// Tachometer readings in hours: e.g. 987.6
+	TachStart float64 `json:"tachStart"`
+	TachEnd   float64 `json:"tachEnd"`
+	// Block off and block on times
+	BlockOff time.Time `json:"blockOff"`
+	BlockOn  time.Time `json:"blockOn"`
+	// Number of landings
+	Landings int `json:"landings"`
+	// Fuel uplifted after the flight in litres
+	FuelUplift float64 `json:"fuelUplift"`
+	// The pilot paid the fuel uplift, e.g. at another airport, instead of the club
+	FuelPaidByPilot bool `json:"fuelPaidByPilot"`
+	// ICAO location indicators of the airports: e.g. LSGE
+	DepartureAirport string    `json:"departureAirport"`
+	ArrivalAirport   string    `json:"arrivalAirport"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// Database item to store the flight log.
+type databaseItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key: e.g. FLIGHTLOG#01H55420KY47HRVVPK1Z3BSACK
+	SK string
+	// GSI1 primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP#AIRCRAFT#HB-KFQ
+	GSI1PK string
+	// GSI1 sort key, ordered by block off time: e.g. FLIGHTLOG#2023-04-05T12:30:00Z#01H55420KY47HRVVPK1Z3BSACK
+	GSI1SK string
+
+	// Item type: flightLog
+	ItemType string
+	// Flight log copied for GSI1, which only projects GSIData
+	GSIData FlightLog
+	FlightLog
+}
+
+// Returns a new flight log API client from the provided config.
+func NewFromConfig(c Config) *Client {
+	return &Client{Config: c}
+}
+
+func (c *Client) Logger() *slog.Logger {
+	return c.Config.Logger
+}
+
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.Config.Logger = logger
+}
+
+// Returns the flight time in hours measured by the Hobbs meter.
+func (f FlightLog) FlightTime() float64 {
+	return f.HobbsEnd - f.HobbsStart
+}
+
+// Checks the readings, times, landings, fuel and airports of the flight log.
+// Readings are only checked against each other, the reservation checks them against the last readings of the aircraft.
+func (f FlightLog) Validate() error {
+	if f.HobbsStart < 0 || f.TachStart < 0 || f.HobbsEnd < f.HobbsStart || f.TachEnd < f.TachStart {
+		return FlightLogInvalidMetersError
+	}
+	if f.BlockOff.IsZero() || !f.BlockOff.Before(f.BlockOn) {
+		return FlightLogInvalidBlockTimesError
+	}
+	if f.Landings < 0 {
+		return FlightLogInvalidLandingsError
+	}
+	if f.FuelUplift < 0 {
+		return FlightLogInvalidFuelUpliftError
+	}
+	if !airportPattern.MatchString(f.DepartureAirport) || !airportPattern.MatchString(f.ArrivalAirport) {
+		return FlightLogInvalidAirportError
+	}
+	return nil
+}
+
+// Builds the put of a new flight log, to be run inside the transaction checking the reservation in.
+func (c *Client) NewPut(input FlightLog) (*types.Put, error) {
+	now := time.Now().UTC()
+	input.CreatedAt = now
+	input.UpdatedAt = now
+
+	item, err := attributevalue.MarshalMap(databaseItem{
+		PK:        c.clubPK(),
+		SK:        fmt.Sprintf("%s#%s", FLIGHT_LOG_PARTITION_KEY, input.ReservationId),
+		GSI1PK:    c.aircraftPK(input.Aircraft),
+		GSI1SK:    fmt.Sprintf("%s#%s#%s", FLIGHT_LOG_PARTITION_KEY, input.BlockOff.UTC().Format(TIME_KEY_FORMAT), input.ReservationId),
+		ItemType:  "flightLog",
+		GSIData:   input,
+		FlightLog: input,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Put{
+		TableName:           aws.String(c.DatabaseClient.TableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(PK)"),
+	}, nil
+}
+
+// Returns true if the caller may read every flight log, pilots and guests may only read their own ones.
+func (c *Client) canReadAll() bool {
+	return c.UserRole == constants.ROLE_ADMIN || c.UserRole == constants.ROLE_INSTRUCTOR
+}
+
+// Returns the flight log of a reservation.
+func (c *Client) Get(reservationId string) (*FlightLog, error) {
+	c.SetLogger(c.Logger().With("reservation", reservationId))
+	c.Logger().Info("retrieving flight log")
+
+	output, err := c.DatabaseClient.Get(database.GetInput{
+		PK: c.clubPK(),
+		SK: fmt.Sprintf("%s#%s", FLIGHT_LOG_PARTITION_KEY, reservationId),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.Item == nil {
+		return nil, FlightLogNotFoundError
+	}
+
+	flightLog := new(FlightLog)
+	err = attributevalue.UnmarshalMap(output.Item, flightLog)
+	if err != nil {
+		return nil, err
+	}
+
+	isCrew := flightLog.Pilot == c.UserId || (flightLog.Instructor != nil && *flightLog.Instructor == c.UserId)
+	if !c.canReadAll() && !isCrew {
+		return nil, FlightLogAccessDenyError
+	}
+
+	c.Logger().Info("flight log retrieved")
+	return flightLog, nil
+}
+
+type ListInput struct {
+	NextToken *string
+	Limit     *int32
+}
+
+type ListOutput struct {
+	NextToken *string     `json:"nextToken"`
+	Results   []FlightLog `json:"results"`
+}
+
+// Returns the flight logs of an aircraft, ordered by block off time.
+// Only instructors and admins can list the flight logs of an aircraft.
+func (c *Client) List(registration string, input ListInput) (*ListOutput, error) {
+	c.SetLogger(c.Logger().With("aircraft", registration))
+	c.Logger().Info("listing flight logs")
+
+	if !c.canReadAll() {
+		return nil, FlightLogAccessDenyError
+	}
+
+	exclusiveStartKey, err := database.DecodeNextToken(input.NextToken, c.clubPK())
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.DatabaseClient.Query(&database.QueryInput{
+		Index:                  aws.String("GSI1"),
+		Limit:                  input.Limit,
+		KeyConditionExpression: aws.String("GSI1PK = :pk AND begins_with(GSI1SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: c.aircraftPK(registration)},
+			":sk": &types.AttributeValueMemberS{Value: FLIGHT_LOG_PARTITION_KEY + "#"},
+		},
+		ExclusiveStartKey: exclusiveStartKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var indexItems []struct {
+		GSIData FlightLog
+	}
+	err = attributevalue.UnmarshalListOfMaps(output.Items, &indexItems)
+	if err != nil {
+		return nil, err
+	}
+	flightLogs := make([]FlightLog, 0, len(indexItems))
+	for _, indexItem := range indexItems {
+		flightLogs = append(flightLogs, indexItem.GSIData)
+	}
+
+	nextToken, err := database.EncodeNextToken(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("flight logs listed", "count", len(flightLogs), "isNextToken", nextToken != nil)
+	return &ListOutput{
+		NextToken: nextToken,
+		Results:   flightLogs,
+	}, nil
+}
+
+// Returns the partition key of the tenant club owning the flight log.
+func (c *Client) clubPK() string {
+	return fmt.Sprintf("%s#%s", constants.CLUB_PARTITION_KEY, c.TenantId)
+}
+
+// Returns the GSI1 partition key of the aircraft, shared with its reservations.
+func (c *Client) aircraftPK(registration string) string {
+	return fmt.Sprintf("%s#%s#%s", c.clubPK(), aircraft.AIRCRAFT_PARTITION_KEY, registration)
+}
@@ -10,6 +10,7 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.30.1
 	github.com/aws/smithy-go v1.20.1
 	github.com/oklog/ulid/v2 v2.1.0
+	rsc.io/qr v0.2.0
 )
 
 require (
@@ -0,0 +1,261 @@
+package invoice
+
+import (
+	"aviator/billing"
+	"strconv"
+	"time"
+)
+
+// Height of the payment part at the bottom of the last page, in millimetres
+const PAYMENT_PART_HEIGHT = 105.0
+
+// Width of the receipt on the left of the payment part, in millimetres
+const RECEIPT_WIDTH = 62.0
+
+// Size of the QR code and of the Swiss cross at its centre, in millimetres
+const QR_CODE_SIZE = 46.0
+const SWISS_CROSS_SIZE = 7.0
+
+// Vertical space taken by a charge in the table, in millimetres
+const ROW_HEIGHT = 6.0
+
+// Labels printed on the bill, by language
+var labels = map[string]map[string]string{
+	"en": {
+		"invoice":                "Invoice",
+		"reference":              "Reference",
+		"date":                   "Date",
+		"registration":           "Aircraft",
+		"description":            "Description",
+		"quantity":               "Quantity",
+		"unitPrice":              "Unit price",
+		"amount":                 "Amount",
+		"total":                  "Total",
+		"page":                   "Page",
+		"receipt":                "Receipt",
+		"paymentPart":            "Payment part",
+		"payableTo":              "Account / Payable to",
+		"information":            "Additional information",
+		"payableBy":              "Payable by",
+		"payableByBlank":         "Payable by (name/address)",
+		"currency":               "Currency",
+		"acceptancePoint":        "Acceptance point",
+		billing.KIND_AIRCRAFT:    "Flight time",
+		billing.KIND_INSTRUCTION: "Instruction",
+		billing.KIND_LANDING:     "Landings",
+		billing.KIND_FUEL:        "Fuel",
+		billing.KIND_FUEL_CREDIT: "Fuel paid by the pilot",
+	},
+	"fr": {
+		"invoice":                "Facture",
+		"reference":              "Référence",
+		"date":                   "Date",
+		"registration":           "Avion",
+		"description":            "Description",
+		"quantity":               "Quantité",
+		"unitPrice":              "Prix unitaire",
+		"amount":                 "Montant",
+		"total":                  "Total",
+		"page":                   "Page",
+		"receipt":                "Récépissé",
+		"paymentPart":            "Section paiement",
+		"payableTo":              "Compte / Payable à",
+		"information":            "Informations supplémentaires",
+		"payableBy":              "Payable par",
+		"payableByBlank":         "Payable par (nom/adresse)",
+		"currency":               "Monnaie",
+		"acceptancePoint":        "Point de dépôt",
+		billing.KIND_AIRCRAFT:    "Temps de vol",
+		billing.KIND_INSTRUCTION: "Instruction",
+		billing.KIND_LANDING:     "Atterrissages",
+		billing.KIND_FUEL:        "Carburant",
+		billing.KIND_FUEL_CREDIT: "Carburant payé par le pilote",
+	},
+}
+
+// Returns the labels of a language, in English when the language is not translated.
+func labelsOf(language string) map[string]string {
+	if l, ok := labels[language]; ok {
+		return l
+	}
+	return labels["en"]
+}
+
+// Returns the unstructured message of the payment: e.g. Invoice 2024-04
+func invoiceMessage(language string, month string) string {
+	return labelsOf(language)["invoice"] + " " + month
+}
+
+// Renders an invoice as a PDF file: the charges of the month, spread over as many pages as needed,
+// followed by the QR-bill payment part at the bottom of the last page.
+func renderInvoice(invoice Invoice) ([]byte, error) {
+	l := labelsOf(invoice.Language)
+	location, err := time.LoadLocation(invoice.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	doc := new(pdfDocument)
+	pageNumber := 0
+	newPage := func() float64 {
+		doc.addPage()
+		pageNumber++
+		doc.textRight(190, 12, 8, false, strconv.Itoa(pageNumber))
+		doc.text(190-textWidth(strconv.Itoa(pageNumber), 8)-10, 12, 8, false, l["page"])
+		return 20
+	}
+
+	// Header with the club, the member and the reference
+	y := newPage()
+	doc.text(20, y, 12, true, invoice.Creditor.Name)
+	for i, line := range addressLines(invoice.Creditor.Address) {
+		doc.text(20, y+5+float64(i)*4.5, 10, false, line)
+	}
+	if invoice.Debtor != nil {
+		doc.text(120, 50, 10, false, invoice.Debtor.Name)
+		for i, line := range addressLines(invoice.Debtor.Address) {
+			doc.text(120, 54.5+float64(i)*4.5, 10, false, line)
+		}
+	}
+	doc.text(20, 80, 16, true, invoiceMessage(invoice.Language, invoice.Month))
+	doc.text(20, 87, 9, false, l["reference"]+" "+formatReference(invoice.Reference))
+
+	// Table of the charges
+	tableHeader := func(y float64) float64 {
+		doc.rect(20, y-4.5, 170, 6.5, 0.9)
+		doc.text(22, y, 9, true, l["date"])
+		doc.text(44, y, 9, true, l["registration"])
+		doc.text(68, y, 9, true, l["description"])
+		doc.text(122, y, 9, true, l["quantity"])
+		doc.text(143, y, 9, true, l["unitPrice"])
+		doc.text(174, y, 9, true, l["amount"])
+		return y + ROW_HEIGHT + 1
+	}
+	y = tableHeader(100)
+	for _, charge := range invoice.Charges {
+		if y > PAGE_HEIGHT-20 {
+			y = tableHeader(newPage())
+		}
+		doc.text(22, y, 9, false, charge.Date.In(location).Format("02.01.2006"))
+		doc.text(44, y, 9, false, charge.Aircraft)
+		doc.text(68, y, 9, false, l[charge.Kind])
+		doc.textRight(138, y, 9, false, strconv.FormatFloat(charge.Quantity, 'f', -1, 64))
+		doc.textRight(163, y, 9, false, strconv.FormatFloat(charge.UnitPrice, 'f', 2, 64))
+		doc.textRight(188, y, 9, false, formatAmount(charge.Amount))
+		y += ROW_HEIGHT
+	}
+	doc.line(20, y-3.5, 190, y-3.5, false)
+	doc.text(22, y+2, 10, true, l["total"]+" "+invoice.Currency)
+	doc.textRight(188, y+2, 10, true, formatAmount(invoice.Amount))
+
+	// The payment part needs the bottom of a page for itself
+	if y+10 > PAGE_HEIGHT-PAYMENT_PART_HEIGHT {
+		newPage()
+	}
+	err = renderPaymentPart(doc, invoice, l)
+	if err != nil {
+		return nil, err
+	}
+	return doc.bytes(), nil
+}
+
+// Draws the receipt and the payment part of a QR-bill at the bottom of the current page.
+func renderPaymentPart(doc *pdfDocument, invoice Invoice, l map[string]string) error {
+	top := PAGE_HEIGHT - PAYMENT_PART_HEIGHT
+	doc.line(0, top, PAGE_WIDTH, top, true)
+	doc.line(RECEIPT_WIDTH, top, RECEIPT_WIDTH, PAGE_HEIGHT, true)
+
+	// Receipt
+	x := 5.0
+	doc.text(x, top+10, 11, true, l["receipt"])
+	y := renderParty(doc, x, top+17, 6, 8, l["payableTo"], formatIban(invoice.QrIban), &invoice.Creditor)
+	y = renderField(doc, x, y, 6, 8, l["reference"], formatReference(invoice.Reference))
+	renderDebtor(doc, x, y, 6, 8, invoice.Debtor, l)
+	doc.text(x, top+73, 6, true, l["currency"])
+	doc.text(x+13, top+73, 6, true, l["amount"])
+	doc.text(x, top+76.5, 8, false, invoice.Currency)
+	doc.text(x+13, top+76.5, 8, false, formatAmount(invoice.Amount))
+	doc.text(RECEIPT_WIDTH-5-acceptancePointWidth(l), top+87, 6, true, l["acceptancePoint"])
+
+	// Payment part, with the QR code on the left and the details on the right
+	x = RECEIPT_WIDTH + 5
+	doc.text(x, top+10, 11, true, l["paymentPart"])
+	code, err := qrCode(invoice.Payload)
+	if err != nil {
+		return err
+	}
+	qrTop := top + 17
+	module := QR_CODE_SIZE / float64(code.Size)
+	for row := 0; row < code.Size; row++ {
+		// Runs of dark modules are drawn as a single rectangle
+		for column := 0; column < code.Size; {
+			if !code.Black(column, row) {
+				column++
+				continue
+			}
+			start := column
+			for column < code.Size && code.Black(column, row) {
+				column++
+			}
+			doc.rect(x+float64(start)*module, qrTop+float64(row)*module, float64(column-start)*module, module, 0)
+		}
+	}
+	renderSwissCross(doc, x+(QR_CODE_SIZE-SWISS_CROSS_SIZE)/2, qrTop+(QR_CODE_SIZE-SWISS_CROSS_SIZE)/2)
+	doc.text(x, top+73, 8, true, l["currency"])
+	doc.text(x+13, top+73, 8, true, l["amount"])
+	doc.text(x, top+77.5, 10, false, invoice.Currency)
+	doc.text(x+13, top+77.5, 10, false, formatAmount(invoice.Amount))
+
+	x = RECEIPT_WIDTH + 56
+	y = renderParty(doc, x, top+10, 8, 10, l["payableTo"], formatIban(invoice.QrIban), &invoice.Creditor)
+	y = renderField(doc, x, y, 8, 10, l["reference"], formatReference(invoice.Reference))
+	y = renderField(doc, x, y, 8, 10, l["information"], invoiceMessage(invoice.Language, invoice.Month))
+	renderDebtor(doc, x, y, 8, 10, invoice.Debtor, l)
+	return nil
+}
+
+// Draws a heading followed by a value, and returns the position of the next heading.
+func renderField(doc *pdfDocument, x float64, y float64, headingSize float64, valueSize float64, heading string, value string) float64 {
+	doc.text(x, y, headingSize, true, heading)
+	doc.text(x, y+valueSize*0.45, valueSize, false, value)
+	return y + valueSize*0.45 + headingSize*0.9
+}
+
+// Draws a heading followed by an account and a party, and returns the position of the next heading.
+func renderParty(doc *pdfDocument, x float64, y float64, headingSize float64, valueSize float64, heading string, account string, party *Party) float64 {
+	doc.text(x, y, headingSize, true, heading)
+	lines := append([]string{account, party.Name}, addressLines(party.Address)...)
+	for _, line := range lines {
+		y += valueSize * 0.45
+		doc.text(x, y, valueSize, false, line)
+	}
+	return y + headingSize*0.9
+}
+
+// Draws the debtor, or a heading asking for the name and address of the payer when the member has no address.
+func renderDebtor(doc *pdfDocument, x float64, y float64, headingSize float64, valueSize float64, debtor *Party, l map[string]string) {
+	if debtor == nil {
+		doc.text(x, y, headingSize, true, l["payableByBlank"])
+		return
+	}
+	doc.text(x, y, headingSize, true, l["payableBy"])
+	for _, line := range append([]string{debtor.Name}, addressLines(debtor.Address)...) {
+		y += valueSize * 0.45
+		doc.text(x, y, valueSize, false, line)
+	}
+}
+
+// Draws the Swiss cross at the centre of the QR code: a white cross on a black square with a white border.
+func renderSwissCross(doc *pdfDocument, x float64, y float64) {
+	doc.rect(x, y, SWISS_CROSS_SIZE, SWISS_CROSS_SIZE, 1)
+	doc.rect(x+0.5, y+0.5, SWISS_CROSS_SIZE-1, SWISS_CROSS_SIZE-1, 0)
+	arm, width := 3.9, 1.2
+	centre := SWISS_CROSS_SIZE / 2
+	doc.rect(x+centre-width/2, y+centre-arm/2, width, arm, 1)
+	doc.rect(x+centre-arm/2, y+centre-width/2, arm, width, 1)
+}
+
+// Returns an estimate of the width of the acceptance point heading, to align it on the right of the receipt.
+func acceptancePointWidth(l map[string]string) float64 {
+	return float64(len([]rune(l["acceptancePoint"]))) * 6 * 0.5 / MM
+}
@@ -0,0 +1,66 @@
+package invoice
+
+import "aviator/errors"
+
+var InvoiceNotFoundError = errors.AviatorError{
+	Id: "invoice_not_found",
+	Message: errors.Message{
+		EN: "Invoice not found",
+		FR: "Facture introuvable",
+	},
+	ApiError: 404,
+}
+
+var InvoiceAccessDenyError = errors.AviatorError{
+	Id: "invoice_access_deny",
+	Message: errors.Message{
+		EN: "Only admins can issue invoices and see the invoices of other members",
+		FR: "Seuls les administrateurs peuvent émettre des factures et voir les factures des autres membres",
+	},
+	ApiError: 401,
+}
+
+var InvoiceInvalidMonthError = errors.AviatorError{
+	Id: "invoice_invalid_month",
+	Message: errors.Message{
+		EN: "The invoiced month must be formatted as YYYY-MM",
+		FR: "Le mois facturé doit être au format AAAA-MM",
+	},
+	ApiError: 400,
+}
+
+var InvoiceInvalidMemberError = errors.AviatorError{
+	Id: "invoice_invalid_member",
+	Message: errors.Message{
+		EN: "The invoiced member does not exist",
+		FR: "Le membre facturé n'existe pas",
+	},
+	ApiError: 400,
+}
+
+var InvoiceMissingCreditorError = errors.AviatorError{
+	Id: "invoice_missing_creditor",
+	Message: errors.Message{
+		EN: "The club requires a QR-IBAN and an address to issue invoices",
+		FR: "Le club requiert un QR-IBAN et une adresse pour émettre des factures",
+	},
+	ApiError: 400,
+}
+
+var InvoiceNothingDueError = errors.AviatorError{
+	Id: "invoice_nothing_due",
+	Message: errors.Message{
+		EN: "The member owes nothing for this month",
+		FR: "Le membre ne doit rien pour ce mois",
+	},
+	ApiError: 400,
+}
+
+var InvoiceAlreadyExistsError = errors.AviatorError{
+	Id: "invoice_already_exists",
+	Message: errors.Message{
+		EN: "The member has already been invoiced for this month",
+		FR: "Le membre a déjà été facturé pour ce mois",
+	},
+	ApiError: 409,
+}
@@ -0,0 +1,416 @@
+/*
+Package invoice provides methods for issuing the monthly invoices of the members of a club as Swiss QR-bills.
+An invoice groups the charges of a member over a month, and is never modified once issued.
+*/
+package invoice
+
+import (
+	"aviator/billing"
+	"aviator/club"
+	"aviator/constants"
+	"aviator/database"
+	"aviator/member"
+	"aviator/tenant"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const INVOICE_PARTITION_KEY = "INVOICE"
+
+// Sort key of the item counting the invoices of a club, which numbers their QR references
+const INVOICE_COUNTER_SORT_KEY = "INVOICE_COUNTER"
+
+// Format of the invoiced months
+const MONTH_FORMAT = "2006-01"
+
+// Number of times an invoice is retried when a concurrent invoice took its number
+const MAX_WRITE_ATTEMPTS = 3
+
+type InvoiceApiInterface interface {
+	Logger() *slog.Logger
+	SetLogger(logger *slog.Logger)
+	Generate(memberId string, month string) (*Invoice, error)
+	Get(memberId string, month string) (*Invoice, error)
+	List(memberId string, input ListInput) (*ListOutput, error)
+	PDF(memberId string, month string) ([]byte, error)
+}
+
+type Config struct {
+	tenant.Scope
+}
+
+type Client struct {
+	Config
+}
+
+// Item used to store an invoice
+type Invoice struct {
+	// Member Id of the invoiced member: e.g. 01H55420KY47HRVVPK1Z3BSACK
+	MemberId string `json:"memberId"`
+	// Invoiced month, in the timezone of the club: e.g. 2024-04
+	Month string `json:"month"`
+	// QR reference of the payment, 27 digits: e.g. 000000000000000000000000012
+	Reference string `json:"reference"`
+	// Amount due in centimes: e.g. 31160
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	// QR-IBAN of the club when the invoice was issued: e.g. CH4431999123000889012
+	QrIban   string `json:"qrIban"`
+	Creditor Party  `json:"creditor"`
+	// Invoiced member, without address when the member has none
+	Debtor *Party `dynamodbav:",omitempty" json:"debtor"`
+	// Language of the printed bill: en or fr
+	Language string `json:"language"`
+	// Timezone of the club, in which the dates of the charges are printed: e.g. Europe/Zurich
+	Timezone string           `json:"timezone"`
+	Charges  []billing.Charge `json:"charges"`
+	// Swiss Payment Standards payload encoded in the QR code
+	Payload   string    `json:"payload"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Database item to store the invoice.
+type databaseItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key: e.g. INVOICE#01H55420KY47HRVVPK1Z3BSACK#2024-04
+	SK string
+
+	// Item type: invoice
+	ItemType string
+	Invoice
+}
+
+// Database item counting the invoices of a club.
+type counterItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key: INVOICE_COUNTER
+	SK string
+
+	// Item type: invoiceCounter
+	ItemType string
+	// Number of the last issued invoice
+	Number int
+}
+
+// Returns a new invoice API client from the provided config.
+func NewFromConfig(c Config) *Client {
+	return &Client{Config: c}
+}
+
+func (c *Client) Logger() *slog.Logger {
+	return c.Config.Logger
+}
+
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.Config.Logger = logger
+}
+
+func (c *Client) invoiceSK(memberId string, month string) string {
+	return fmt.Sprintf("%s#%s#%s", INVOICE_PARTITION_KEY, memberId, month)
+}
+
+// Issues the invoice of a member for the charges of a month. Only admins can issue invoices.
+// The club must have a QR-IBAN and an address, and each member is invoiced at most once per month.
+func (c *Client) Generate(memberId string, month string) (*Invoice, error) {
+	c.SetLogger(c.Logger().With("member", memberId, "month", month))
+	c.Logger().Info("generating invoice")
+
+	if c.UserRole != constants.ROLE_ADMIN {
+		return nil, InvoiceAccessDenyError
+	}
+	monthStart, err := time.Parse(MONTH_FORMAT, month)
+	if err != nil {
+		return nil, InvoiceInvalidMonthError
+	}
+
+	existing, err := c.getItem(memberId, month)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, InvoiceAlreadyExistsError
+	}
+
+	input, err := c.newInvoice(memberId, month, monthStart)
+	if err != nil {
+		return nil, err
+	}
+
+	var out *Invoice
+	for attempt := 1; ; attempt++ {
+		out, err = c.write(*input)
+		if err == nil {
+			break
+		}
+		if !database.IsConditionalCheckFailure(err) || attempt == MAX_WRITE_ATTEMPTS {
+			return nil, err
+		}
+		existing, err := c.getItem(memberId, month)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, InvoiceAlreadyExistsError
+		}
+		c.Logger().Warn("concurrent invoice detected, retrying", "attempt", attempt)
+	}
+
+	c.Logger().Info("invoice generated", "reference", out.Reference, "amount", out.Amount)
+	return out, nil
+}
+
+// Builds the invoice of a member from the club, the member and the statement of the month, without its reference.
+func (c *Client) newInvoice(memberId string, month string, monthStart time.Time) (*Invoice, error) {
+	clubClient := club.NewFromConfig(club.Config{Scope: c.Scope})
+	invoicingClub, err := clubClient.Get(c.TenantId)
+	if errors.Is(err, club.ClubNotFoundError) {
+		return nil, InvoiceMissingCreditorError
+	}
+	if err != nil {
+		return nil, err
+	}
+	if invoicingClub.QrIban == "" || invoicingClub.Address == nil {
+		return nil, InvoiceMissingCreditorError
+	}
+	location, err := time.LoadLocation(invoicingClub.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	memberClient := member.NewFromConfig(member.Config{Scope: c.Scope})
+	invoicedMember, err := memberClient.Get(memberId)
+	if errors.Is(err, member.MemberNotFoundError) {
+		return nil, InvoiceInvalidMemberError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The month is invoiced in the timezone of the club, charges are dated to the second
+	start := time.Date(monthStart.Year(), monthStart.Month(), 1, 0, 0, 0, 0, location)
+	billingClient := billing.NewFromConfig(billing.Config{Scope: c.Scope})
+	statement, err := billingClient.Statement(memberId, billing.StatementInput{
+		Start: start,
+		End:   start.AddDate(0, 1, 0).Add(-time.Second),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if statement.Total <= 0 {
+		return nil, InvoiceNothingDueError
+	}
+
+	var debtor *Party
+	if invoicedMember.Address != nil {
+		debtor = &Party{
+			Name:    strings.TrimSpace(invoicedMember.FirstName + " " + invoicedMember.LastName),
+			Address: *invoicedMember.Address,
+		}
+	}
+	return &Invoice{
+		MemberId: memberId,
+		Month:    month,
+		Amount:   statement.Total,
+		Currency: statement.Currency,
+		QrIban:   invoicingClub.QrIban,
+		Creditor: Party{Name: invoicingClub.Name, Address: *invoicingClub.Address},
+		Debtor:   debtor,
+		Language: invoicingClub.Language,
+		Timezone: location.String(),
+		Charges:  statement.Charges,
+	}, nil
+}
+
+// Numbers the invoice with the next QR reference of the club and stores it along with the counter in a single transaction.
+func (c *Client) write(input Invoice) (*Invoice, error) {
+	output, err := c.DatabaseClient.Get(database.GetInput{
+		PK:             c.clubPK(),
+		SK:             INVOICE_COUNTER_SORT_KEY,
+		ConsistentRead: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	counter := counterItem{PK: c.clubPK(), SK: INVOICE_COUNTER_SORT_KEY, ItemType: "invoiceCounter"}
+	if output.Item != nil {
+		err = attributevalue.UnmarshalMap(output.Item, &counter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The counter must not have moved since it was read, or a concurrent invoice took the number
+	counterPut := &types.Put{
+		TableName:           aws.String(c.DatabaseClient.TableName),
+		ConditionExpression: aws.String("attribute_not_exists(PK)"),
+	}
+	if counter.Number > 0 {
+		counterPut.ConditionExpression = aws.String("#number = :number")
+		counterPut.ExpressionAttributeNames = map[string]string{"#number": "Number"}
+		counterPut.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":number": &types.AttributeValueMemberN{Value: fmt.Sprint(counter.Number)},
+		}
+	}
+	counter.Number++
+	counterPut.Item, err = attributevalue.MarshalMap(counter)
+	if err != nil {
+		return nil, err
+	}
+
+	input.Reference = qrReference(counter.Number)
+	input.Payload = spcPayload(input.QrIban, input.Creditor, input.Amount, input.Currency, input.Debtor, input.Reference,
+		invoiceMessage(input.Language, input.Month))
+	input.CreatedAt = time.Now().UTC()
+	invoiceMap, err := attributevalue.MarshalMap(databaseItem{
+		PK:       c.clubPK(),
+		SK:       c.invoiceSK(input.MemberId, input.Month),
+		ItemType: "invoice",
+		Invoice:  input,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.DatabaseClient.TransactWriteItems(dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{
+				TableName:           aws.String(c.DatabaseClient.TableName),
+				Item:                invoiceMap,
+				ConditionExpression: aws.String("attribute_not_exists(PK)"),
+			}},
+			{Put: counterPut},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &input, nil
+}
+
+// Returns true if the admin or the invoiced member is the caller.
+func (c *Client) canRead(memberId string) bool {
+	return c.UserRole == constants.ROLE_ADMIN || memberId == c.UserId
+}
+
+// Returns the invoice of a member for a month. Members can only read their own invoices.
+func (c *Client) Get(memberId string, month string) (*Invoice, error) {
+	c.SetLogger(c.Logger().With("member", memberId, "month", month))
+	c.Logger().Info("retrieving invoice")
+
+	if !c.canRead(memberId) {
+		return nil, InvoiceAccessDenyError
+	}
+
+	invoice, err := c.getItem(memberId, month)
+	if err != nil {
+		return nil, err
+	}
+	if invoice == nil {
+		return nil, InvoiceNotFoundError
+	}
+
+	c.Logger().Info("invoice retrieved")
+	return invoice, nil
+}
+
+// Returns the stored invoice, or nil if it does not exist.
+func (c *Client) getItem(memberId string, month string) (*Invoice, error) {
+	output, err := c.DatabaseClient.Get(database.GetInput{
+		PK: c.clubPK(),
+		SK: c.invoiceSK(memberId, month),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.Item == nil {
+		return nil, nil
+	}
+
+	invoice := new(Invoice)
+	err = attributevalue.UnmarshalMap(output.Item, invoice)
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// Returns the printable QR-bill of an invoice as a PDF file. Members can only print their own invoices.
+func (c *Client) PDF(memberId string, month string) ([]byte, error) {
+	invoice, err := c.Get(memberId, month)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("printing invoice")
+	return renderInvoice(*invoice)
+}
+
+type ListInput struct {
+	NextToken *string
+	Limit     *int32
+}
+
+type ListOutput struct {
+	NextToken *string   `json:"nextToken"`
+	Results   []Invoice `json:"results"`
+}
+
+// Returns the invoices of a member, ordered by month. Members can only list their own invoices.
+func (c *Client) List(memberId string, input ListInput) (*ListOutput, error) {
+	c.SetLogger(c.Logger().With("member", memberId))
+	c.Logger().Info("listing invoices")
+
+	if !c.canRead(memberId) {
+		return nil, InvoiceAccessDenyError
+	}
+
+	exclusiveStartKey, err := database.DecodeNextToken(input.NextToken, c.clubPK())
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.DatabaseClient.Query(&database.QueryInput{
+		Limit:                  input.Limit,
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: c.clubPK()},
+			":sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("%s#%s#", INVOICE_PARTITION_KEY, memberId)},
+		},
+		ExclusiveStartKey: exclusiveStartKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	invoices := make([]Invoice, 0)
+	err = attributevalue.UnmarshalListOfMaps(output.Items, &invoices)
+	if err != nil {
+		return nil, err
+	}
+
+	nextToken, err := database.EncodeNextToken(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("invoices listed", "count", len(invoices), "isNextToken", nextToken != nil)
+	return &ListOutput{
+		NextToken: nextToken,
+		Results:   invoices,
+	}, nil
+}
+
+// Returns the partition key of the tenant club owning the invoice.
+func (c *Client) clubPK() string {
+	return fmt.Sprintf("%s#%s", constants.CLUB_PARTITION_KEY, c.TenantId)
+}
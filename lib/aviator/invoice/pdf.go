@@ -0,0 +1,129 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Points per millimetre
+const MM = 72 / 25.4
+
+// Size of an A4 page in millimetres
+const PAGE_WIDTH = 210.0
+const PAGE_HEIGHT = 297.0
+
+// Minimal PDF writer drawing text with the standard Helvetica fonts, lines and filled rectangles on A4 pages.
+// Coordinates are in millimetres from the top left corner of the page.
+type pdfDocument struct {
+	pages []*bytes.Buffer
+}
+
+// Starts a new page, on which the following calls draw.
+func (d *pdfDocument) addPage() {
+	d.pages = append(d.pages, new(bytes.Buffer))
+}
+
+func (d *pdfDocument) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// Draws text with its baseline at y.
+func (d *pdfDocument) text(x float64, y float64, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x*MM, (PAGE_HEIGHT-y)*MM, pdfString(s))
+}
+
+// Draws text ending at x, only accurate for numbers.
+func (d *pdfDocument) textRight(x float64, y float64, size float64, bold bool, s string) {
+	d.text(x-textWidth(s, size), y, size, bold, s)
+}
+
+// Fills a rectangle in the given gray level, from 0 (black) to 1 (white).
+func (d *pdfDocument) rect(x float64, y float64, width float64, height float64, gray float64) {
+	fmt.Fprintf(d.page(), "%.2f g %.2f %.2f %.2f %.2f re f 0 g\n", gray, x*MM, (PAGE_HEIGHT-y-height)*MM, width*MM, height*MM)
+}
+
+// Draws a thin line, dashed for the perforations of the payment part.
+func (d *pdfDocument) line(x1 float64, y1 float64, x2 float64, y2 float64, dashed bool) {
+	dash := "[] 0 d"
+	if dashed {
+		dash = "[2 2] 0 d"
+	}
+	fmt.Fprintf(d.page(), "%s 0.5 w %.2f %.2f m %.2f %.2f l S\n", dash, x1*MM, (PAGE_HEIGHT-y1)*MM, x2*MM, (PAGE_HEIGHT-y2)*MM)
+}
+
+// Returns the PDF file of the document.
+func (d *pdfDocument) bytes() []byte {
+	// Objects 1 and 2 are the catalog and the page tree, 3 and 4 the fonts, then a page and its content per page
+	objects := []string{"", "", "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>"}
+	kids := make([]string, 0, len(d.pages))
+	for _, content := range d.pages {
+		pageObject := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageObject))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				PAGE_WIDTH*MM, PAGE_HEIGHT*MM, pageObject+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+	objects[0] = "<< /Type /Catalog /Pages 2 0 R >>"
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	out := new(bytes.Buffer)
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := out.Len()
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+// Encodes text in WinAnsiEncoding as a PDF literal string, characters outside of Latin-1 are replaced.
+func pdfString(s string) string {
+	out := new(strings.Builder)
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case r == '’':
+			out.WriteByte(0x92)
+		case r == '€':
+			out.WriteByte(0x80)
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			out.WriteByte(byte(r))
+		default:
+			out.WriteByte('?')
+		}
+	}
+	return out.String()
+}
+
+// Returns the width of a number in millimetres in Helvetica, from the widths of its glyphs in thousandths of the font size.
+func textWidth(s string, size float64) float64 {
+	width := 0
+	for _, r := range s {
+		switch r {
+		case ' ', '.', ',':
+			width += 278
+		case '-':
+			width += 333
+		case '\'':
+			width += 191
+		default:
+			width += 556
+		}
+	}
+	return float64(width) / 1000 * size / MM
+}
@@ -0,0 +1,132 @@
+package invoice
+
+import (
+	"aviator/member"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"rsc.io/qr"
+)
+
+// Header of the Swiss Payment Standards payload: QR type, version 2.0 and UTF-8 coding
+var spcHeader = []string{"SPC", "0200", "1"}
+
+// Reference type of a QR reference, the only one allowed with a QR-IBAN
+const REFERENCE_TYPE_QRR = "QRR"
+
+// End of the payment data of the payload
+const TRAILER = "EPD"
+
+// Creditor or debtor of an invoice
+type Party struct {
+	Name    string         `json:"name"`
+	Address member.Address `json:"address"`
+}
+
+// Returns the 27 digits QR reference of an invoice number, the last digit being the modulo 10 recursive check digit.
+func qrReference(number int) string {
+	digits := fmt.Sprintf("%026d", number)
+	return digits + strconv.Itoa(mod10Recursive(digits))
+}
+
+// Returns the check digit of a string of digits with the modulo 10 recursive algorithm of the Swiss Payment Standards.
+func mod10Recursive(digits string) int {
+	table := [10]int{0, 9, 4, 6, 8, 2, 7, 1, 3, 5}
+	carry := 0
+	for _, d := range digits {
+		carry = table[(carry+int(d-'0'))%10]
+	}
+	return (10 - carry) % 10
+}
+
+// Returns the lines of a party in the payload, with a structured address. Empty lines stand for a missing party.
+func partyLines(party *Party) []string {
+	if party == nil {
+		return make([]string, 7)
+	}
+	return []string{"S", truncate(party.Name, 70), party.Address.Street, party.Address.BuildingNumber,
+		party.Address.PostalCode, party.Address.Town, party.Address.Country}
+}
+
+// Returns the Swiss Payment Standards payload encoded in the QR code of a QR-bill.
+// Unused optional elements, like the ultimate creditor and the alternative procedures, are left empty or omitted.
+func spcPayload(iban string, creditor Party, amount int64, currency string, debtor *Party, reference string, message string) string {
+	lines := append([]string{}, spcHeader...)
+	lines = append(lines, iban)
+	lines = append(lines, partyLines(&creditor)...)
+	// Ultimate creditor, reserved for future use
+	lines = append(lines, partyLines(nil)...)
+	lines = append(lines, formatPayloadAmount(amount), currency)
+	lines = append(lines, partyLines(debtor)...)
+	lines = append(lines, REFERENCE_TYPE_QRR, reference, truncate(message, 140), TRAILER)
+	return strings.Join(lines, "\n")
+}
+
+// Returns the QR code of a payload, with the medium error correction level required by the Swiss Payment Standards.
+func qrCode(payload string) (*qr.Code, error) {
+	return qr.Encode(payload, qr.M)
+}
+
+// Returns an amount in centimes as in the payload: e.g. 1949.75
+func formatPayloadAmount(amount int64) string {
+	return fmt.Sprintf("%d.%02d", amount/100, amount%100)
+}
+
+// Returns an amount in centimes as printed on the bill, with spaces between thousands: e.g. 1 949.75
+func formatAmount(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	units := strconv.FormatInt(amount/100, 10)
+	groups := make([]string, 0)
+	for len(units) > 3 {
+		groups = append([]string{units[len(units)-3:]}, groups...)
+		units = units[:len(units)-3]
+	}
+	groups = append([]string{units}, groups...)
+	return fmt.Sprintf("%s%s.%02d", sign, strings.Join(groups, " "), amount%100)
+}
+
+// Returns a QR reference as printed on the bill, in blocks of 5 digits from the right: e.g. 21 00000 00003 13947 14300 09017
+func formatReference(reference string) string {
+	blocks := make([]string, 0)
+	for len(reference) > 5 {
+		blocks = append([]string{reference[len(reference)-5:]}, blocks...)
+		reference = reference[:len(reference)-5]
+	}
+	return strings.Join(append([]string{reference}, blocks...), " ")
+}
+
+// Returns an IBAN as printed on the bill, in blocks of 4 characters: e.g. CH44 3199 9123 0008 8901 2
+func formatIban(iban string) string {
+	blocks := make([]string, 0)
+	for len(iban) > 4 {
+		blocks = append(blocks, iban[:4])
+		iban = iban[4:]
+	}
+	return strings.Join(append(blocks, iban), " ")
+}
+
+// Returns the address lines printed on the bill under the name of a party.
+func addressLines(address member.Address) []string {
+	street := strings.TrimSpace(address.Street + " " + address.BuildingNumber)
+	town := address.PostalCode + " " + address.Town
+	if address.Country != "CH" {
+		town = address.Country + "-" + town
+	}
+	if street == "" {
+		return []string{town}
+	}
+	return []string{street, town}
+}
+
+// Truncates a string to a maximum number of characters.
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	return string(runes[:length])
+}
@@ -0,0 +1,150 @@
+package invoice
+
+import (
+	"aviator/member"
+	"strings"
+	"testing"
+)
+
+// QR references of the examples of the Swiss Implementation Guidelines for the QR-bill
+func TestMod10Recursive(t *testing.T) {
+	tests := []struct {
+		reference string
+	}{
+		{"210000000003139471430009017"},
+		{"000008207791225857421286694"},
+		{"110001234560000000000813457"},
+	}
+	for _, test := range tests {
+		digits, want := test.reference[:26], int(test.reference[26]-'0')
+		if got := mod10Recursive(digits); got != want {
+			t.Errorf("mod10Recursive(%s) = %d, want %d", digits, got, want)
+		}
+	}
+}
+
+func TestQrReference(t *testing.T) {
+	tests := []struct {
+		number int
+		want   string
+	}{
+		{1, "000000000000000000000000011"},
+		{12345, "000000000000000000000123457"},
+		{20240001, "000000000000000000202400017"},
+		{999999999, "000000000000000009999999995"},
+	}
+	for _, test := range tests {
+		got := qrReference(test.number)
+		if got != test.want {
+			t.Errorf("qrReference(%d) = %s, want %s", test.number, got, test.want)
+		}
+		// A reference checks itself: the check digit of all 27 digits is 0
+		if mod10Recursive(got) != 0 {
+			t.Errorf("qrReference(%d) = %s does not check itself", test.number, got)
+		}
+	}
+}
+
+func TestSpcPayload(t *testing.T) {
+	creditor := Party{
+		Name:    "Robert Schneider AG",
+		Address: member.Address{Street: "Rue du Lac", BuildingNumber: "1268", PostalCode: "2501", Town: "Biel", Country: "CH"},
+	}
+	debtor := Party{
+		Name: "Pia-Maria Rutschmann-Schnyder",
+		Address: member.Address{Street: "Grosse Marktgasse", BuildingNumber: "28", PostalCode: "9400", Town: "Rorschach",
+			Country: "CH"},
+	}
+	emptyParty := []string{"", "", "", "", "", "", ""}
+	creditorLines := []string{"S", "Robert Schneider AG", "Rue du Lac", "1268", "2501", "Biel", "CH"}
+	debtorLines := []string{"S", "Pia-Maria Rutschmann-Schnyder", "Grosse Marktgasse", "28", "9400", "Rorschach", "CH"}
+	lines := func(parts ...[]string) string {
+		all := make([]string, 0)
+		for _, part := range parts {
+			all = append(all, part...)
+		}
+		return strings.Join(all, "\n")
+	}
+
+	tests := []struct {
+		name    string
+		amount  int64
+		debtor  *Party
+		message string
+		want    string
+	}{
+		{
+			name:    "with a debtor",
+			amount:  194975,
+			debtor:  &debtor,
+			message: "Order of 15 June 2020",
+			want: lines([]string{"SPC", "0200", "1", "CH4431999123000889012"}, creditorLines, emptyParty,
+				[]string{"1949.75", "CHF"}, debtorLines,
+				[]string{"QRR", "210000000003139471430009017", "Order of 15 June 2020", "EPD"}),
+		},
+		{
+			name:   "without a debtor nor a message",
+			amount: 5,
+			want: lines([]string{"SPC", "0200", "1", "CH4431999123000889012"}, creditorLines, emptyParty,
+				[]string{"0.05", "CHF"}, emptyParty,
+				[]string{"QRR", "210000000003139471430009017", "", "EPD"}),
+		},
+	}
+	for _, test := range tests {
+		got := spcPayload("CH4431999123000889012", creditor, test.amount, "CHF", test.debtor,
+			"210000000003139471430009017", test.message)
+		if got != test.want {
+			t.Errorf("%s: payload\n%q\nwant\n%q", test.name, got, test.want)
+		}
+		// Header, account, creditor, ultimate creditor, amount, currency, debtor, reference type, reference,
+		// message and trailer
+		if count := len(strings.Split(got, "\n")); count != 31 {
+			t.Errorf("%s: payload of %d lines, want 31", test.name, count)
+		}
+	}
+}
+
+func TestSpcPayloadTruncation(t *testing.T) {
+	creditor := Party{Name: strings.Repeat("é", 80)}
+	payload := strings.Split(spcPayload("CH4431999123000889012", creditor, 100, "CHF", nil, "", strings.Repeat("m", 150)), "\n")
+	if name := payload[5]; len([]rune(name)) != 70 {
+		t.Errorf("creditor name of %d characters, want 70", len([]rune(name)))
+	}
+	if message := payload[len(payload)-2]; len(message) != 140 {
+		t.Errorf("message of %d characters, want 140", len(message))
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount        int64
+		want          string
+		wantInPayload string
+	}{
+		{194975, "1 949.75", "1949.75"},
+		{5, "0.05", "0.05"},
+		{100000000, "1 000 000.00", "1000000.00"},
+		{99900, "999.00", "999.00"},
+		{-123456, "-1 234.56", ""},
+	}
+	for _, test := range tests {
+		if got := formatAmount(test.amount); got != test.want {
+			t.Errorf("formatAmount(%d) = %s, want %s", test.amount, got, test.want)
+		}
+		if test.wantInPayload == "" {
+			continue
+		}
+		if got := formatPayloadAmount(test.amount); got != test.wantInPayload {
+			t.Errorf("formatPayloadAmount(%d) = %s, want %s", test.amount, got, test.wantInPayload)
+		}
+	}
+}
+
+func TestFormatReferenceAndIban(t *testing.T) {
+	if got := formatReference("210000000003139471430009017"); got != "21 00000 00003 13947 14300 09017" {
+		t.Errorf("formatReference = %s", got)
+	}
+	if got := formatIban("CH4431999123000889012"); got != "CH44 3199 9123 0008 8901 2" {
+		t.Errorf("formatIban = %s", got)
+	}
+}
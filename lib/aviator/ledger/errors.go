@@ -0,0 +1,48 @@
+package ledger
+
+import "aviator/errors"
+
+var LedgerAccessDenyError = errors.AviatorError{
+	Id: "ledger_access_deny",
+	Message: errors.Message{
+		EN: "Only admins can record entries and see the accounts of other members",
+		FR: "Seuls les administrateurs peuvent enregistrer des écritures et voir les comptes des autres membres",
+	},
+	ApiError: 401,
+}
+
+var LedgerInvalidKindError = errors.AviatorError{
+	Id: "ledger_invalid_kind",
+	Message: errors.Message{
+		EN: "An entry is either a deposit, a charge or an adjustment",
+		FR: "Une écriture est soit un dépôt, soit une charge, soit un ajustement",
+	},
+	ApiError: 400,
+}
+
+var LedgerInvalidAmountError = errors.AviatorError{
+	Id: "ledger_invalid_amount",
+	Message: errors.Message{
+		EN: "Deposits must be positive, charges negative and adjustments different from zero",
+		FR: "Les dépôts doivent être positifs, les charges négatives et les ajustements différents de zéro",
+	},
+	ApiError: 400,
+}
+
+var LedgerDescriptionRequiredError = errors.AviatorError{
+	Id: "ledger_description_required",
+	Message: errors.Message{
+		EN: "Adjustments require a description",
+		FR: "Les ajustements requièrent une description",
+	},
+	ApiError: 400,
+}
+
+var LedgerInvalidMemberError = errors.AviatorError{
+	Id: "ledger_invalid_member",
+	Message: errors.Message{
+		EN: "The account holder is not a member of the club",
+		FR: "Le titulaire du compte n'est pas membre du club",
+	},
+	ApiError: 400,
+}
@@ -0,0 +1,396 @@
+/*
+Package ledger provides methods for keeping the prepaid accounts of the members of a club.
+Every entry is posted twice, to the account of the member and to a counterpart account of the club,
+so the balances of all the accounts of a club always sum to zero.
+*/
+package ledger
+
+import (
+	"aviator/billing"
+	"aviator/constants"
+	"aviator/database"
+	"aviator/member"
+	"aviator/tenant"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/oklog/ulid/v2"
+)
+
+const LEDGER_PARTITION_KEY = "LEDGER"
+const ACCOUNT_PARTITION_KEY = "ACCOUNT"
+
+// Money paid in by a member, credited to the member and debited from the cash of the club
+const KIND_DEPOSIT = "deposit"
+
+// Flights and fees charged to a member, debited from the member and credited to the revenue of the club
+const KIND_CHARGE = "charge"
+
+// Corrections in either direction, posted against the adjustments account of the club
+const KIND_ADJUSTMENT = "adjustment"
+
+// Counterpart accounts of the club
+const ACCOUNT_CASH = "CLUB#cash"
+const ACCOUNT_REVENUE = "CLUB#revenue"
+const ACCOUNT_ADJUSTMENTS = "CLUB#adjustments"
+
+// Counterpart account of each kind of entry
+var counterparts = map[string]string{
+	KIND_DEPOSIT:    ACCOUNT_CASH,
+	KIND_CHARGE:     ACCOUNT_REVENUE,
+	KIND_ADJUSTMENT: ACCOUNT_ADJUSTMENTS,
+}
+
+type LedgerApiInterface interface {
+	Logger() *slog.Logger
+	SetLogger(logger *slog.Logger)
+	Record(input Entry) (*Entry, error)
+	List(memberId string, input ListInput) (*ListOutput, error)
+	Balance(memberId string) (*Balance, error)
+}
+
+type Config struct {
+	tenant.Scope
+}
+
+type Client struct {
+	Config
+}
+
+// Item used to store an entry of the ledger of a member
+type Entry struct {
+	// Entry Id, ordered by creation time: e.g. 01HS2B7Y8YJ0G5B3RM2Q2R7C9F
+	Id string `json:"id"`
+	// Member Id of the account holder: e.g. 01H55420KY47HRVVPK1Z3BSACK
+	MemberId string `json:"memberId"`
+	// Either deposit, charge or adjustment
+	Kind string `json:"kind"`
+	// Amount in centimes credited to the member, negative for debits: e.g. 50000
+	Amount int64 `json:"amount"`
+	// Free text shown on the ledger of the member: e.g. Bank transfer of 2024-04-02
+	Description string `json:"description"`
+	// Id of the charged reservation, for charges of flights (if any)
+	ReservationId *string `dynamodbav:",omitempty" json:"reservationId"`
+	// Account of the club posted against the member account, set from the kind: e.g. CLUB#cash
+	Counterpart string `json:"counterpart"`
+	// Member Id of the admin who recorded the entry, set from the authenticated caller
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Database item to store the entry.
+type databaseItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key, ordered by creation time: e.g. LEDGER#01H55420KY47HRVVPK1Z3BSACK#01HS2B7Y8YJ0G5B3RM2Q2R7C9F
+	SK string
+
+	// Item type: ledgerEntry
+	ItemType string
+	Entry
+}
+
+// Balance of the prepaid account of a member
+type Balance struct {
+	// Member Id of the account holder: e.g. 01H55420KY47HRVVPK1Z3BSACK
+	MemberId string `json:"memberId"`
+	// Balance in centimes, negative when the member owes money to the club: e.g. 18840
+	Balance  int64  `json:"balance"`
+	Currency string `json:"currency"`
+	// Time of the last entry, zero when the account has none
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Database item storing the running balance of an account, updated along with each entry.
+type accountItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key: e.g. ACCOUNT#MEMBER#01H55420KY47HRVVPK1Z3BSACK or ACCOUNT#CLUB#cash
+	SK string
+
+	// Item type: ledgerAccount
+	ItemType  string
+	Balance   int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Returns a new ledger API client from the provided config.
+func NewFromConfig(c Config) *Client {
+	return &Client{Config: c}
+}
+
+func (c *Client) Logger() *slog.Logger {
+	return c.Config.Logger
+}
+
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.Config.Logger = logger
+}
+
+// Returns the account Id of a member: e.g. MEMBER#01H55420KY47HRVVPK1Z3BSACK
+func memberAccount(memberId string) string {
+	return fmt.Sprintf("MEMBER#%s", memberId)
+}
+
+func (c *Client) accountSK(account string) string {
+	return fmt.Sprintf("%s#%s", ACCOUNT_PARTITION_KEY, account)
+}
+
+// Records a deposit, a charge or an adjustment on the account of a member. Only admins can record entries.
+// Deposits must be positive, charges negative and adjustments not zero.
+func (c *Client) Record(input Entry) (*Entry, error) {
+	c.SetLogger(c.Logger().With("member", input.MemberId, "kind", input.Kind, "amount", input.Amount))
+	c.Logger().Info("recording ledger entry")
+
+	if c.UserRole != constants.ROLE_ADMIN {
+		return nil, LedgerAccessDenyError
+	}
+	switch {
+	case input.Kind == KIND_DEPOSIT && input.Amount <= 0,
+		input.Kind == KIND_CHARGE && input.Amount >= 0,
+		input.Kind == KIND_ADJUSTMENT && input.Amount == 0:
+		return nil, LedgerInvalidAmountError
+	case input.Kind != KIND_DEPOSIT && input.Kind != KIND_CHARGE && input.Kind != KIND_ADJUSTMENT:
+		return nil, LedgerInvalidKindError
+	}
+	input.Description = strings.TrimSpace(input.Description)
+	if input.Kind == KIND_ADJUSTMENT && input.Description == "" {
+		return nil, LedgerDescriptionRequiredError
+	}
+	memberClient := member.NewFromConfig(member.Config{Scope: c.Scope})
+	_, err := memberClient.Get(input.MemberId)
+	if errors.Is(err, member.MemberNotFoundError) {
+		return nil, LedgerInvalidMemberError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	input.CreatedBy = c.UserId
+	transactItems, out, err := c.newPostings(input)
+	if err != nil {
+		return nil, err
+	}
+	_, err = c.DatabaseClient.TransactWriteItems(dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("ledger entry recorded", "entry", out.Id)
+	return out, nil
+}
+
+// Returns the transaction items debiting the revenue of the club and charging the member for the charges of a flight,
+// so the ledger is written along with the charges. Returns no items when the charges sum to zero.
+func (c *Client) NewChargeItems(memberId string, reservationId string, charges []billing.Charge) ([]types.TransactWriteItem, error) {
+	total := int64(0)
+	for _, charge := range charges {
+		total += charge.Amount
+	}
+	if total == 0 {
+		return nil, nil
+	}
+
+	transactItems, _, err := c.newPostings(Entry{
+		MemberId:      memberId,
+		Kind:          KIND_CHARGE,
+		Amount:        -total,
+		Description:   fmt.Sprintf("Flight %s", reservationId),
+		ReservationId: aws.String(reservationId),
+		CreatedBy:     c.UserId,
+	})
+	return transactItems, err
+}
+
+// Returns the transaction items storing an entry and moving its amount between the account of the member
+// and the counterpart account of the club. They must be written in a single transaction to keep the accounts balanced.
+func (c *Client) newPostings(input Entry) ([]types.TransactWriteItem, *Entry, error) {
+	input.Id = ulid.Make().String()
+	input.Counterpart = counterparts[input.Kind]
+	input.CreatedAt = time.Now().UTC()
+
+	item, err := attributevalue.MarshalMap(databaseItem{
+		PK:       c.clubPK(),
+		SK:       fmt.Sprintf("%s#%s#%s", LEDGER_PARTITION_KEY, input.MemberId, input.Id),
+		ItemType: "ledgerEntry",
+		Entry:    input,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return []types.TransactWriteItem{
+		{Put: &types.Put{
+			TableName:           aws.String(c.DatabaseClient.TableName),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(PK)"),
+		}},
+		{Update: c.newAccountUpdate(memberAccount(input.MemberId), input.Amount)},
+		{Update: c.newAccountUpdate(input.Counterpart, -input.Amount)},
+	}, &input, nil
+}
+
+// Returns the update adding an amount to the balance of an account, creating the account on its first entry.
+func (c *Client) newAccountUpdate(account string, amount int64) *types.Update {
+	return &types.Update{
+		TableName: aws.String(c.DatabaseClient.TableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: c.clubPK()},
+			"SK": &types.AttributeValueMemberS{Value: c.accountSK(account)},
+		},
+		UpdateExpression: aws.String("ADD #balance :amount " +
+			"SET #itemType = :itemType, #createdAt = if_not_exists(#createdAt, :now), #updatedAt = :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#balance":   "Balance",
+			"#itemType":  "ItemType",
+			"#createdAt": "CreatedAt",
+			"#updatedAt": "UpdatedAt",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":amount":   &types.AttributeValueMemberN{Value: fmt.Sprint(amount)},
+			":itemType": &types.AttributeValueMemberS{Value: "ledgerAccount"},
+			":now":      &types.AttributeValueMemberS{Value: time.Now().Format("2006-01-02T15:04:05.000Z")},
+		},
+	}
+}
+
+// Returns the transaction item failing a booking if the balance of the member fell below the minimum it was checked
+// against, e.g. because a charge was recorded in the meantime.
+func (c *Client) NewBalanceCheckItem(memberId string, minimum int64) types.TransactWriteItem {
+	// Members without any entry have a zero balance
+	conditionExpression := "#balance >= :minimum"
+	if minimum <= 0 {
+		conditionExpression = "attribute_not_exists(PK) OR #balance >= :minimum"
+	}
+	return types.TransactWriteItem{ConditionCheck: &types.ConditionCheck{
+		TableName: aws.String(c.DatabaseClient.TableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: c.clubPK()},
+			"SK": &types.AttributeValueMemberS{Value: c.accountSK(memberAccount(memberId))},
+		},
+		ConditionExpression:      aws.String(conditionExpression),
+		ExpressionAttributeNames: map[string]string{"#balance": "Balance"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":minimum": &types.AttributeValueMemberN{Value: fmt.Sprint(minimum)},
+		},
+	}}
+}
+
+// Returns the balance of the prepaid account of a member. Members can only read their own balance.
+func (c *Client) Balance(memberId string) (*Balance, error) {
+	c.SetLogger(c.Logger().With("member", memberId))
+	c.Logger().Info("retrieving balance")
+
+	if !c.canRead(memberId) {
+		return nil, LedgerAccessDenyError
+	}
+
+	balance, err := c.AccountBalance(memberId)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("balance retrieved", "balance", balance.Balance)
+	return balance, nil
+}
+
+// Returns the balance of the prepaid account of a member, without checking the caller may see it.
+// Used to check bookings against the balance of their pilot.
+func (c *Client) AccountBalance(memberId string) (*Balance, error) {
+	output, err := c.DatabaseClient.Get(database.GetInput{
+		PK:             c.clubPK(),
+		SK:             c.accountSK(memberAccount(memberId)),
+		ConsistentRead: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	balance := &Balance{MemberId: memberId, Currency: billing.CURRENCY}
+	if output.Item == nil {
+		return balance, nil
+	}
+	var account accountItem
+	err = attributevalue.UnmarshalMap(output.Item, &account)
+	if err != nil {
+		return nil, err
+	}
+	balance.Balance = account.Balance
+	balance.UpdatedAt = account.UpdatedAt
+	return balance, nil
+}
+
+// Returns true if the admin or the account holder is the caller.
+func (c *Client) canRead(memberId string) bool {
+	return c.UserRole == constants.ROLE_ADMIN || memberId == c.UserId
+}
+
+type ListInput struct {
+	NextToken *string
+	Limit     *int32
+}
+
+type ListOutput struct {
+	NextToken *string `json:"nextToken"`
+	Results   []Entry `json:"results"`
+}
+
+// Returns the entries of the ledger of a member, oldest first. Members can only list their own entries.
+func (c *Client) List(memberId string, input ListInput) (*ListOutput, error) {
+	c.SetLogger(c.Logger().With("member", memberId))
+	c.Logger().Info("listing ledger entries")
+
+	if !c.canRead(memberId) {
+		return nil, LedgerAccessDenyError
+	}
+
+	exclusiveStartKey, err := database.DecodeNextToken(input.NextToken, c.clubPK())
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.DatabaseClient.Query(&database.QueryInput{
+		Limit:                  input.Limit,
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: c.clubPK()},
+			":sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("%s#%s#", LEDGER_PARTITION_KEY, memberId)},
+		},
+		ExclusiveStartKey: exclusiveStartKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0)
+	err = attributevalue.UnmarshalListOfMaps(output.Items, &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	nextToken, err := database.EncodeNextToken(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("ledger entries listed", "count", len(entries), "isNextToken", nextToken != nil)
+	return &ListOutput{
+		NextToken: nextToken,
+		Results:   entries,
+	}, nil
+}
+
+// Returns the partition key of the tenant club owning the ledger.
+func (c *Client) clubPK() string {
+	return fmt.Sprintf("%s#%s", constants.CLUB_PARTITION_KEY, c.TenantId)
+}
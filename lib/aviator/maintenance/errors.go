@@ -0,0 +1,57 @@
+package maintenance
+
+import "aviator/errors"
+
+var MaintenanceNotFoundError = errors.AviatorError{
+	Id: "maintenance_not_found",
+	Message: errors.Message{
+		EN: "The selected maintenance block does not exist",
+		FR: "Le blocage pour maintenance sélectionné n'existe pas",
+	},
+	ApiError: 404,
+}
+
+var MaintenanceAccessDenyError = errors.AviatorError{
+	Id: "maintenance_access_deny",
+	Message: errors.Message{
+		EN: "Only instructors can block aircraft for maintenance",
+		FR: "Seuls les instructeurs peuvent bloquer un appareil pour maintenance",
+	},
+	ApiError: 401,
+}
+
+var MaintenanceInvalidTypeError = errors.AviatorError{
+	Id: "maintenance_invalid_type",
+	Message: errors.Message{
+		EN: "The type of a maintenance block must be scheduled or grounded",
+		FR: "Le type d'un blocage pour maintenance doit être planifié ou immobilisé",
+	},
+	ApiError: 400,
+}
+
+var MaintenanceReasonRequiredError = errors.AviatorError{
+	Id: "maintenance_reason_required",
+	Message: errors.Message{
+		EN: "A reason is required to block an aircraft",
+		FR: "Une raison est requise pour bloquer un appareil",
+	},
+	ApiError: 400,
+}
+
+var MaintenanceInvalidTimesError = errors.AviatorError{
+	Id: "maintenance_invalid_times",
+	Message: errors.Message{
+		EN: "A maintenance block must start before it ends, and a scheduled block must have an end time",
+		FR: "Un blocage pour maintenance doit commencer avant de finir, et un blocage planifié doit avoir une heure de fin",
+	},
+	ApiError: 400,
+}
+
+var MaintenanceInvalidAircraftError = errors.AviatorError{
+	Id: "maintenance_invalid_aircraft",
+	Message: errors.Message{
+		EN: "The selected aircraft does not exist",
+		FR: "L'appareil sélectionné n'existe pas",
+	},
+	ApiError: 400,
+}
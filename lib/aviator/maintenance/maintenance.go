@@ -0,0 +1,422 @@
+/*
+Package maintenance provides methods for blocking aircraft of a club for maintenance, either during a scheduled window
+or by grounding them until further notice.
+*/
+package maintenance
+
+import (
+	"aviator/aircraft"
+	"aviator/constants"
+	"aviator/database"
+	"aviator/tenant"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/oklog/ulid/v2"
+)
+
+const MAINTENANCE_PARTITION_KEY = "MAINTENANCE"
+
+// Maintenance planned between a start and an end time: e.g. a 100-hour inspection
+const TYPE_SCHEDULED = "scheduled"
+
+// Aircraft unavailable from the start time until the block is lifted by setting its end time
+const TYPE_GROUNDED = "grounded"
+
+type MaintenanceApiInterface interface {
+	Logger() *slog.Logger
+	SetLogger(logger *slog.Logger)
+	CreateOrUpdate(input Block) (*Block, error)
+	Get(registration string, blockId string) (*Block, error)
+	List(registration string, input ListInput) (*ListOutput, error)
+	Delete(registration string, blockId string) error
+}
+
+type Config struct {
+	tenant.Scope
+}
+
+type Client struct {
+	Config
+}
+
+// Item used to store a maintenance block of an aircraft
+type Block struct {
+	// Block Id: e.g. 01HRC8J5Q2W7E4R6T8Y0U1I3OP
+	Id string `json:"id"`
+	// Registration of the blocked aircraft: e.g. HB-KFQ
+	Aircraft string `json:"aircraft"`
+	// Either scheduled or grounded
+	BlockType string `json:"blockType"`
+	// Reason shown to the pilots: e.g. 100-hour inspection
+	Reason string `json:"reason"`
+	// Start of the block, defaults to now for a grounding
+	StartTime time.Time `json:"startTime"`
+	// End of the block, required for a scheduled block and empty while a grounded aircraft is not released
+	EndTime *time.Time `dynamodbav:",omitempty" json:"endTime"`
+	// Member Id of the member who created the block, set from the authenticated caller and kept on updates
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Database item to store the maintenance block.
+type databaseItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key: e.g. MAINTENANCE#HB-KFQ#01HRC8J5Q2W7E4R6T8Y0U1I3OP
+	SK string
+
+	// Item type: maintenanceBlock
+	ItemType string
+	Block
+}
+
+// Database item versioned by every write of the maintenance blocks of an aircraft,
+// so reservations validated against the blocks can check in their transaction that none was written since.
+type lockItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key: e.g. LOCK#MAINTENANCE#HB-KFQ
+	SK string
+
+	// Item type: maintenanceLock
+	ItemType string
+	Version  int
+}
+
+// Returns a new maintenance API client from the provided config.
+func NewFromConfig(c Config) *Client {
+	return &Client{Config: c}
+}
+
+func (c *Client) Logger() *slog.Logger {
+	return c.Config.Logger
+}
+
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.Config.Logger = logger
+}
+
+// Returns true if the block makes the aircraft unavailable at some point of the [start, end) interval.
+func (b Block) Overlaps(start time.Time, end time.Time) bool {
+	return b.StartTime.Before(end) && (b.EndTime == nil || start.Before(*b.EndTime))
+}
+
+func (c *Client) blockSK(registration string, blockId string) string {
+	return fmt.Sprintf("%s#%s#%s", MAINTENANCE_PARTITION_KEY, registration, blockId)
+}
+
+func (c *Client) lockSK(registration string) string {
+	return fmt.Sprintf("LOCK#%s#%s", MAINTENANCE_PARTITION_KEY, registration)
+}
+
+func (c *Client) canManage() bool {
+	return c.UserRole == constants.ROLE_ADMIN || c.UserRole == constants.ROLE_INSTRUCTOR
+}
+
+// Create or update a maintenance block. Only instructors and admins can manage the maintenance blocks.
+func (c *Client) CreateOrUpdate(input Block) (*Block, error) {
+	newBlock := input.Id == ""
+	conditionExpression := "attribute_exists(PK)"
+	if newBlock {
+		input.Id = ulid.Make().String()
+		conditionExpression = "attribute_not_exists(PK)"
+		c.SetLogger(c.Logger().With("aircraft", input.Aircraft, "block", input.Id))
+		c.Logger().Info("creating maintenance block")
+	} else {
+		c.SetLogger(c.Logger().With("aircraft", input.Aircraft, "block", input.Id))
+		c.Logger().Info("updating maintenance block")
+	}
+
+	if !c.canManage() {
+		return nil, MaintenanceAccessDenyError
+	}
+
+	var stored *Block
+	if newBlock {
+		input.CreatedBy = c.UserId
+	} else {
+		// The author of the block is kept when it is edited by another instructor
+		var err error
+		stored, err = c.Get(input.Aircraft, input.Id)
+		if err != nil {
+			return nil, err
+		}
+		input.CreatedBy = stored.CreatedBy
+	}
+	if input.BlockType == TYPE_GROUNDED && input.StartTime.IsZero() {
+		input.StartTime = time.Now().UTC()
+	}
+	err := c.validate(input)
+	if err != nil {
+		return nil, err
+	}
+
+	update, updatedAt, err := c.DatabaseClient.NewReplace(database.PutInput{
+		Item: databaseItem{
+			PK:       c.clubPK(),
+			SK:       c.blockSK(input.Aircraft, input.Id),
+			ItemType: "maintenanceBlock",
+			Block:    input,
+		},
+		ConditionExpression: aws.String(conditionExpression),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The block and the version of the blocks of the aircraft are written together,
+	// which fails the reservations validated against the former blocks
+	_, err = c.DatabaseClient.TransactWriteItems(dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Update: update},
+			{Update: c.newLockUpdate(input.Aircraft)},
+		},
+	})
+	if err != nil {
+		if !newBlock && database.IsConditionalCheckFailure(err) {
+			return nil, MaintenanceNotFoundError
+		}
+		return nil, err
+	}
+
+	input.CreatedAt = updatedAt
+	if stored != nil {
+		input.CreatedAt = stored.CreatedAt
+	}
+	input.UpdatedAt = updatedAt
+
+	if newBlock {
+		c.Logger().Info("maintenance block created")
+	} else {
+		c.Logger().Info("maintenance block updated")
+	}
+
+	return &input, nil
+}
+
+// Checks the type, reason and times of the block, and that the aircraft exists.
+func (c *Client) validate(input Block) error {
+	if input.BlockType != TYPE_SCHEDULED && input.BlockType != TYPE_GROUNDED {
+		return MaintenanceInvalidTypeError
+	}
+	if strings.TrimSpace(input.Reason) == "" {
+		return MaintenanceReasonRequiredError
+	}
+	if input.StartTime.IsZero() || (input.BlockType == TYPE_SCHEDULED && input.EndTime == nil) {
+		return MaintenanceInvalidTimesError
+	}
+	if input.EndTime != nil && !input.StartTime.Before(*input.EndTime) {
+		return MaintenanceInvalidTimesError
+	}
+
+	aircraftClient := aircraft.NewFromConfig(aircraft.Config{Scope: c.Scope})
+	_, err := aircraftClient.Get(input.Aircraft)
+	if errors.Is(err, aircraft.AircraftNotFoundError) {
+		return MaintenanceInvalidAircraftError
+	}
+	return err
+}
+
+type ListInput struct {
+	NextToken *string
+	Limit     *int32
+}
+
+type ListOutput struct {
+	NextToken *string `json:"nextToken"`
+	Results   []Block `json:"results"`
+}
+
+// Returns the maintenance blocks of an aircraft, ordered by creation.
+func (c *Client) List(registration string, input ListInput) (*ListOutput, error) {
+	c.SetLogger(c.Logger().With("aircraft", registration))
+	c.Logger().Info("listing maintenance blocks")
+
+	exclusiveStartKey, err := database.DecodeNextToken(input.NextToken, c.clubPK())
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.DatabaseClient.Query(&database.QueryInput{
+		Limit:                  input.Limit,
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: c.clubPK()},
+			":sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("%s#%s#", MAINTENANCE_PARTITION_KEY, registration)},
+		},
+		ExclusiveStartKey: exclusiveStartKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]Block, 0)
+	err = attributevalue.UnmarshalListOfMaps(output.Items, &blocks)
+	if err != nil {
+		return nil, err
+	}
+
+	nextToken, err := database.EncodeNextToken(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("maintenance blocks listed", "count", len(blocks), "isNextToken", nextToken != nil)
+	return &ListOutput{
+		NextToken: nextToken,
+		Results:   blocks,
+	}, nil
+}
+
+// Returns all maintenance blocks of an aircraft overlapping the [start, end) interval.
+// Used to check reservations, so it is available to every caller.
+func (c *Client) Overlapping(registration string, start time.Time, end time.Time) ([]Block, error) {
+	blocks := make([]Block, 0)
+	var nextToken *string
+	for {
+		output, err := c.List(registration, ListInput{NextToken: nextToken})
+		if err != nil {
+			return nil, err
+		}
+		for _, block := range output.Results {
+			if block.Overlaps(start, end) {
+				blocks = append(blocks, block)
+			}
+		}
+		if output.NextToken == nil {
+			return blocks, nil
+		}
+		nextToken = output.NextToken
+	}
+}
+
+// Returns stored data for a maintenance block.
+func (c *Client) Get(registration string, blockId string) (*Block, error) {
+	c.SetLogger(c.Logger().With("aircraft", registration, "block", blockId))
+	c.Logger().Info("retrieving maintenance block")
+
+	output, err := c.DatabaseClient.Get(database.GetInput{
+		PK: c.clubPK(),
+		SK: c.blockSK(registration, blockId),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.Item == nil {
+		return nil, MaintenanceNotFoundError
+	}
+
+	block := new(Block)
+	err = attributevalue.UnmarshalMap(output.Item, block)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("maintenance block retrieved")
+	return block, nil
+}
+
+// Removes a maintenance block, which makes the aircraft available again during its window.
+func (c *Client) Delete(registration string, blockId string) error {
+	c.SetLogger(c.Logger().With("aircraft", registration, "block", blockId))
+	c.Logger().Info("deleting maintenance block")
+
+	if !c.canManage() {
+		return MaintenanceAccessDenyError
+	}
+
+	_, err := c.DatabaseClient.TransactWriteItems(dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Delete: &types.Delete{
+				TableName: aws.String(c.DatabaseClient.TableName),
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: c.clubPK()},
+					"SK": &types.AttributeValueMemberS{Value: c.blockSK(registration, blockId)},
+				},
+			}},
+			{Update: c.newLockUpdate(registration)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Logger().Info("maintenance block deleted")
+	return nil
+}
+
+// Returns the version of the maintenance blocks of an aircraft, 0 if none was ever written.
+// Reservations read it before the blocks, see NewLockCheckItem.
+func (c *Client) LockVersion(registration string) (int, error) {
+	output, err := c.DatabaseClient.Get(database.GetInput{
+		PK:             c.clubPK(),
+		SK:             c.lockSK(registration),
+		ConsistentRead: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	lock := lockItem{}
+	if output.Item != nil {
+		err = attributevalue.UnmarshalMap(output.Item, &lock)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return lock.Version, nil
+}
+
+// Returns the transaction item failing a reservation write if a maintenance block of the aircraft
+// was created, updated or deleted since the version was read.
+func (c *Client) NewLockCheckItem(registration string, version int) types.TransactWriteItem {
+	return types.TransactWriteItem{ConditionCheck: &types.ConditionCheck{
+		TableName: aws.String(c.DatabaseClient.TableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: c.clubPK()},
+			"SK": &types.AttributeValueMemberS{Value: c.lockSK(registration)},
+		},
+		ConditionExpression:      aws.String("attribute_not_exists(PK) OR #version = :version"),
+		ExpressionAttributeNames: map[string]string{"#version": database.VERSION_ATTRIBUTE},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":version": &types.AttributeValueMemberN{Value: fmt.Sprint(version)},
+		},
+	}}
+}
+
+// Returns the update incrementing the version of the maintenance blocks of an aircraft, creating it on the first block.
+func (c *Client) newLockUpdate(registration string) *types.Update {
+	return &types.Update{
+		TableName: aws.String(c.DatabaseClient.TableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: c.clubPK()},
+			"SK": &types.AttributeValueMemberS{Value: c.lockSK(registration)},
+		},
+		UpdateExpression: aws.String("ADD #version :one " +
+			"SET #itemType = :itemType, #createdAt = if_not_exists(#createdAt, :now), #updatedAt = :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#version":   database.VERSION_ATTRIBUTE,
+			"#itemType":  "ItemType",
+			"#createdAt": "CreatedAt",
+			"#updatedAt": "UpdatedAt",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one":      &types.AttributeValueMemberN{Value: "1"},
+			":itemType": &types.AttributeValueMemberS{Value: "maintenanceLock"},
+			":now":      &types.AttributeValueMemberS{Value: time.Now().Format("2006-01-02T15:04:05.000Z")},
+		},
+	}
+}
+
+// Returns the partition key of the tenant club owning the maintenance blocks.
+func (c *Client) clubPK() string {
+	return fmt.Sprintf("%s#%s", constants.CLUB_PARTITION_KEY, c.TenantId)
+}
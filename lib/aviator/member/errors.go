@@ -0,0 +1,21 @@
+package member
+
+import "aviator/errors"
+
+var MemberNotFoundError = errors.AviatorError{
+	Id: "member_not_found",
+	Message: errors.Message{
+		EN: "The selected member does not exist",
+		FR: "Le membre sélectionné n'existe pas",
+	},
+	ApiError: 404,
+}
+
+var MemberInvalidAddressError = errors.AviatorError{
+	Id: "member_invalid_address",
+	Message: errors.Message{
+		EN: "An address requires a postal code, a town and a two-letter country code",
+		FR: "Une adresse requiert un code postal, une localité et un code pays à deux lettres",
+	},
+	ApiError: 400,
+}
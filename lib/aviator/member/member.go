@@ -0,0 +1,275 @@
+/*
+Package member provides methods for performing CRUD operations on the members of a club.
+*/
+package member
+
+import (
+	"aviator/constants"
+	"aviator/database"
+	"aviator/tenant"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/oklog/ulid/v2"
+)
+
+const MEMBER_PARTITION_KEY = "MEMBER"
+
+type MemberApiInterface interface {
+	Logger() *slog.Logger
+	SetLogger(logger *slog.Logger)
+	CreateOrUpdate(input Member) (*Member, error)
+	Get(memberId string) (*Member, error)
+	List(input ListInput) (*ListOutput, error)
+	Delete(memberId string) error
+}
+
+type Config struct {
+	tenant.Scope
+}
+
+type Client struct {
+	Config
+}
+
+// Pilot licence held by a member
+type Licence struct {
+	// Licence type: e.g. PPL(A)
+	LicenceType string `json:"licenceType"`
+	// Licence number: e.g. CH.FCL.12345
+	Number string `json:"number"`
+}
+
+// Item used to store a member
+// Postal address, printed on invoices
+type Address struct {
+	// Street name: e.g. Route de l'Aéroport
+	Street string `json:"street"`
+	// Building number: e.g. 12
+	BuildingNumber string `json:"buildingNumber"`
+	// Postal code: e.g. 1215
+	PostalCode string `json:"postalCode"`
+	// Town: e.g. Genève
+	Town string `json:"town"`
+	// ISO 3166-1 alpha-2 country code: e.g. CH
+	Country string `json:"country"`
+}
+
+type Member struct {
+	// Member Id: e.g. 01H55420KY47HRVVPK1Z3BSACK
+	Id        string `json:"id"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+	// Licences held by the member, empty for student pilots
+	Licences []Licence `json:"licences"`
+	// Postal address (if any)
+	Address *Address `dynamodbav:",omitempty" json:"address"`
+	// Last day of validity of the single engine piston rating (if any)
+	SepRatingExpiry *time.Time `dynamodbav:",omitempty" json:"sepRatingExpiry"`
+	// Last day of validity of the medical certificate (if any)
+	MedicalExpiry *time.Time `dynamodbav:",omitempty" json:"medicalExpiry"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// Database item to store the member.
+type databaseItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key: e.g. MEMBER#01H55420KY47HRVVPK1Z3BSACK
+	SK string
+
+	// Item type: member
+	ItemType string
+	Member
+}
+
+// Returns a new member API client from the provided config.
+func NewFromConfig(c Config) *Client {
+	return &Client{Config: c}
+}
+
+func (c *Client) Logger() *slog.Logger {
+	return c.Config.Logger
+}
+
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.Config.Logger = logger
+}
+
+// Returns true if the SEP rating of the member is valid until the given time.
+func (m Member) SepRatingValidUntil(t time.Time) bool {
+	return m.SepRatingExpiry != nil && !m.SepRatingExpiry.Before(t)
+}
+
+// Returns true if the medical certificate of the member is valid until the given time.
+func (m Member) MedicalValidUntil(t time.Time) bool {
+	return m.MedicalExpiry != nil && !m.MedicalExpiry.Before(t)
+}
+
+// Returns true if the address can be printed on a Swiss QR-bill, which limits the length of its fields.
+func (a Address) Valid() bool {
+	if len(a.Country) != 2 || strings.ToUpper(a.Country) != a.Country {
+		return false
+	}
+	return utf8.RuneCountInString(a.Street) <= 70 && utf8.RuneCountInString(a.BuildingNumber) <= 16 &&
+		a.PostalCode != "" && utf8.RuneCountInString(a.PostalCode) <= 16 &&
+		a.Town != "" && utf8.RuneCountInString(a.Town) <= 35
+}
+
+// Create or update a member
+func (c *Client) CreateOrUpdate(input Member) (*Member, error) {
+	newMember := input.Id == ""
+	conditionExpression := "attribute_exists(PK)"
+	if newMember {
+		input.Id = ulid.Make().String()
+		conditionExpression = "attribute_not_exists(PK)"
+		c.SetLogger(c.Logger().With("member", input.Id))
+		c.Logger().Info("creating member")
+	} else {
+		c.SetLogger(c.Logger().With("member", input.Id))
+		c.Logger().Info("updating member")
+	}
+
+	if input.Licences == nil {
+		input.Licences = make([]Licence, 0)
+	}
+	if input.Address != nil && !input.Address.Valid() {
+		return nil, MemberInvalidAddressError
+	}
+
+	databaseItem := databaseItem{
+		PK:       c.clubPK(),
+		SK:       fmt.Sprintf("%s#%s", MEMBER_PARTITION_KEY, input.Id),
+		ItemType: "member",
+		Member:   input,
+	}
+
+	out, err := c.DatabaseClient.Put(database.PutInput{
+		Item:                databaseItem,
+		ConditionExpression: aws.String(conditionExpression),
+	})
+	if err != nil {
+		var conditionalCheckFailed *types.ConditionalCheckFailedException
+		if !newMember && errors.As(err, &conditionalCheckFailed) {
+			return nil, MemberNotFoundError
+		}
+		return nil, err
+	}
+
+	input.CreatedAt = out.CreatedAt
+	input.UpdatedAt = out.UpdatedAt
+
+	if newMember {
+		c.Logger().Info("member created")
+	} else {
+		c.Logger().Info("member updated")
+	}
+
+	return &input, nil
+}
+
+type ListInput struct {
+	NextToken *string
+	Limit     *int32
+}
+
+type ListOutput struct {
+	NextToken *string  `json:"nextToken"`
+	Results   []Member `json:"results"`
+}
+
+// Returns stored data for all members of the club.
+func (c *Client) List(input ListInput) (*ListOutput, error) {
+	c.Logger().Info("listing members")
+
+	exclusiveStartKey, err := database.DecodeNextToken(input.NextToken, c.clubPK())
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.DatabaseClient.Query(&database.QueryInput{
+		Limit:                  input.Limit,
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: c.clubPK()},
+			":sk": &types.AttributeValueMemberS{Value: MEMBER_PARTITION_KEY + "#"},
+		},
+		ExclusiveStartKey: exclusiveStartKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]Member, 0)
+	err = attributevalue.UnmarshalListOfMaps(output.Items, &members)
+	if err != nil {
+		return nil, err
+	}
+
+	nextToken, err := database.EncodeNextToken(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("members listed", "count", len(members), "isNextToken", nextToken != nil)
+	return &ListOutput{
+		NextToken: nextToken,
+		Results:   members,
+	}, nil
+}
+
+// Returns stored data for a member.
+func (c *Client) Get(memberId string) (*Member, error) {
+	c.SetLogger(c.Logger().With("member", memberId))
+	c.Logger().Info("retrieving member")
+
+	output, err := c.DatabaseClient.Get(database.GetInput{
+		PK: c.clubPK(),
+		SK: fmt.Sprintf("%s#%s", MEMBER_PARTITION_KEY, memberId),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.Item == nil {
+		return nil, MemberNotFoundError
+	}
+
+	member := new(Member)
+	err = attributevalue.UnmarshalMap(output.Item, member)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("member retrieved")
+	return member, nil
+}
+
+func (c *Client) Delete(memberId string) error {
+	c.SetLogger(c.Logger().With("member", memberId))
+	c.Logger().Info("deleting member")
+
+	_, err := c.DatabaseClient.Delete(&database.DeleteInput{
+		PK: c.clubPK(),
+		SK: fmt.Sprintf("%s#%s", MEMBER_PARTITION_KEY, memberId),
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Logger().Info("member deleted")
+	return nil
+}
+
+// Returns the partition key of the tenant club owning the members.
+func (c *Client) clubPK() string {
+	return fmt.Sprintf("%s#%s", constants.CLUB_PARTITION_KEY, c.TenantId)
+}
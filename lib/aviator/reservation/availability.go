@@ -0,0 +1,311 @@
+package reservation
+
+import (
+	"aviator/aircraft"
+	"aviator/club"
+	"aviator/resource"
+	"errors"
+	"sort"
+	"time"
+)
+
+// Maximum number of days covered by an availability request, every day of every resource is read
+const MAX_AVAILABILITY_DAYS = 31
+
+type AvailabilityInput struct {
+	// Registrations of the aircraft, all aircraft of the club when neither aircraft nor resources are set
+	Aircraft []string
+	// Ids of the rooms and simulators
+	Resources []string
+	// Only return free slots between Start and End, both must be provided
+	Start time.Time
+	End   time.Time
+	// Only return free slots lasting at least this long, defaults to the slot granularity of the club
+	MinDuration time.Duration
+}
+
+// Free time interval of a resource
+type FreeSlot struct {
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+}
+
+// Free slots of an aircraft, or of a room or simulator
+type ResourceAvailability struct {
+	Aircraft  string     `json:"aircraft,omitempty"`
+	Resource  string     `json:"resource,omitempty"`
+	FreeSlots []FreeSlot `json:"freeSlots"`
+}
+
+type AvailabilityOutput struct {
+	// Granularity of the free slots, which start and end on multiples of it in the timezone of the club
+	SlotMinutes int                    `json:"slotMinutes"`
+	Results     []ResourceAvailability `json:"results"`
+}
+
+// Time interval [start, end)
+type interval struct {
+	start time.Time
+	end   time.Time
+}
+
+// Returns the free slots of the requested resources between the start and the end of the input.
+// A resource is free when the club is open and the resource is active, not in maintenance and not held by any reservation.
+// Reservations are read from the slot locks of each resource and day, so no partition is scanned.
+func (c *Client) Availability(input AvailabilityInput) (*AvailabilityOutput, error) {
+	c.SetLogger(c.Logger().With(
+		"start", input.Start.Format("2006-01-02T15:04:05.000Z"),
+		"end", input.End.Format("2006-01-02T15:04:05.000Z")))
+	c.Logger().Info("computing availability")
+
+	err := c.authorizeAvailability()
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Start.IsZero() || input.End.IsZero() {
+		return nil, ReservationTimeRangeError
+	}
+	if !input.Start.Before(input.End) {
+		return nil, ReservationTimesSwappedError
+	}
+	if len(slotDays(input.Start, input.End)) > MAX_AVAILABILITY_DAYS {
+		return nil, ReservationAvailabilityRangeError
+	}
+	if input.MinDuration < 0 {
+		return nil, ReservationInvalidMinDurationError
+	}
+
+	reservationClub, location, err := c.reservationClub()
+	if err != nil {
+		return nil, err
+	}
+	slotMinutes := club.DEFAULT_SLOT_MINUTES
+	var openingHours []club.OpeningHours
+	if reservationClub != nil {
+		openingHours = reservationClub.OpeningHours
+		if reservationClub.SlotMinutes > 0 {
+			slotMinutes = reservationClub.SlotMinutes
+		}
+	}
+	slot := time.Duration(slotMinutes) * time.Minute
+	minDuration := input.MinDuration
+	if minDuration < slot {
+		minDuration = slot
+	}
+
+	open, err := openingIntervals(openingHours, location, input.Start, input.End)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(input.Aircraft) == 0 && len(input.Resources) == 0 {
+		input.Aircraft, err = c.activeAircraft()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]ResourceAvailability, 0, len(input.Aircraft)+len(input.Resources))
+	for _, registration := range input.Aircraft {
+		active, err := c.aircraftActive(registration)
+		if err != nil {
+			return nil, err
+		}
+		blocked, err := c.maintenanceIntervals(registration, input.Start, input.End)
+		if err != nil {
+			return nil, err
+		}
+		freeSlots, err := c.freeSlots(aircraftLock(registration), active, blocked, open, input.Start, input.End, slot, minDuration, location)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, ResourceAvailability{Aircraft: registration, FreeSlots: freeSlots})
+	}
+	for _, resourceId := range input.Resources {
+		active, err := c.resourceActive(resourceId)
+		if err != nil {
+			return nil, err
+		}
+		freeSlots, err := c.freeSlots(resourceLock(resourceId), active, nil, open, input.Start, input.End, slot, minDuration, location)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, ResourceAvailability{Resource: resourceId, FreeSlots: freeSlots})
+	}
+
+	c.Logger().Info("availability computed", "resources", len(results))
+	return &AvailabilityOutput{
+		SlotMinutes: slotMinutes,
+		Results:     results,
+	}, nil
+}
+
+// Returns the free slots of a resource: the opening intervals minus the reservations holding it and the blocked
+// intervals (maintenance of an aircraft), aligned on the slot granularity and at least minDuration long.
+func (c *Client) freeSlots(lockedResource string, active bool, blocked []interval, open []interval, start time.Time, end time.Time,
+	slot time.Duration, minDuration time.Duration, location *time.Location) ([]FreeSlot, error) {
+	freeSlots := make([]FreeSlot, 0)
+	if !active {
+		return freeSlots, nil
+	}
+
+	locks, err := c.readSlotLocks([]string{lockedResource}, slotDays(start, end))
+	if err != nil {
+		return nil, err
+	}
+	busy := append(make([]interval, 0, len(blocked)), blocked...)
+	for _, lock := range locks {
+		for _, s := range lock.Reservations {
+			busy = append(busy, interval{start: s.StartTime, end: s.EndTime})
+		}
+	}
+
+	return alignedSlots(subtractIntervals(open, busy), slot, minDuration, location), nil
+}
+
+// Shrinks the free intervals to the slot granularity and keeps the ones lasting at least minDuration.
+func alignedSlots(free []interval, slot time.Duration, minDuration time.Duration, location *time.Location) []FreeSlot {
+	freeSlots := make([]FreeSlot, 0, len(free))
+	for _, f := range free {
+		freeStart := alignTime(f.start, slot, location, true)
+		freeEnd := alignTime(f.end, slot, location, false)
+		if freeEnd.Sub(freeStart) < minDuration {
+			continue
+		}
+		freeSlots = append(freeSlots, FreeSlot{StartTime: freeStart, EndTime: freeEnd})
+	}
+	return freeSlots
+}
+
+// Returns the registrations of all active aircraft of the club.
+func (c *Client) activeAircraft() ([]string, error) {
+	aircraftClient := aircraft.NewFromConfig(aircraft.Config{Scope: c.Scope})
+
+	registrations := make([]string, 0)
+	var nextToken *string
+	for {
+		output, err := aircraftClient.List(aircraft.ListInput{NextToken: nextToken})
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range output.Results {
+			if a.Status == aircraft.STATUS_ACTIVE {
+				registrations = append(registrations, a.Registration)
+			}
+		}
+		if output.NextToken == nil {
+			return registrations, nil
+		}
+		nextToken = output.NextToken
+	}
+}
+
+// Returns true if the aircraft can be reserved.
+func (c *Client) aircraftActive(registration string) (bool, error) {
+	_, err := c.validateAircraft(registration)
+	if errors.Is(err, ReservationInactiveAircraftError) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Returns true if the room or simulator can be reserved.
+func (c *Client) resourceActive(resourceId string) (bool, error) {
+	resourceClient := resource.NewFromConfig(resource.Config{Scope: c.Scope})
+
+	reservedResource, err := resourceClient.Get(resourceId)
+	if errors.Is(err, resource.ResourceNotFoundError) {
+		return false, ReservationInvalidResourceError
+	}
+	if err != nil {
+		return false, err
+	}
+	return reservedResource.Status == resource.STATUS_ACTIVE, nil
+}
+
+// Returns the intervals during which the club is open between start and end, sorted and merged.
+// Opening hours are in the timezone of the club, and a club without opening hours is always open.
+func openingIntervals(openingHours []club.OpeningHours, location *time.Location, start time.Time, end time.Time) ([]interval, error) {
+	if len(openingHours) == 0 {
+		return []interval{{start: start, end: end}}, nil
+	}
+
+	intervals := make([]interval, 0)
+	localStart := start.In(location)
+	day := time.Date(localStart.Year(), localStart.Month(), localStart.Day(), 0, 0, 0, 0, location)
+	for day.Before(end) {
+		for _, hours := range openingHours {
+			if hours.Weekday != day.Weekday() {
+				continue
+			}
+			open, closing, err := hours.Times()
+			if err != nil {
+				return nil, err
+			}
+			// Built from the wall clock, so that opening hours stay the same across DST changes
+			openTime := time.Date(day.Year(), day.Month(), day.Day(), 0, int(open.Minutes()), 0, 0, location)
+			closeTime := time.Date(day.Year(), day.Month(), day.Day(), 0, int(closing.Minutes()), 0, 0, location)
+			if openTime.Before(start) {
+				openTime = start
+			}
+			if closeTime.After(end) {
+				closeTime = end
+			}
+			if openTime.Before(closeTime) {
+				intervals = append(intervals, interval{start: openTime, end: closeTime})
+			}
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, location)
+	}
+
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].start.Before(intervals[j].start) })
+	merged := make([]interval, 0, len(intervals))
+	for _, iv := range intervals {
+		last := len(merged) - 1
+		if last >= 0 && !iv.start.After(merged[last].end) {
+			if iv.end.After(merged[last].end) {
+				merged[last].end = iv.end
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	return merged, nil
+}
+
+// Returns the parts of the sorted, non-overlapping free intervals not covered by any busy interval.
+func subtractIntervals(free []interval, busy []interval) []interval {
+	sort.Slice(busy, func(i, j int) bool { return busy[i].start.Before(busy[j].start) })
+
+	result := make([]interval, 0, len(free))
+	for _, f := range free {
+		cursor := f.start
+		for _, b := range busy {
+			if !b.end.After(cursor) || !b.start.Before(f.end) {
+				continue
+			}
+			if b.start.After(cursor) {
+				result = append(result, interval{start: cursor, end: b.start})
+			}
+			cursor = b.end
+		}
+		if cursor.Before(f.end) {
+			result = append(result, interval{start: cursor, end: f.end})
+		}
+	}
+	return result
+}
+
+// Rounds a time up or down to a multiple of the slot duration since midnight, on the wall clock of the club,
+// and returns it in UTC.
+func alignTime(t time.Time, slot time.Duration, location *time.Location, up bool) time.Time {
+	local := t.In(location)
+	sinceMidnight := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second + time.Duration(local.Nanosecond())
+	slots := sinceMidnight / slot
+	if up && sinceMidnight%slot != 0 {
+		slots++
+	}
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, int((slots * slot).Seconds()), 0, location).UTC()
+}
@@ -0,0 +1,273 @@
+package reservation
+
+import (
+	"aviator/club"
+	"aviator/constants"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Returns the interval between two UTC times on 2024-03-05, given as hours and minutes.
+func utcInterval(startHour, startMinute, endHour, endMinute int) interval {
+	return interval{
+		start: time.Date(2024, 3, 5, startHour, startMinute, 0, 0, time.UTC),
+		end:   time.Date(2024, 3, 5, endHour, endMinute, 0, 0, time.UTC),
+	}
+}
+
+func equalIntervals(a []interval, b []interval) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].start.Equal(b[i].start) || !a[i].end.Equal(b[i].end) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSubtractIntervals(t *testing.T) {
+	tests := []struct {
+		name string
+		free []interval
+		busy []interval
+		want []interval
+	}{
+		{
+			name: "nothing busy",
+			free: []interval{utcInterval(8, 0, 18, 0)},
+			want: []interval{utcInterval(8, 0, 18, 0)},
+		},
+		{
+			name: "busy in the middle",
+			free: []interval{utcInterval(8, 0, 18, 0)},
+			busy: []interval{utcInterval(10, 0, 12, 0)},
+			want: []interval{utcInterval(8, 0, 10, 0), utcInterval(12, 0, 18, 0)},
+		},
+		{
+			name: "busy at both ends",
+			free: []interval{utcInterval(8, 0, 18, 0)},
+			busy: []interval{utcInterval(7, 0, 9, 0), utcInterval(17, 0, 19, 0)},
+			want: []interval{utcInterval(9, 0, 17, 0)},
+		},
+		{
+			name: "busy all the time",
+			free: []interval{utcInterval(8, 0, 18, 0)},
+			busy: []interval{utcInterval(6, 0, 20, 0)},
+			want: []interval{},
+		},
+		{
+			name: "unsorted and overlapping busy intervals",
+			free: []interval{utcInterval(8, 0, 18, 0)},
+			busy: []interval{utcInterval(14, 0, 16, 0), utcInterval(9, 0, 11, 0), utcInterval(10, 0, 12, 0)},
+			want: []interval{utcInterval(8, 0, 9, 0), utcInterval(12, 0, 14, 0), utcInterval(16, 0, 18, 0)},
+		},
+		{
+			name: "busy interval ending when the free one starts",
+			free: []interval{utcInterval(8, 0, 18, 0)},
+			busy: []interval{utcInterval(6, 0, 8, 0), utcInterval(18, 0, 20, 0)},
+			want: []interval{utcInterval(8, 0, 18, 0)},
+		},
+		{
+			name: "busy interval spanning two free intervals",
+			free: []interval{utcInterval(8, 0, 12, 0), utcInterval(13, 0, 18, 0)},
+			busy: []interval{utcInterval(11, 0, 14, 0)},
+			want: []interval{utcInterval(8, 0, 11, 0), utcInterval(14, 0, 18, 0)},
+		},
+		{
+			name: "back to back busy intervals",
+			free: []interval{utcInterval(8, 0, 18, 0)},
+			busy: []interval{utcInterval(10, 0, 11, 0), utcInterval(11, 0, 12, 0)},
+			want: []interval{utcInterval(8, 0, 10, 0), utcInterval(12, 0, 18, 0)},
+		},
+	}
+	for _, test := range tests {
+		got := subtractIntervals(test.free, test.busy)
+		if !equalIntervals(got, test.want) {
+			t.Errorf("%s: free = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAlignTime(t *testing.T) {
+	zurich := mustLoadLocation(t, "Europe/Zurich")
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+	local := func(location *time.Location, month time.Month, day int, hour int, minute int, second int) time.Time {
+		return time.Date(2024, month, day, hour, minute, second, 0, location)
+	}
+	tests := []struct {
+		name     string
+		t        time.Time
+		slot     time.Duration
+		location *time.Location
+		up       bool
+		want     time.Time
+	}{
+		{"aligned time is kept going up", local(zurich, 3, 5, 10, 30, 0), 30 * time.Minute, zurich, true, local(zurich, 3, 5, 10, 30, 0)},
+		{"aligned time is kept going down", local(zurich, 3, 5, 10, 30, 0), 30 * time.Minute, zurich, false, local(zurich, 3, 5, 10, 30, 0)},
+		{"rounded up", local(zurich, 3, 5, 10, 31, 0), 30 * time.Minute, zurich, true, local(zurich, 3, 5, 11, 0, 0)},
+		{"rounded down", local(zurich, 3, 5, 10, 59, 59), 30 * time.Minute, zurich, false, local(zurich, 3, 5, 10, 30, 0)},
+		{"a second past is rounded up", local(zurich, 3, 5, 10, 0, 1), 15 * time.Minute, zurich, true, local(zurich, 3, 5, 10, 15, 0)},
+		{"rounded up to the next day", local(zurich, 3, 5, 23, 45, 0), time.Hour, zurich, true, local(zurich, 3, 6, 0, 0, 0)},
+		// Slots follow the wall clock of the club, not UTC: 10:15 in Kolkata is 04:45 UTC
+		{"wall clock of a half hour offset", local(kolkata, 3, 5, 10, 10, 0), 15 * time.Minute, kolkata, true, local(kolkata, 3, 5, 10, 15, 0)},
+		{"after the start of summer time", local(zurich, 3, 31, 10, 10, 0), time.Hour, zurich, true, local(zurich, 3, 31, 11, 0, 0)},
+		{"after the end of summer time", local(zurich, 10, 27, 10, 10, 0), time.Hour, zurich, false, local(zurich, 10, 27, 10, 0, 0)},
+	}
+	for _, test := range tests {
+		got := alignTime(test.t, test.slot, test.location, test.up)
+		if !got.Equal(test.want) || got.Location() != time.UTC {
+			t.Errorf("%s: alignTime = %s, want %s in UTC", test.name, got, test.want.UTC())
+		}
+	}
+}
+
+func TestOpeningIntervals(t *testing.T) {
+	zurich := mustLoadLocation(t, "Europe/Zurich")
+	local := func(month time.Month, day int, hour int, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, zurich)
+	}
+	// 2024-03-05 is a Tuesday
+	weekdays := []club.OpeningHours{
+		{Weekday: time.Monday, Open: "08:00", Close: "20:00"},
+		{Weekday: time.Tuesday, Open: "08:00", Close: "12:00"},
+		{Weekday: time.Tuesday, Open: "13:00", Close: "20:00"},
+	}
+	tests := []struct {
+		name         string
+		openingHours []club.OpeningHours
+		start        time.Time
+		end          time.Time
+		want         []interval
+	}{
+		{
+			name:  "always open without opening hours",
+			start: local(3, 5, 0, 0),
+			end:   local(3, 7, 0, 0),
+			want:  []interval{{local(3, 5, 0, 0), local(3, 7, 0, 0)}},
+		},
+		{
+			name:         "several intervals a day",
+			openingHours: weekdays,
+			start:        local(3, 4, 0, 0),
+			end:          local(3, 6, 0, 0),
+			want: []interval{
+				{local(3, 4, 8, 0), local(3, 4, 20, 0)},
+				{local(3, 5, 8, 0), local(3, 5, 12, 0)},
+				{local(3, 5, 13, 0), local(3, 5, 20, 0)},
+			},
+		},
+		{
+			name:         "clipped to the requested window",
+			openingHours: weekdays,
+			start:        local(3, 5, 10, 0),
+			end:          local(3, 5, 15, 0),
+			want: []interval{
+				{local(3, 5, 10, 0), local(3, 5, 12, 0)},
+				{local(3, 5, 13, 0), local(3, 5, 15, 0)},
+			},
+		},
+		{
+			name:         "closed days",
+			openingHours: weekdays,
+			start:        local(3, 6, 0, 0),
+			end:          local(3, 10, 0, 0),
+			want:         []interval{},
+		},
+		{
+			name: "open across midnight",
+			openingHours: []club.OpeningHours{
+				{Weekday: time.Friday, Open: "18:00", Close: "24:00"},
+				{Weekday: time.Saturday, Open: "00:00", Close: "06:00"},
+			},
+			start: local(3, 8, 0, 0),
+			end:   local(3, 10, 0, 0),
+			want:  []interval{{local(3, 8, 18, 0), local(3, 9, 6, 0)}},
+		},
+		{
+			name:         "wall clock kept on the start of summer time",
+			openingHours: []club.OpeningHours{{Weekday: time.Saturday, Open: "08:00", Close: "20:00"}, {Weekday: time.Sunday, Open: "08:00", Close: "20:00"}},
+			start:        local(3, 30, 0, 0),
+			end:          local(4, 1, 0, 0),
+			want: []interval{
+				{time.Date(2024, 3, 30, 7, 0, 0, 0, time.UTC), time.Date(2024, 3, 30, 19, 0, 0, 0, time.UTC)},
+				{time.Date(2024, 3, 31, 6, 0, 0, 0, time.UTC), time.Date(2024, 3, 31, 18, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name:         "whole day on the end of summer time lasts 25 hours",
+			openingHours: []club.OpeningHours{{Weekday: time.Sunday, Open: "00:00", Close: "24:00"}},
+			start:        local(10, 27, 0, 0),
+			end:          local(10, 28, 0, 0),
+			want:         []interval{{time.Date(2024, 10, 26, 22, 0, 0, 0, time.UTC), time.Date(2024, 10, 27, 23, 0, 0, 0, time.UTC)}},
+		},
+	}
+	for _, test := range tests {
+		got, err := openingIntervals(test.openingHours, zurich, test.start, test.end)
+		if err != nil {
+			t.Errorf("%s: error = %v", test.name, err)
+			continue
+		}
+		if !equalIntervals(got, test.want) {
+			t.Errorf("%s: open = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAlignedSlots(t *testing.T) {
+	zurich := mustLoadLocation(t, "Europe/Zurich")
+	free := []interval{
+		// 09:10 to 10:50 in Zurich, 09:30 to 10:30 once aligned on 30 minutes
+		utcInterval(8, 10, 9, 50),
+		// 11:05 to 11:40, shorter than a slot once aligned
+		utcInterval(10, 5, 10, 40),
+		// 12:00 to 12:30, exactly one slot
+		utcInterval(11, 0, 11, 30),
+	}
+	tests := []struct {
+		name        string
+		minDuration time.Duration
+		want        []FreeSlot
+	}{
+		{"slot granularity", 30 * time.Minute, []FreeSlot{
+			{time.Date(2024, 3, 5, 8, 30, 0, 0, time.UTC), time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC)},
+			{time.Date(2024, 3, 5, 11, 0, 0, 0, time.UTC), time.Date(2024, 3, 5, 11, 30, 0, 0, time.UTC)},
+		}},
+		{"minimum duration", time.Hour, []FreeSlot{
+			{time.Date(2024, 3, 5, 8, 30, 0, 0, time.UTC), time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC)},
+		}},
+		{"minimum duration longer than every interval", 90 * time.Minute, []FreeSlot{}},
+	}
+	for _, test := range tests {
+		got := alignedSlots(free, 30*time.Minute, test.minDuration, zurich)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: slots = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// Requests are checked before any read.
+func TestAvailabilityInput(t *testing.T) {
+	start := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		input AvailabilityInput
+		want  error
+	}{
+		{"missing start", AvailabilityInput{End: start}, ReservationTimeRangeError},
+		{"missing end", AvailabilityInput{Start: start}, ReservationTimeRangeError},
+		{"empty window", AvailabilityInput{Start: start, End: start}, ReservationTimesSwappedError},
+		{"swapped times", AvailabilityInput{Start: start.Add(time.Hour), End: start}, ReservationTimesSwappedError},
+		{"too many days", AvailabilityInput{Start: start, End: start.AddDate(0, 0, MAX_AVAILABILITY_DAYS).Add(time.Minute)}, ReservationAvailabilityRangeError},
+		{"negative minimum duration", AvailabilityInput{Start: start, End: start.Add(time.Hour), MinDuration: -time.Minute}, ReservationInvalidMinDurationError},
+	}
+	for _, test := range tests {
+		_, err := newTestClient(USER_ID, constants.ROLE_PILOT).Availability(test.input)
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s: error = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
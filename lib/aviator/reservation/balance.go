@@ -0,0 +1,187 @@
+package reservation
+
+import (
+	"aviator/aircraft"
+	"aviator/database"
+	"aviator/ledger"
+	"aviator/reservationtype"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Checks that the prepaid balance of the pilot covers the estimated cost of a reservation of an aircraft,
+// when the club requires prepaid bookings. The cost is estimated from the reserved time at the hourly rate
+// of the aircraft, and at the instructor hourly rate of the reservation type when flying with an instructor.
+// The balance available to the reservation is the account balance minus the estimated cost of the other open
+// future reservations of the pilot, and of the pending reservations about to be written along with it.
+// Updates which do not make the reservation more expensive for its pilot are always accepted.
+// Returns the minimum balance the check relied on, nil when nothing was checked, see ledger.NewBalanceCheckItem.
+// The check is best-effort against concurrent bookings of the same pilot: bookings on other days do not share
+// any slot lock, so two of them can each be validated against the same balance.
+func (c *Client) validatePrepaidBalance(input Reservation, previous *Reservation, pending []Reservation) (*int64, error) {
+	reservationClub, _, err := c.reservationClub()
+	if err != nil {
+		return nil, err
+	}
+	if reservationClub == nil || !reservationClub.PrepaidBookings {
+		return nil, nil
+	}
+
+	estimator := c.newCostEstimator()
+	estimatedCost, err := estimator.estimate(input)
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil && previous.Pilot == input.Pilot {
+		previousCost, err := estimator.estimate(*previous)
+		if err != nil {
+			return nil, err
+		}
+		if estimatedCost <= previousCost {
+			return nil, nil
+		}
+	}
+
+	committedCost, err := c.committedCost(estimator, input, pending)
+	if err != nil {
+		return nil, err
+	}
+
+	ledgerClient := ledger.NewFromConfig(ledger.Config{Scope: c.Scope})
+	balance, err := ledgerClient.AccountBalance(input.Pilot)
+	if err != nil {
+		return nil, err
+	}
+	if estimatedCost > balance.Balance-committedCost {
+		c.Logger().Info("prepaid balance too low", "estimatedCost", estimatedCost, "committedCost", committedCost,
+			"balance", balance.Balance)
+		return nil, ReservationInsufficientBalanceError
+	}
+	minimum := estimatedCost + committedCost
+	return &minimum, nil
+}
+
+// Checks the prepaid balance of the pilot of a reservation and returns the transaction item failing the reservation
+// write if the balance falls below the checked minimum in the meantime. Returns no item when nothing was checked.
+func (c *Client) balanceCheckItem(input Reservation, previous *Reservation) (*types.TransactWriteItem, error) {
+	minimum, err := c.validatePrepaidBalance(input, previous, nil)
+	if err != nil || minimum == nil {
+		return nil, err
+	}
+	ledgerClient := ledger.NewFromConfig(ledger.Config{Scope: c.Scope})
+	checkItem := ledgerClient.NewBalanceCheckItem(input.Pilot, *minimum)
+	return &checkItem, nil
+}
+
+// Returns the estimated cost in centimes of the open reservations of the pilot starting in the future,
+// other than the input, and of the pending reservations of the same pilot.
+func (c *Client) committedCost(estimator *costEstimator, input Reservation, pending []Reservation) (int64, error) {
+	reservations := make([]Reservation, 0, len(pending))
+	for _, reservation := range pending {
+		if reservation.Pilot == input.Pilot && reservation.Id != input.Id {
+			reservations = append(reservations, reservation)
+		}
+	}
+
+	// Reservations of the pilot are sorted by start time in the member calendar
+	queryInput := database.QueryInput{
+		Index:                  aws.String("GSI1"),
+		KeyConditionExpression: aws.String("GSI1PK = :pk AND GSI1SK BETWEEN :skStart AND :skEnd"),
+		FilterExpression:       aws.String("GSIData.Pilot = :pilot"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":      &types.AttributeValueMemberS{Value: c.memberPK(input.Pilot)},
+			":skStart": &types.AttributeValueMemberS{Value: fmt.Sprintf("%s#%s", RESERVATION_PARTITION_KEY, time.Now().UTC().Format(TIME_KEY_FORMAT))},
+			":skEnd":   &types.AttributeValueMemberS{Value: RESERVATION_PARTITION_KEY + "#~"},
+			":pilot":   &types.AttributeValueMemberS{Value: input.Pilot},
+		},
+	}
+	for {
+		output, err := c.DatabaseClient.Query(&queryInput)
+		if err != nil {
+			return 0, err
+		}
+		for _, item := range output.Items {
+			reservation, err := unmarshalListItem(item, true)
+			if err != nil {
+				return 0, err
+			}
+			if reservation.Id != input.Id && isOpen(reservation.Status) {
+				reservations = append(reservations, *reservation)
+			}
+		}
+		if len(output.LastEvaluatedKey) == 0 {
+			break
+		}
+		queryInput.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+
+	var committedCost int64
+	for _, reservation := range reservations {
+		cost, err := estimator.estimate(reservation)
+		if err != nil {
+			return 0, err
+		}
+		committedCost += cost
+	}
+	return committedCost, nil
+}
+
+// Estimates the cost of reservations, reading every aircraft and reservation type only once.
+type costEstimator struct {
+	client           *Client
+	aircraft         map[string]*aircraft.Aircraft
+	reservationTypes map[string]*reservationtype.ReservationType
+}
+
+func (c *Client) newCostEstimator() *costEstimator {
+	return &costEstimator{
+		client:           c,
+		aircraft:         make(map[string]*aircraft.Aircraft),
+		reservationTypes: make(map[string]*reservationtype.ReservationType),
+	}
+}
+
+// Returns the estimated cost of a reservation in centimes.
+// Reservations without an aircraft, or of an aircraft which no longer exists, cost nothing.
+func (e *costEstimator) estimate(input Reservation) (int64, error) {
+	if input.Aircraft == "" {
+		return 0, nil
+	}
+	reservedAircraft, ok := e.aircraft[input.Aircraft]
+	if !ok {
+		aircraftClient := aircraft.NewFromConfig(aircraft.Config{Scope: e.client.Scope})
+		var err error
+		reservedAircraft, err = aircraftClient.Get(input.Aircraft)
+		if err != nil && !errors.Is(err, aircraft.AircraftNotFoundError) {
+			return 0, err
+		}
+		e.aircraft[input.Aircraft] = reservedAircraft
+	}
+	if reservedAircraft == nil {
+		return 0, nil
+	}
+
+	hours := input.EndTime.Sub(input.StartTime).Hours()
+	hourlyRate := reservedAircraft.HourlyRate
+	if input.Instructor != nil {
+		reservedType, ok := e.reservationTypes[input.ReservationType]
+		if !ok {
+			reservationTypeClient := reservationtype.NewFromConfig(reservationtype.Config{Scope: e.client.Scope})
+			var err error
+			reservedType, err = reservationTypeClient.Get(input.ReservationType)
+			if err != nil && !errors.Is(err, reservationtype.ReservationTypeNotFoundError) {
+				return 0, err
+			}
+			e.reservationTypes[input.ReservationType] = reservedType
+		}
+		if reservedType != nil {
+			hourlyRate += reservedType.InstructorHourlyRate
+		}
+	}
+	return int64(math.Round(hours * hourlyRate * 100)), nil
+}
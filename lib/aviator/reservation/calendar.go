@@ -0,0 +1,162 @@
+package reservation
+
+import (
+	"aviator/member"
+	"aviator/resource"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const CALENDAR_PARTITION_KEY = "CALENDAR"
+
+// Database item indexing a reservation in the calendar of one of its members (booker, pilot or instructor) or of one of its rooms and simulators.
+// A reservation has one calendar entry per distinct member and resource, so that GSI1 can list their reservations by start time.
+// Reservations of an aircraft are listed from the reservation items themselves.
+type calendarEntryItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key: e.g. CALENDAR#01HRA0G9W4X9ZQ5E7V1FJ2C3KD#RESERVATION#01H55420KY47HRVVPK1Z3BSACK
+	// or CALENDAR#RESOURCE#01HRB3T7C6M8N2Q4W9X5Y1Z0AB#RESERVATION#01H55420KY47HRVVPK1Z3BSACK
+	SK string
+	// GSI1 primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP#MEMBER#01HRA0G9W4X9ZQ5E7V1FJ2C3KD
+	// or CLUB#01HR9ZZNRFCKMAYNW3RY561QCP#RESOURCE#01HRB3T7C6M8N2Q4W9X5Y1Z0AB
+	GSI1PK string
+	// GSI1 sort key, ordered by start time: e.g. RESERVATION#2023-04-05T12:30:00Z#01H55420KY47HRVVPK1Z3BSACK
+	GSI1SK string
+
+	// Item type: calendarEntry
+	ItemType string
+	// Reservation Id: e.g. 01H55420KY47HRVVPK1Z3BSACK
+	Id        string
+	GSIData   gsiData
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Returns the distinct members involved in a reservation.
+func reservationMembers(reservation Reservation) []string {
+	members := make([]string, 0)
+	candidates := []string{reservation.Booker, reservation.Pilot}
+	if reservation.Instructor != nil {
+		candidates = append(candidates, *reservation.Instructor)
+	}
+	for _, candidate := range candidates {
+		if candidate == "" || containsMember(members, candidate) {
+			continue
+		}
+		members = append(members, candidate)
+	}
+	return members
+}
+
+func containsMember(members []string, memberId string) bool {
+	for _, m := range members {
+		if m == memberId {
+			return true
+		}
+	}
+	return false
+}
+
+// A calendar listing reservations: the calendar of a member, or of a room or simulator
+type calendar struct {
+	// Calendar key, part of the sort key of the entries: a member Id or RESOURCE#<resource Id>
+	Key string
+	// GSI1 partition key of the entries
+	GSI1PK string
+}
+
+// Returns the calendars a reservation is listed in: one per distinct member, then one per room or simulator.
+func (c *Client) reservationCalendars(reservation Reservation) []calendar {
+	calendars := make([]calendar, 0)
+	for _, memberId := range reservationMembers(reservation) {
+		calendars = append(calendars, calendar{Key: memberId, GSI1PK: c.memberPK(memberId)})
+	}
+	for _, resourceId := range reservation.Resources {
+		calendars = append(calendars, calendar{Key: resourceLock(resourceId), GSI1PK: c.resourcePK(resourceId)})
+	}
+	return calendars
+}
+
+func containsCalendar(calendars []calendar, key string) bool {
+	for _, cal := range calendars {
+		if cal.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func calendarEntrySK(calendarKey string, reservationId string) string {
+	return fmt.Sprintf("%s#%s#%s#%s", CALENDAR_PARTITION_KEY, calendarKey, RESERVATION_PARTITION_KEY, reservationId)
+}
+
+func (c *Client) newCalendarEntryItem(input Reservation, cal calendar) calendarEntryItem {
+	item := c.newDatabaseItem(input)
+	return calendarEntryItem{
+		PK:        item.PK,
+		SK:        calendarEntrySK(cal.Key, input.Id),
+		GSI1PK:    cal.GSI1PK,
+		GSI1SK:    item.GSI1SK,
+		ItemType:  "calendarEntry",
+		Id:        input.Id,
+		GSIData:   item.GSIData,
+		CreatedAt: input.CreatedAt,
+		UpdatedAt: input.UpdatedAt,
+	}
+}
+
+// Builds the transaction items writing the calendar entries of a reservation,
+// and removing the entries of the members and resources of the previous version it no longer involves.
+func (c *Client) putCalendarEntryItems(input Reservation, previous *Reservation) ([]types.TransactWriteItem, error) {
+	transactItems := make([]types.TransactWriteItem, 0)
+	calendars := c.reservationCalendars(input)
+	for _, cal := range calendars {
+		item, err := attributevalue.MarshalMap(c.newCalendarEntryItem(input, cal))
+		if err != nil {
+			return nil, err
+		}
+		transactItems = append(transactItems, types.TransactWriteItem{
+			Put: &types.Put{
+				TableName: aws.String(c.DatabaseClient.TableName),
+				Item:      item,
+			},
+		})
+	}
+
+	if previous != nil {
+		for _, cal := range c.reservationCalendars(*previous) {
+			if !containsCalendar(calendars, cal.Key) {
+				transactItems = append(transactItems, c.deleteCalendarEntryItem(cal.Key, input.Id))
+			}
+		}
+	}
+	return transactItems, nil
+}
+
+// Builds the transaction item removing a reservation from a calendar.
+func (c *Client) deleteCalendarEntryItem(calendarKey string, reservationId string) types.TransactWriteItem {
+	return types.TransactWriteItem{
+		Delete: &types.Delete{
+			TableName: aws.String(c.DatabaseClient.TableName),
+			Key: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: c.clubPK()},
+				"SK": &types.AttributeValueMemberS{Value: calendarEntrySK(calendarKey, reservationId)},
+			},
+		},
+	}
+}
+
+// Returns the GSI1 partition key grouping the reservations of a member.
+func (c *Client) memberPK(memberId string) string {
+	return fmt.Sprintf("%s#%s#%s", c.clubPK(), member.MEMBER_PARTITION_KEY, memberId)
+}
+
+// Returns the GSI1 partition key grouping the reservations of a room or simulator.
+func (c *Client) resourcePK(resourceId string) string {
+	return fmt.Sprintf("%s#%s#%s", c.clubPK(), resource.RESOURCE_PARTITION_KEY, resourceId)
+}
@@ -1,6 +1,9 @@
 package reservation
 
-import "aviator/errors"
+import (
+	"aviator/errors"
+	"fmt"
+)
 
 var ReservationAircraftAccessDenyError = errors.AviatorError{
 	Id: "reservation_aircraft_access_deny",
@@ -12,7 +15,7 @@ var ReservationAircraftAccessDenyError = errors.AviatorError{
 }
 
 var ReservationRoomAccessDenyError = errors.AviatorError{
-	Id: "reservation_aircraft_access_deny",
+	Id: "reservation_room_access_deny",
 	Message: errors.Message{
 		EN: "You do not have permission to reserve the selected room",
 		FR: "Vous n'avez pas l'autorisation de réserver la salle sélectionnée",
@@ -20,6 +23,15 @@ var ReservationRoomAccessDenyError = errors.AviatorError{
 	ApiError: 401,
 }
 
+var ReservationSimulatorAccessDenyError = errors.AviatorError{
+	Id: "reservation_simulator_access_deny",
+	Message: errors.Message{
+		EN: "You do not have permission to reserve the selected simulator",
+		FR: "Vous n'avez pas l'autorisation de réserver le simulateur sélectionné",
+	},
+	ApiError: 401,
+}
+
 var ReservationInvalidBookerError = errors.AviatorError{
 	Id: "reservation_invalid_booker",
 	Message: errors.Message{
@@ -74,6 +86,15 @@ var ReservationInvalidAircraftError = errors.AviatorError{
 	ApiError: 400,
 }
 
+var ReservationInactiveAircraftError = errors.AviatorError{
+	Id: "reservation_inactive_aircraft",
+	Message: errors.Message{
+		EN: "The selected aircraft is not available for reservations",
+		FR: "L'appareil sélectionné n'est pas disponible à la réservation",
+	},
+	ApiError: 400,
+}
+
 var ReservationInvalidReservationTypeError = errors.AviatorError{
 	Id: "reservation_invalid_reservation_type",
 	Message: errors.Message{
@@ -101,6 +122,15 @@ var ReservationTimesSwappedError = errors.AviatorError{
 	ApiError: 400,
 }
 
+var ReservationTooManyItemsError = errors.AviatorError{
+	Id: "reservation_too_many_items",
+	Message: errors.Message{
+		EN: "The reservation holds too many rooms, simulators and days to be written at once, shorten it or split it",
+		FR: "La réservation contient trop de salles, de simulateurs et de jours pour être enregistrée en une fois, raccourcissez-la ou divisez-la",
+	},
+	ApiError: 400,
+}
+
 var ReservationTimesEqualError = errors.AviatorError{
 	Id: "reservation_times_equal",
 	Message: errors.Message{
@@ -181,3 +211,261 @@ var ReservationSamePilotInstructorError = errors.AviatorError{
 	},
 	ApiError: 401,
 }
+
+var ReservationVersionMismatchError = errors.AviatorError{
+	Id: "reservation_version_mismatch",
+	Message: errors.Message{
+		EN: "The reservation was modified in the meantime, reload it and try again",
+		FR: "La réservation a été modifiée entre-temps, rechargez-la et réessayez",
+	},
+	ApiError: 412,
+}
+
+var ReservationNotFoundError = errors.AviatorError{
+	Id: "reservation_not_found",
+	Message: errors.Message{
+		EN: "The selected reservation does not exist",
+		FR: "La réservation sélectionnée n'existe pas",
+	},
+	ApiError: 404,
+}
+
+var ReservationDeleteNotPendingError = errors.AviatorError{
+	Id: "reservation_delete_not_pending",
+	Message: errors.Message{
+		EN: "Only pending reservations can be deleted, cancel the reservation instead",
+		FR: "Seules les réservations en attente peuvent être supprimées, annulez la réservation à la place",
+	},
+	ApiError: 409,
+}
+
+var ReservationInvalidTransitionError = errors.AviatorError{
+	Id: "reservation_invalid_transition",
+	Message: errors.Message{
+		EN: "The reservation cannot be moved to this status from its current status",
+		FR: "La réservation ne peut pas passer à ce statut depuis son statut actuel",
+	},
+	ApiError: 409,
+}
+
+var ReservationClosedError = errors.AviatorError{
+	Id: "reservation_closed",
+	Message: errors.Message{
+		EN: "A completed, cancelled or no-show reservation cannot be modified",
+		FR: "Une réservation terminée, annulée ou non honorée ne peut pas être modifiée",
+	},
+	ApiError: 400,
+}
+
+var ReservationCancellationReasonRequiredError = errors.AviatorError{
+	Id: "reservation_cancellation_reason_required",
+	Message: errors.Message{
+		EN: "A reason is required to cancel a reservation",
+		FR: "Une raison est requise pour annuler une réservation",
+	},
+	ApiError: 400,
+}
+
+var ReservationInvalidRecurrenceRuleError = errors.AviatorError{
+	Id: "reservation_invalid_recurrence_rule",
+	Message: errors.Message{
+		EN: "The recurrence rule is invalid, supported rules repeat daily, weekly or monthly and end after a COUNT or at an UNTIL date",
+		FR: "La règle de récurrence est invalide, les règles supportées se répètent chaque jour, semaine ou mois et se terminent après un COUNT ou à une date UNTIL",
+	},
+	ApiError: 400,
+}
+
+var ReservationTooManyOccurrencesError = errors.AviatorError{
+	Id: "reservation_too_many_occurrences",
+	Message: errors.Message{
+		EN: "A series cannot have more than 100 occurrences",
+		FR: "Une série ne peut pas avoir plus de 100 occurrences",
+	},
+	ApiError: 400,
+}
+
+var ReservationEmptySeriesError = errors.AviatorError{
+	Id: "reservation_empty_series",
+	Message: errors.Message{
+		EN: "The recurrence rule does not produce any occurrence",
+		FR: "La règle de récurrence ne produit aucune occurrence",
+	},
+	ApiError: 400,
+}
+
+var ReservationSeriesConflictError = errors.AviatorError{
+	Id: "reservation_series_conflict",
+	Message: errors.Message{
+		EN: "None of the occurrences of the series could be reserved",
+		FR: "Aucune occurrence de la série n'a pu être réservée",
+	},
+	ApiError: 409,
+}
+
+var ReservationInvalidScopeError = errors.AviatorError{
+	Id: "reservation_invalid_scope",
+	Message: errors.Message{
+		EN: "The scope must be this, following or all, and only occurrences of a series accept following or all",
+		FR: "La portée doit être this, following ou all, et seules les occurrences d'une série acceptent following ou all",
+	},
+	ApiError: 400,
+}
+
+var ReservationSeriesNotFoundError = errors.AviatorError{
+	Id: "reservation_series_not_found",
+	Message: errors.Message{
+		EN: "The reservation series does not exist",
+		FR: "La série de réservations n'existe pas",
+	},
+	ApiError: 404,
+}
+
+var ReservationInvalidDurationError = errors.AviatorError{
+	Id: "reservation_invalid_duration",
+	Message: errors.Message{
+		EN: "The duration of the reservation is not allowed for the selected reservation type",
+		FR: "La durée de la réservation n'est pas autorisée pour le type de réservation sélectionné",
+	},
+	ApiError: 400,
+}
+
+var ReservationNoResourceError = errors.AviatorError{
+	Id: "reservation_no_resource",
+	Message: errors.Message{
+		EN: "A reservation must include an aircraft or at least one room or simulator",
+		FR: "Une réservation doit comprendre un avion ou au moins une salle ou un simulateur",
+	},
+	ApiError: 400,
+}
+
+var ReservationInvalidResourceError = errors.AviatorError{
+	Id: "reservation_invalid_resource",
+	Message: errors.Message{
+		EN: "The selected room or simulator does not exist",
+		FR: "La salle ou le simulateur sélectionné n'existe pas",
+	},
+	ApiError: 400,
+}
+
+var ReservationDuplicateResourceError = errors.AviatorError{
+	Id: "reservation_duplicate_resource",
+	Message: errors.Message{
+		EN: "A room or simulator cannot be selected more than once in a reservation",
+		FR: "Une salle ou un simulateur ne peut pas être sélectionné plus d'une fois dans une réservation",
+	},
+	ApiError: 400,
+}
+
+var ReservationInactiveResourceError = errors.AviatorError{
+	Id: "reservation_inactive_resource",
+	Message: errors.Message{
+		EN: "The selected room or simulator is not available for reservations",
+		FR: "La salle ou le simulateur sélectionné n'est pas disponible pour les réservations",
+	},
+	ApiError: 400,
+}
+
+var ReservationResourceInstructorRequiredError = errors.AviatorError{
+	Id: "reservation_resource_instructor_required",
+	Message: errors.Message{
+		EN: "The selected room or simulator can only be reserved with an instructor",
+		FR: "La salle ou le simulateur sélectionné ne peut être réservé qu'avec un instructeur",
+	},
+	ApiError: 400,
+}
+
+var ReservationResourceConflictError = errors.AviatorError{
+	Id: "reservation_resource_conflict",
+	Message: errors.Message{
+		EN: "The selected room or simulator is already reserved during this time slot",
+		FR: "La salle ou le simulateur sélectionné est déjà réservé pendant ce créneau",
+	},
+	ApiError: 400,
+}
+
+// Returns the error reported when the pilot, identified by its member id, is already booked during the time slot.
+func ReservationPilotConflictError(memberId string) errors.AviatorError {
+	return errors.AviatorError{
+		Id: "reservation_pilot_conflict",
+		Message: errors.Message{
+			EN: fmt.Sprintf("The pilot %s is already booked on another reservation during this time slot", memberId),
+			FR: fmt.Sprintf("Le pilote %s est déjà engagé dans une autre réservation pendant ce créneau", memberId),
+		},
+		ApiError: 400,
+	}
+}
+
+// Returns the error reported when the instructor, identified by its member id, is already booked during the time slot.
+func ReservationInstructorConflictError(memberId string) errors.AviatorError {
+	return errors.AviatorError{
+		Id: "reservation_instructor_conflict",
+		Message: errors.Message{
+			EN: fmt.Sprintf("The instructor %s is already booked on another reservation during this time slot", memberId),
+			FR: fmt.Sprintf("L'instructeur %s est déjà engagé dans une autre réservation pendant ce créneau", memberId),
+		},
+		ApiError: 400,
+	}
+}
+
+var ReservationAvailabilityRangeError = errors.AviatorError{
+	Id: "reservation_availability_range",
+	Message: errors.Message{
+		EN: "The availability can be requested for at most 31 days at once",
+		FR: "La disponibilité peut être demandée pour 31 jours au maximum à la fois",
+	},
+	ApiError: 400,
+}
+
+var ReservationInvalidMinDurationError = errors.AviatorError{
+	Id: "reservation_invalid_min_duration",
+	Message: errors.Message{
+		EN: "The minimum duration of a free slot cannot be negative",
+		FR: "La durée minimale d'un créneau libre ne peut pas être négative",
+	},
+	ApiError: 400,
+}
+
+var ReservationAircraftMaintenanceError = errors.AviatorError{
+	Id: "reservation_aircraft_maintenance",
+	Message: errors.Message{
+		EN: "The aircraft is in maintenance during this time slot",
+		FR: "L'appareil est en maintenance pendant ce créneau",
+	},
+	ApiError: 400,
+}
+
+var ReservationAircraftGroundedError = errors.AviatorError{
+	Id: "reservation_aircraft_grounded",
+	Message: errors.Message{
+		EN: "The aircraft is grounded during this time slot",
+		FR: "L'appareil est immobilisé pendant ce créneau",
+	},
+	ApiError: 400,
+}
+
+var ReservationFlightLogRequiredError = errors.AviatorError{
+	Id: "reservation_flight_log_required",
+	Message: errors.Message{
+		EN: "A flight log is required to check in an aircraft",
+		FR: "Un carnet de vol est requis pour restituer un appareil",
+	},
+	ApiError: 400,
+}
+
+var ReservationMeterRegressionError = errors.AviatorError{
+	Id: "reservation_meter_regression",
+	Message: errors.Message{
+		EN: "The meter readings at the start of the flight are lower than the last readings of the aircraft",
+		FR: "Les relevés des compteurs au début du vol sont inférieurs aux derniers relevés de l'appareil",
+	},
+	ApiError: 400,
+}
+
+var ReservationInsufficientBalanceError = errors.AviatorError{
+	Id: "reservation_insufficient_balance",
+	Message: errors.Message{
+		EN: "The prepaid balance of the pilot does not cover the estimated cost of the flight",
+		FR: "Le solde prépayé du pilote ne couvre pas le coût estimé du vol",
+	},
+	ApiError: 400,
+}
@@ -0,0 +1,203 @@
+package reservation
+
+import (
+	"aviator/constants"
+	"aviator/database"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/oklog/ulid/v2"
+)
+
+const HISTORY_PARTITION_KEY = "HISTORY"
+
+// Audit entry recording one change of a reservation
+type HistoryEntry struct {
+	// Member Id of the author of the change
+	UserId string `json:"userId"`
+	// Role of the author when making the change: e.g. instructor
+	UserRole string `json:"userRole"`
+	// Operation performed: create, update or delete
+	Operation string `json:"operation"`
+	// Version of the reservation after the change, or the deleted version
+	Version int `json:"version"`
+	// Changed attributes with their values before and after the change, keyed by JSON name: e.g. endTime
+	Changes   map[string]Change `json:"changes"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// Values of an attribute before and after a change, nil when the attribute was not set
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Database item to store a history entry, written once and never updated.
+type historyItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key, ordered by time of the change: e.g. HISTORY#RESERVATION#01H55420KY47HRVVPK1Z3BSACK#01HRB3T0V4Y8KQ9X2M5N6P7R8S
+	SK string
+
+	// Item type: reservationHistory
+	ItemType string
+	// Reservation Id: e.g. 01H55420KY47HRVVPK1Z3BSACK
+	Id        string
+	UserId    string
+	UserRole  string
+	Operation string
+	Version   int
+	Changes   map[string]Change
+}
+
+func historySKPrefix(reservationId string) string {
+	return fmt.Sprintf("%s#%s#%s#", HISTORY_PARTITION_KEY, RESERVATION_PARTITION_KEY, reservationId)
+}
+
+// Builds the transaction item recording the change of a reservation from before to after.
+// Before is nil for a creation, after is nil for a deletion.
+func (c *Client) historyEntryItem(op operation, before *Reservation, after *Reservation) (*types.TransactWriteItem, error) {
+	reservationId, version := "", 0
+	if after != nil {
+		reservationId, version = after.Id, after.Version
+	} else {
+		reservationId, version = before.Id, before.Version
+	}
+
+	changes, err := diff(before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := attributevalue.MarshalMap(historyItem{
+		PK:        c.clubPK(),
+		SK:        historySKPrefix(reservationId) + ulid.Make().String(),
+		ItemType:  "reservationHistory",
+		Id:        reservationId,
+		UserId:    c.UserId,
+		UserRole:  c.UserRole,
+		Operation: string(op),
+		Version:   version,
+		Changes:   changes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:           aws.String(c.DatabaseClient.TableName),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(PK)"),
+		},
+	}, nil
+}
+
+// Returns the attributes that differ between two versions of a reservation.
+// Timestamps and versions change on every write and are not reported.
+func diff(before *Reservation, after *Reservation) (map[string]Change, error) {
+	beforeValues, err := jsonValues(before)
+	if err != nil {
+		return nil, err
+	}
+	afterValues, err := jsonValues(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]Change)
+	for _, values := range []map[string]interface{}{beforeValues, afterValues} {
+		for name := range values {
+			if name == "createdAt" || name == "updatedAt" || name == "version" {
+				continue
+			}
+			if !reflect.DeepEqual(beforeValues[name], afterValues[name]) {
+				changes[name] = Change{Before: beforeValues[name], After: afterValues[name]}
+			}
+		}
+	}
+	return changes, nil
+}
+
+func jsonValues(reservation *Reservation) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if reservation == nil {
+		return values, nil
+	}
+	b, err := json.Marshal(reservation)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &values)
+	return values, err
+}
+
+type HistoryInput struct {
+	NextToken *string
+	Limit     *int32
+}
+
+type HistoryOutput struct {
+	NextToken *string        `json:"nextToken"`
+	Results   []HistoryEntry `json:"results"`
+}
+
+// Returns the changes of a reservation, oldest first.
+// The history of a deleted reservation remains available to admins.
+func (c *Client) History(reservationId string, input HistoryInput) (*HistoryOutput, error) {
+	c.SetLogger(c.Logger().With("reservation", reservationId))
+	c.Logger().Info("retrieving reservation history")
+
+	reservation, err := c.getItem(reservationId, false)
+	if err != nil {
+		return nil, err
+	}
+	if reservation != nil {
+		err = c.authorize(OPERATION_READ, *reservation)
+	} else if c.UserRole != constants.ROLE_ADMIN {
+		err = ReservationNotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	exclusiveStartKey, err := database.DecodeNextToken(input.NextToken, c.clubPK())
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.DatabaseClient.Query(&database.QueryInput{
+		Limit:                  input.Limit,
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: c.clubPK()},
+			":sk": &types.AttributeValueMemberS{Value: historySKPrefix(reservationId)},
+		},
+		ExclusiveStartKey: exclusiveStartKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]HistoryEntry, 0)
+	err = attributevalue.UnmarshalListOfMaps(output.Items, &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	nextToken, err := database.EncodeNextToken(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("reservation history retrieved", "count", len(entries), "isNextToken", nextToken != nil)
+	return &HistoryOutput{
+		NextToken: nextToken,
+		Results:   entries,
+	}, nil
+}
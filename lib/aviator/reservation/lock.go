@@ -0,0 +1,284 @@
+package reservation
+
+import (
+	"aviator/aircraft"
+	"aviator/database"
+	"aviator/member"
+	"aviator/resource"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const LOCK_PARTITION_KEY = "LOCK"
+
+// Number of times a reservation write is retried when a concurrent write modified one of its slot locks
+const MAX_WRITE_ATTEMPTS = 3
+
+// Maximum number of items written by a single DynamoDB transaction
+const MAX_TRANSACT_ITEMS = 100
+
+// A reserved time interval stored inside a slot lock
+type slot struct {
+	StartTime time.Time
+	EndTime   time.Time
+	Pilot     string
+}
+
+// Database item holding all reservations of a resource (aircraft, room, simulator, pilot or instructor) for a given UTC day.
+// Every write touching the day must increment Version, which serializes concurrent writers.
+type slotLockItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key: e.g. LOCK#AIRCRAFT#HB-KFQ#2024-03-08, LOCK#RESOURCE#01HRB3T7C6M8N2Q4W9X5Y1Z0AB#2024-03-08
+	// or LOCK#MEMBER#01H64K6E1H92C83DXSK1A0SD0R#2024-03-08
+	SK string
+
+	// Item type: lock
+	ItemType     string
+	Version      int
+	Reservations map[string]slot
+	// Locked resource, part of the sort key: e.g. AIRCRAFT#HB-KFQ
+	Resource string `dynamodbav:"-"`
+}
+
+// Returns the UTC days covered by the [start, end) interval.
+func slotDays(start time.Time, end time.Time) []string {
+	days := make([]string, 0)
+	day := start.UTC().Truncate(24 * time.Hour)
+	for day.Before(end) {
+		days = append(days, day.Format("2006-01-02"))
+		day = day.Add(24 * time.Hour)
+	}
+	return days
+}
+
+// Returns the lock key of an aircraft.
+func aircraftLock(registration string) string {
+	return fmt.Sprintf("%s#%s", aircraft.AIRCRAFT_PARTITION_KEY, registration)
+}
+
+// Returns the lock key of a room or simulator.
+func resourceLock(resourceId string) string {
+	return fmt.Sprintf("%s#%s", resource.RESOURCE_PARTITION_KEY, resourceId)
+}
+
+// Returns the lock key of a pilot or instructor.
+func memberLock(memberId string) string {
+	return fmt.Sprintf("%s#%s", member.MEMBER_PARTITION_KEY, memberId)
+}
+
+// Returns the lock keys of all resources held by a reservation: its aircraft, rooms and simulators,
+// then its pilot and instructor, who cannot be in two reservations at the same time either.
+// Locks are read by key, so checking the people of a reservation does not scan any partition.
+func lockedResources(reservation Reservation) []string {
+	resources := make([]string, 0, len(reservation.Resources)+3)
+	if reservation.Aircraft != "" {
+		resources = append(resources, aircraftLock(reservation.Aircraft))
+	}
+	for _, resourceId := range reservation.Resources {
+		resources = append(resources, resourceLock(resourceId))
+	}
+	if reservation.Pilot != "" {
+		resources = append(resources, memberLock(reservation.Pilot))
+	}
+	if reservation.Instructor != nil {
+		resources = append(resources, memberLock(*reservation.Instructor))
+	}
+	return resources
+}
+
+func slotLockSK(lockedResource string, day string) string {
+	return fmt.Sprintf("%s#%s#%s", LOCK_PARTITION_KEY, lockedResource, day)
+}
+
+// Reads the slot locks of the resources for the given days with strongly consistent reads.
+// Missing locks are returned with a zero version.
+func (c *Client) readSlotLocks(resources []string, days []string) (map[string]*slotLockItem, error) {
+	locks := make(map[string]*slotLockItem)
+	for _, lockedResource := range resources {
+		for _, day := range days {
+			sk := slotLockSK(lockedResource, day)
+			if _, ok := locks[sk]; ok {
+				continue
+			}
+
+			output, err := c.DatabaseClient.Get(database.GetInput{
+				PK:             c.clubPK(),
+				SK:             sk,
+				ConsistentRead: true,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			lock := &slotLockItem{
+				PK:           c.clubPK(),
+				SK:           sk,
+				ItemType:     "lock",
+				Reservations: make(map[string]slot),
+			}
+			if output.Item != nil {
+				err = attributevalue.UnmarshalMap(output.Item, lock)
+				if err != nil {
+					return nil, err
+				}
+			}
+			lock.Resource = lockedResource
+			locks[sk] = lock
+		}
+	}
+	return locks, nil
+}
+
+// Returns the first reservation found in the locks of the input overlapping it, ignoring the input itself,
+// along with the lock holding it. Locks are checked in the order of lockedResources.
+func findOverlap(locks map[string]*slotLockItem, input Reservation) (string, *slot, *slotLockItem) {
+	for _, lockedResource := range lockedResources(input) {
+		for _, day := range slotDays(input.StartTime, input.EndTime) {
+			lock, ok := locks[slotLockSK(lockedResource, day)]
+			if !ok {
+				continue
+			}
+			for id, s := range lock.Reservations {
+				if id == input.Id {
+					continue
+				}
+				if s.StartTime.Before(input.EndTime) && input.StartTime.Before(s.EndTime) {
+					return id, &s, lock
+				}
+			}
+		}
+	}
+	return "", nil, nil
+}
+
+// Returns the error explaining why the input overlaps a reservation found in the locks, or nil if it does not.
+func (c *Client) overlapError(locks map[string]*slotLockItem, input Reservation) error {
+	conflictId, conflict, lock := findOverlap(locks, input)
+	if conflict == nil {
+		return nil
+	}
+	c.Logger().Info("reservation overlaps an existing reservation", "conflict", conflictId, "resource", lock.Resource)
+	switch {
+	case lock.Resource == memberLock(input.Pilot):
+		return ReservationPilotConflictError(input.Pilot)
+	case input.Instructor != nil && lock.Resource == memberLock(*input.Instructor):
+		return ReservationInstructorConflictError(*input.Instructor)
+	case lock.Resource != aircraftLock(input.Aircraft):
+		return ReservationResourceConflictError
+	case conflict.Pilot == input.Pilot:
+		return ReservationDoubleBookerError
+	}
+	return ReservationOverbookingConflictError
+}
+
+// Builds the transaction item adding the reservation to a slot lock.
+// The write only succeeds if nobody modified the lock since it was read.
+func (c *Client) lockSlotItem(lock *slotLockItem, input Reservation) (*types.TransactWriteItem, error) {
+	values := map[string]types.AttributeValue{
+		":itemType": &types.AttributeValueMemberS{Value: lock.ItemType},
+		":one":      &types.AttributeValueMemberN{Value: "1"},
+	}
+
+	var updateExpression string
+	var conditionExpression string
+	if lock.Version == 0 {
+		reservations, err := attributevalue.Marshal(map[string]slot{input.Id: slotFrom(input)})
+		if err != nil {
+			return nil, err
+		}
+		values[":reservations"] = reservations
+		updateExpression = "SET ItemType = :itemType, Reservations = :reservations ADD Version :one"
+		conditionExpression = "attribute_not_exists(PK)"
+	} else {
+		reservation, err := attributevalue.Marshal(slotFrom(input))
+		if err != nil {
+			return nil, err
+		}
+		values[":reservation"] = reservation
+		values[":version"] = &types.AttributeValueMemberN{Value: fmt.Sprint(lock.Version)}
+		updateExpression = "SET ItemType = :itemType, Reservations.#id = :reservation ADD Version :one"
+		conditionExpression = "Version = :version"
+	}
+
+	update := &types.Update{
+		TableName: aws.String(c.DatabaseClient.TableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: lock.PK},
+			"SK": &types.AttributeValueMemberS{Value: lock.SK},
+		},
+		UpdateExpression:          aws.String(updateExpression),
+		ConditionExpression:       aws.String(conditionExpression),
+		ExpressionAttributeValues: values,
+	}
+	if lock.Version != 0 {
+		update.ExpressionAttributeNames = map[string]string{"#id": input.Id}
+	}
+
+	return &types.TransactWriteItem{Update: update}, nil
+}
+
+// Builds the transaction item replacing all reservations of a slot lock, used when a single transaction
+// adds several reservations to the same lock.
+// The write only succeeds if nobody modified the lock since it was read.
+func (c *Client) writeSlotLockItem(lock *slotLockItem) (*types.TransactWriteItem, error) {
+	reservations, err := attributevalue.Marshal(lock.Reservations)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]types.AttributeValue{
+		":itemType":     &types.AttributeValueMemberS{Value: lock.ItemType},
+		":reservations": reservations,
+		":one":          &types.AttributeValueMemberN{Value: "1"},
+	}
+	conditionExpression := "attribute_not_exists(PK)"
+	if lock.Version != 0 {
+		values[":version"] = &types.AttributeValueMemberN{Value: fmt.Sprint(lock.Version)}
+		conditionExpression = "Version = :version"
+	}
+
+	return &types.TransactWriteItem{
+		Update: &types.Update{
+			TableName: aws.String(c.DatabaseClient.TableName),
+			Key: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: lock.PK},
+				"SK": &types.AttributeValueMemberS{Value: lock.SK},
+			},
+			UpdateExpression:          aws.String("SET ItemType = :itemType, Reservations = :reservations ADD Version :one"),
+			ConditionExpression:       aws.String(conditionExpression),
+			ExpressionAttributeValues: values,
+		},
+	}, nil
+}
+
+// Builds the transaction item removing a reservation from a slot lock.
+func (c *Client) unlockSlotItem(lock *slotLockItem, reservationId string) *types.TransactWriteItem {
+	return &types.TransactWriteItem{
+		Update: &types.Update{
+			TableName: aws.String(c.DatabaseClient.TableName),
+			Key: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: lock.PK},
+				"SK": &types.AttributeValueMemberS{Value: lock.SK},
+			},
+			UpdateExpression:         aws.String("REMOVE Reservations.#id ADD Version :one"),
+			ConditionExpression:      aws.String("Version = :version"),
+			ExpressionAttributeNames: map[string]string{"#id": reservationId},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":one":     &types.AttributeValueMemberN{Value: "1"},
+				":version": &types.AttributeValueMemberN{Value: fmt.Sprint(lock.Version)},
+			},
+		},
+	}
+}
+
+func slotFrom(input Reservation) slot {
+	return slot{
+		StartTime: input.StartTime,
+		EndTime:   input.EndTime,
+		Pilot:     input.Pilot,
+	}
+}
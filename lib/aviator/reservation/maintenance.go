@@ -0,0 +1,161 @@
+package reservation
+
+import (
+	"aviator/database"
+	"aviator/maintenance"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Maintenance block along with the reservations it invalidates
+type MaintenanceImpact struct {
+	Block *maintenance.Block `json:"block"`
+	// Open reservations of the aircraft overlapping the block, ordered by start time
+	AffectedReservations []Reservation `json:"affectedReservations"`
+}
+
+// Checks that the aircraft is not grounded or in maintenance during the reservation.
+func (c *Client) validateMaintenance(input Reservation) error {
+	maintenanceClient := maintenance.NewFromConfig(maintenance.Config{Scope: c.Scope})
+
+	blocks, err := maintenanceClient.Overlapping(input.Aircraft, input.StartTime, input.EndTime)
+	if err != nil {
+		return err
+	}
+	for _, block := range blocks {
+		if block.BlockType == maintenance.TYPE_GROUNDED {
+			return ReservationAircraftGroundedError
+		}
+	}
+	if len(blocks) > 0 {
+		return ReservationAircraftMaintenanceError
+	}
+	return nil
+}
+
+// Checks the reservation against the maintenance blocks of its aircraft and returns the transaction item
+// failing the reservation write if a block of the aircraft is written in the meantime.
+// The version is read before the blocks, so a block written after the check always changes it.
+func (c *Client) maintenanceCheckItem(input Reservation) (*types.TransactWriteItem, error) {
+	maintenanceClient := maintenance.NewFromConfig(maintenance.Config{Scope: c.Scope})
+
+	version, err := maintenanceClient.LockVersion(input.Aircraft)
+	if err != nil {
+		return nil, err
+	}
+	err = c.validateMaintenance(input)
+	if err != nil {
+		return nil, err
+	}
+	checkItem := maintenanceClient.NewLockCheckItem(input.Aircraft, version)
+	return &checkItem, nil
+}
+
+// Returns the busy intervals of an aircraft due to maintenance between start and end.
+func (c *Client) maintenanceIntervals(registration string, start time.Time, end time.Time) ([]interval, error) {
+	maintenanceClient := maintenance.NewFromConfig(maintenance.Config{Scope: c.Scope})
+
+	blocks, err := maintenanceClient.Overlapping(registration, start, end)
+	if err != nil {
+		return nil, err
+	}
+	intervals := make([]interval, 0, len(blocks))
+	for _, block := range blocks {
+		blockEnd := end
+		if block.EndTime != nil && block.EndTime.Before(end) {
+			blockEnd = *block.EndTime
+		}
+		intervals = append(intervals, interval{start: block.StartTime, end: blockEnd})
+	}
+	return intervals, nil
+}
+
+// Returns the open reservations invalidated by a maintenance block, so dispatchers can contact the pilots.
+// Reservations which already ended are ignored. Ongoing reservations are read from the slot lock of the aircraft
+// for the first affected day, and the following ones from the key range of the aircraft in GSI1,
+// so the history of the aircraft is not scanned even for an open-ended grounding.
+func (c *Client) MaintenanceImpact(block maintenance.Block) (*MaintenanceImpact, error) {
+	c.SetLogger(c.Logger().With("aircraft", block.Aircraft, "block", block.Id))
+	c.Logger().Info("listing reservations affected by maintenance")
+
+	impact := &MaintenanceImpact{Block: &block, AffectedReservations: make([]Reservation, 0)}
+	from := block.StartTime
+	if now := time.Now().UTC(); now.After(from) {
+		from = now
+	}
+	if block.EndTime != nil && !from.Before(*block.EndTime) {
+		return impact, nil
+	}
+
+	ids := make([]string, 0)
+	locks, err := c.readSlotLocks([]string{aircraftLock(block.Aircraft)}, slotDays(from, from.Add(time.Nanosecond)))
+	if err != nil {
+		return nil, err
+	}
+	for _, lock := range locks {
+		for id, s := range lock.Reservations {
+			if s.StartTime.Before(from) && s.EndTime.After(from) {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	skEnd := RESERVATION_PARTITION_KEY + "#~"
+	if block.EndTime != nil {
+		skEnd = fmt.Sprintf("%s#%s~", RESERVATION_PARTITION_KEY, block.EndTime.UTC().Format(TIME_KEY_FORMAT))
+	}
+	queryInput := database.QueryInput{
+		Index:                  aws.String("GSI1"),
+		KeyConditionExpression: aws.String("GSI1PK = :pk AND GSI1SK BETWEEN :skStart AND :skEnd"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":      &types.AttributeValueMemberS{Value: c.aircraftPK(block.Aircraft)},
+			":skStart": &types.AttributeValueMemberS{Value: fmt.Sprintf("%s#%s", RESERVATION_PARTITION_KEY, from.Format(TIME_KEY_FORMAT))},
+			":skEnd":   &types.AttributeValueMemberS{Value: skEnd},
+		},
+	}
+	for {
+		output, err := c.DatabaseClient.Query(&queryInput)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range output.Items {
+			reservation, err := unmarshalListItem(item, true)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, reservation.Id)
+		}
+		if len(output.LastEvaluatedKey) == 0 {
+			break
+		}
+		queryInput.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		reservation, err := c.getItem(id, false)
+		if err != nil {
+			return nil, err
+		}
+		if reservation == nil || !isOpen(reservation.Status) || !block.Overlaps(reservation.StartTime, reservation.EndTime) ||
+			!c.isAllowed(OPERATION_READ, *reservation) {
+			continue
+		}
+		impact.AffectedReservations = append(impact.AffectedReservations, *reservation)
+	}
+	sort.Slice(impact.AffectedReservations, func(i, j int) bool {
+		return impact.AffectedReservations[i].StartTime.Before(impact.AffectedReservations[j].StartTime)
+	})
+
+	c.Logger().Info("reservations affected by maintenance listed", "count", len(impact.AffectedReservations))
+	return impact, nil
+}
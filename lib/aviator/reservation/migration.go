@@ -0,0 +1,189 @@
+package reservation
+
+import (
+	"aviator/constants"
+	"aviator/database"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+type MigrateInput struct {
+	NextToken *string
+	Limit     *int32
+}
+
+type MigrateOutput struct {
+	NextToken *string
+	Migrated  int
+}
+
+// Migrates one page of stored reservations to the current key layout.
+// Reservations are re-indexed by aircraft and start time in GSI1, registered in their slot locks
+// and added to the calendars of their members.
+// Migrating a reservation twice is harmless, so the migration can be restarted from any token.
+func (c *Client) Migrate(input MigrateInput) (*MigrateOutput, error) {
+	c.Logger().Info("migrating reservations")
+
+	if c.UserRole != constants.ROLE_ADMIN {
+		return nil, ReservationUnauthorizedError
+	}
+
+	exclusiveStartKey, err := database.DecodeNextToken(input.NextToken, c.clubPK())
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.DatabaseClient.Query(&database.QueryInput{
+		Limit:                  input.Limit,
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: c.clubPK()},
+			":sk": &types.AttributeValueMemberS{Value: RESERVATION_PARTITION_KEY + "#"},
+		},
+		ExclusiveStartKey: exclusiveStartKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	migrated := 0
+	for _, item := range output.Items {
+		var reservation Reservation
+		err := attributevalue.UnmarshalMap(item, &reservation)
+		if err != nil {
+			return nil, err
+		}
+
+		for attempt := 1; ; attempt++ {
+			err = c.migrate(reservation)
+			if err == nil {
+				break
+			}
+			if !database.IsConditionalCheckFailure(err) || attempt == MAX_WRITE_ATTEMPTS {
+				return nil, err
+			}
+		}
+		migrated++
+	}
+
+	nextToken, err := database.EncodeNextToken(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("reservations migrated", "count", migrated, "isNextToken", nextToken != nil)
+	return &MigrateOutput{
+		NextToken: nextToken,
+		Migrated:  migrated,
+	}, nil
+}
+
+// Rewrites the index attributes of a reservation, adds it to the slot locks it is missing from
+// and to the calendars of its members. Reservations without a booker are considered booked by their pilot,
+// and reservations without a status are considered confirmed.
+// The timestamps of the reservation are left untouched.
+func (c *Client) migrate(reservation Reservation) error {
+	if reservation.Booker == "" {
+		reservation.Booker = reservation.Pilot
+	}
+	normalizeStatus(&reservation)
+	item := c.newDatabaseItem(reservation)
+	gsiData, err := attributevalue.Marshal(item.GSIData)
+	if err != nil {
+		return err
+	}
+
+	// Index keys cannot be empty strings: reservations without an aircraft are removed from GSI1
+	updateExpression := "SET GSIData = :gsiData, Booker = :booker, #status = :status"
+	expressionAttributeValues := map[string]types.AttributeValue{
+		":gsiData": gsiData,
+		":booker":  &types.AttributeValueMemberS{Value: reservation.Booker},
+		":status":  &types.AttributeValueMemberS{Value: reservation.Status},
+	}
+	if item.GSI1PK != "" {
+		updateExpression += ", GSI1PK = :gsi1pk, GSI1SK = :gsi1sk"
+		expressionAttributeValues[":gsi1pk"] = &types.AttributeValueMemberS{Value: item.GSI1PK}
+		expressionAttributeValues[":gsi1sk"] = &types.AttributeValueMemberS{Value: item.GSI1SK}
+	} else {
+		updateExpression += " REMOVE GSI1PK, GSI1SK"
+	}
+
+	transactItems := []types.TransactWriteItem{
+		{
+			Update: &types.Update{
+				TableName: aws.String(c.DatabaseClient.TableName),
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: item.PK},
+					"SK": &types.AttributeValueMemberS{Value: item.SK},
+				},
+				UpdateExpression:    aws.String(updateExpression),
+				ConditionExpression: aws.String("attribute_exists(PK)"),
+				ExpressionAttributeNames: map[string]string{
+					"#status": "Status",
+				},
+				ExpressionAttributeValues: expressionAttributeValues,
+			},
+		},
+	}
+
+	// Cancelled reservations do not hold any slot
+	locks := make(map[string]*slotLockItem)
+	if reservation.Status != STATUS_CANCELLED {
+		locks, err = c.readSlotLocks(lockedResources(reservation), slotDays(reservation.StartTime, reservation.EndTime))
+		if err != nil {
+			return err
+		}
+	}
+	// Existing data is kept as is: overlapping reservations are reported but still locked
+	if conflictId, conflict, _ := findOverlap(locks, reservation); conflict != nil {
+		c.Logger().Warn("migrated reservation overlaps an existing reservation",
+			"reservation", reservation.Id, "conflict", conflictId)
+	}
+	for _, lock := range locks {
+		if _, ok := lock.Reservations[reservation.Id]; ok {
+			continue
+		}
+		transactItem, err := c.lockSlotItem(lock, reservation)
+		if err != nil {
+			return err
+		}
+		transactItems = append(transactItems, *transactItem)
+	}
+
+	// Calendar entries are updated rather than put, so that they keep the timestamps of the reservation
+	for _, cal := range c.reservationCalendars(reservation) {
+		entry := c.newCalendarEntryItem(reservation, cal)
+		transactItems = append(transactItems, types.TransactWriteItem{
+			Update: &types.Update{
+				TableName: aws.String(c.DatabaseClient.TableName),
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: entry.PK},
+					"SK": &types.AttributeValueMemberS{Value: entry.SK},
+				},
+				UpdateExpression: aws.String("SET GSI1PK = :gsi1pk, GSI1SK = :gsi1sk, ItemType = :itemType, Id = :id, " +
+					"GSIData = :gsiData, CreatedAt = :createdAt, UpdatedAt = :updatedAt"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":gsi1pk":    &types.AttributeValueMemberS{Value: entry.GSI1PK},
+					":gsi1sk":    &types.AttributeValueMemberS{Value: entry.GSI1SK},
+					":itemType":  &types.AttributeValueMemberS{Value: entry.ItemType},
+					":id":        &types.AttributeValueMemberS{Value: entry.Id},
+					":gsiData":   gsiData,
+					":createdAt": &types.AttributeValueMemberS{Value: entry.CreatedAt.Format("2006-01-02T15:04:05.000Z")},
+					":updatedAt": &types.AttributeValueMemberS{Value: entry.UpdatedAt.Format("2006-01-02T15:04:05.000Z")},
+				},
+			},
+		})
+	}
+
+	_, err = c.DatabaseClient.TransactWriteItems(dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+	if err != nil {
+		return fmt.Errorf("migrating reservation %s: %w", reservation.Id, err)
+	}
+	return nil
+}
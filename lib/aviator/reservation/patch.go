@@ -0,0 +1,196 @@
+package reservation
+
+import (
+	"aviator/database"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Partial update of a reservation, only the fields that are set are changed.
+// The aircraft and the instructor cannot be removed by a patch, replace the reservation instead.
+type ReservationPatch struct {
+	Aircraft *string `json:"aircraft"`
+	// Replaces all rooms and simulators of the reservation
+	Resources       *[]string  `json:"resources"`
+	ReservationType *string    `json:"reservationType"`
+	Pilot           *string    `json:"pilot"`
+	Instructor      *string    `json:"instructor"`
+	StartTime       *time.Time `json:"startTime"`
+	EndTime         *time.Time `json:"endTime"`
+	Remarks         *string    `json:"remarks"`
+	// The patch only succeeds if the stored reservation still has this version
+	Version *int `json:"version"`
+}
+
+// Attributes of the reservation item changed by a patch, field names are the attribute names.
+// Nil fields are left untouched by database.UpdateExpression.
+type patchItem struct {
+	GSI1PK          *string
+	GSI1SK          *string
+	Aircraft        *string
+	Resources       *[]string
+	ReservationType *string
+	Booker          *string
+	Pilot           *string
+	Instructor      *string
+	StartTime       *time.Time
+	EndTime         *time.Time
+	Remarks         *string
+	GSIData         *map[string]interface{}
+}
+
+// Applies a partial update to a reservation.
+// All business validations run on the patched reservation, and only the changed attributes are written.
+func (c *Client) Patch(reservationId string, patch ReservationPatch) (*Reservation, error) {
+	c.SetLogger(c.Logger().With("reservation", reservationId))
+	c.Logger().Info("patching reservation")
+
+	var out *Reservation
+	for attempt := 1; ; attempt++ {
+		var err error
+		out, err = c.patch(reservationId, patch)
+		if err == nil {
+			break
+		}
+		if !database.IsConditionalCheckFailure(err) || attempt == MAX_WRITE_ATTEMPTS {
+			return nil, err
+		}
+		c.Logger().Warn("concurrent reservation write detected, retrying", "attempt", attempt)
+	}
+
+	c.Logger().Info("reservation patched", "version", out.Version)
+	return out, nil
+}
+
+func (c *Client) patch(reservationId string, patch ReservationPatch) (*Reservation, error) {
+	previous, err := c.getItem(reservationId, true)
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		return nil, ReservationNotFoundError
+	}
+	err = c.authorize(OPERATION_UPDATE, *previous)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Version != nil && *patch.Version != previous.Version {
+		c.Logger().Info("reservation was modified in the meantime", "version", *patch.Version, "storedVersion", previous.Version)
+		return nil, ReservationVersionMismatchError
+	}
+
+	if !isOpen(previous.Status) {
+		return nil, ReservationClosedError
+	}
+	if patch.Aircraft != nil && *patch.Aircraft == "" {
+		return nil, ReservationInvalidAircraftError
+	}
+
+	merged := *previous
+	if merged.Booker == "" {
+		merged.Booker = merged.Pilot
+	}
+	item := patchItem{
+		Aircraft:        patch.Aircraft,
+		Resources:       patch.Resources,
+		ReservationType: patch.ReservationType,
+		Pilot:           patch.Pilot,
+		Instructor:      patch.Instructor,
+		StartTime:       patch.StartTime,
+		EndTime:         patch.EndTime,
+		Remarks:         patch.Remarks,
+	}
+	if patch.Aircraft != nil {
+		merged.Aircraft = *patch.Aircraft
+	}
+	if patch.Resources != nil {
+		merged.Resources = *patch.Resources
+	}
+	if patch.ReservationType != nil {
+		merged.ReservationType = *patch.ReservationType
+	}
+	if patch.Pilot != nil {
+		merged.Pilot = *patch.Pilot
+	}
+	if patch.Instructor != nil {
+		merged.Instructor = patch.Instructor
+	}
+	if patch.StartTime != nil {
+		merged.StartTime = *patch.StartTime
+	}
+	if patch.EndTime != nil {
+		merged.EndTime = *patch.EndTime
+	}
+	if patch.Remarks != nil {
+		merged.Remarks = *patch.Remarks
+	}
+	merged.Version = previous.Version + 1
+
+	err = c.authorize(OPERATION_UPDATE, merged)
+	if err != nil {
+		return nil, err
+	}
+	err = c.validate(merged, previous)
+	if err != nil {
+		return nil, err
+	}
+
+	// The index keys follow the aircraft and start time, and GSIData mirrors the changed attributes.
+	// Reservations without aircraft have no GSI1 partition key.
+	databaseItem := c.newDatabaseItem(merged)
+	if patch.Aircraft != nil || patch.StartTime != nil {
+		if databaseItem.GSI1PK != "" {
+			item.GSI1PK = &databaseItem.GSI1PK
+		}
+		item.GSI1SK = &databaseItem.GSI1SK
+	}
+	gsiData := map[string]interface{}{
+		"Version": databaseItem.GSIData.Version,
+	}
+	if previous.Booker == "" {
+		item.Booker = &merged.Booker
+		gsiData["Booker"] = databaseItem.GSIData.Booker
+	}
+	if patch.Aircraft != nil {
+		gsiData["Aircraft"] = databaseItem.GSIData.Aircraft
+	}
+	if patch.Resources != nil {
+		gsiData["Resources"] = merged.Resources
+	}
+	if patch.ReservationType != nil {
+		gsiData["ReservationType"] = databaseItem.GSIData.ReservationType
+	}
+	if patch.Pilot != nil {
+		gsiData["Pilot"] = databaseItem.GSIData.Pilot
+	}
+	if patch.Instructor != nil {
+		gsiData["Instructor"] = *databaseItem.GSIData.Instructor
+	}
+	if patch.StartTime != nil {
+		gsiData["StartTime"] = databaseItem.GSIData.StartTime
+	}
+	if patch.EndTime != nil {
+		gsiData["EndTime"] = databaseItem.GSIData.EndTime
+	}
+	if patch.Remarks != nil {
+		gsiData["Remarks"] = databaseItem.GSIData.Remarks
+	}
+	item.GSIData = &gsiData
+
+	update, updatedAt, err := c.DatabaseClient.NewUpdate(database.UpdateInput{
+		PK:                  databaseItem.PK,
+		SK:                  databaseItem.SK,
+		Item:                item,
+		ConditionExpression: aws.String("attribute_exists(PK)"),
+		ExpectedVersion:     &previous.Version,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("building reservation update: %w", err)
+	}
+	merged.UpdatedAt = updatedAt
+
+	return c.transactWrite(merged, previous, OPERATION_UPDATE, types.TransactWriteItem{Update: update})
+}
@@ -0,0 +1,85 @@
+package reservation
+
+import "aviator/constants"
+
+// Operation performed on a reservation, checked against the role of the caller
+type operation string
+
+const OPERATION_READ operation = "read"
+const OPERATION_CREATE operation = "create"
+const OPERATION_UPDATE operation = "update"
+const OPERATION_DELETE operation = "delete"
+const OPERATION_CONFIRM operation = "confirm"
+const OPERATION_CANCEL operation = "cancel"
+const OPERATION_CHECK_OUT operation = "check-out"
+const OPERATION_CHECK_IN operation = "check-in"
+const OPERATION_NO_SHOW operation = "no-show"
+
+// Returns true if the caller may perform the operation on the reservation:
+//   - admins may perform any operation on any reservation, and are the only ones who may delete reservations
+//   - instructors may read, confirm and record no-shows of all reservations, and write the ones they booked, fly or instruct
+//   - pilots may read all reservations, and write the ones they fly, so they cannot book on behalf of another pilot.
+//     They cannot confirm their own reservations nor record no-shows
+//   - guests may only read the reservations they booked or fly
+//
+// Unknown roles are denied everything.
+func (c *Client) isAllowed(op operation, reservation Reservation) bool {
+	isPilot := c.UserId != "" && reservation.Pilot == c.UserId
+	isInstructor := c.UserId != "" && reservation.Instructor != nil && *reservation.Instructor == c.UserId
+	isBooker := c.UserId != "" && reservation.Booker == c.UserId
+	isDispatch := op == OPERATION_CONFIRM || op == OPERATION_NO_SHOW
+
+	// Reservations are kept for statistics, other roles cancel them instead
+	if op == OPERATION_DELETE {
+		return c.UserRole == constants.ROLE_ADMIN
+	}
+
+	switch c.UserRole {
+	case constants.ROLE_ADMIN:
+		return true
+	case constants.ROLE_INSTRUCTOR:
+		return op == OPERATION_READ || isDispatch || isPilot || isInstructor || isBooker
+	case constants.ROLE_PILOT:
+		return op == OPERATION_READ || (isPilot && !isDispatch)
+	case constants.ROLE_GUEST:
+		return op == OPERATION_READ && (isPilot || isBooker)
+	}
+	return false
+}
+
+// Returns ReservationUnauthorizedError if the caller may not perform the operation on the reservation.
+func (c *Client) authorize(op operation, reservation Reservation) error {
+	if !c.isAllowed(op, reservation) {
+		c.Logger().Warn("reservation operation denied", "operation", op, "user", c.UserId, "role", c.UserRole)
+		return ReservationUnauthorizedError
+	}
+	return nil
+}
+
+// Restricts a listing to the reservations the caller may read.
+// Only guests are restricted, to the reservations they fly.
+func (c *Client) authorizeList(input ListInput) (ListInput, error) {
+	switch c.UserRole {
+	case constants.ROLE_ADMIN, constants.ROLE_INSTRUCTOR, constants.ROLE_PILOT:
+		return input, nil
+	case constants.ROLE_GUEST:
+		if c.UserId == "" || (input.Pilot != nil && *input.Pilot != c.UserId) {
+			break
+		}
+		input.Pilot = &c.UserId
+		return input, nil
+	}
+	c.Logger().Warn("reservation listing denied", "user", c.UserId, "role", c.UserRole)
+	return input, ReservationUnauthorizedError
+}
+
+// Returns ReservationUnauthorizedError if the caller may not see the free slots of the club.
+// Free slots do not reveal who holds the other ones, so every known role may see them.
+func (c *Client) authorizeAvailability() error {
+	switch c.UserRole {
+	case constants.ROLE_ADMIN, constants.ROLE_INSTRUCTOR, constants.ROLE_PILOT, constants.ROLE_GUEST:
+		return nil
+	}
+	c.Logger().Warn("availability denied", "user", c.UserId, "role", c.UserRole)
+	return ReservationUnauthorizedError
+}
@@ -0,0 +1,185 @@
+package reservation
+
+import (
+	"aviator/constants"
+	"aviator/tenant"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+const USER_ID = "01H55420KY47HRVVPK1Z3BSACK"
+const OTHER_ID = "01HS2B7Y8YJ0G5B3RM2Q2R7C9F"
+
+var allOperations = []operation{
+	OPERATION_READ, OPERATION_CREATE, OPERATION_UPDATE, OPERATION_DELETE, OPERATION_CONFIRM,
+	OPERATION_CANCEL, OPERATION_CHECK_OUT, OPERATION_CHECK_IN, OPERATION_NO_SHOW,
+}
+
+// Operations every role but guests may perform on reservations they fly
+var pilotOperations = []operation{
+	OPERATION_READ, OPERATION_CREATE, OPERATION_UPDATE,
+	OPERATION_CANCEL, OPERATION_CHECK_OUT, OPERATION_CHECK_IN,
+}
+
+// Operations instructors may perform on reservations they book, fly or instruct
+var instructorOperations = []operation{
+	OPERATION_READ, OPERATION_CREATE, OPERATION_UPDATE, OPERATION_CONFIRM,
+	OPERATION_CANCEL, OPERATION_CHECK_OUT, OPERATION_CHECK_IN, OPERATION_NO_SHOW,
+}
+
+var dispatchOperations = []operation{OPERATION_READ, OPERATION_CONFIRM, OPERATION_NO_SHOW}
+
+func newTestClient(userId string, role string) *Client {
+	return NewFromConfig(Config{Scope: tenant.Scope{
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		TenantId: "01HR9ZZNRFCKMAYNW3RY561QCP",
+		UserId:   userId,
+		UserRole: role,
+	}})
+}
+
+// Returns a reservation of which the caller is the pilot, the instructor, the booker or nothing at all.
+func reservationOwnedAs(ownership string) Reservation {
+	reservation := Reservation{Pilot: OTHER_ID, Booker: OTHER_ID}
+	switch ownership {
+	case "pilot":
+		reservation.Pilot = USER_ID
+		reservation.Booker = USER_ID
+	case "instructor":
+		instructor := USER_ID
+		reservation.Instructor = &instructor
+	case "booker":
+		reservation.Booker = USER_ID
+	}
+	return reservation
+}
+
+func TestIsAllowed(t *testing.T) {
+	tests := []struct {
+		role      string
+		ownership string
+		allowed   []operation
+	}{
+		{constants.ROLE_ADMIN, "pilot", allOperations},
+		{constants.ROLE_ADMIN, "instructor", allOperations},
+		{constants.ROLE_ADMIN, "booker", allOperations},
+		{constants.ROLE_ADMIN, "other", allOperations},
+		{constants.ROLE_INSTRUCTOR, "pilot", instructorOperations},
+		{constants.ROLE_INSTRUCTOR, "instructor", instructorOperations},
+		{constants.ROLE_INSTRUCTOR, "booker", instructorOperations},
+		{constants.ROLE_INSTRUCTOR, "other", dispatchOperations},
+		{constants.ROLE_PILOT, "pilot", pilotOperations},
+		{constants.ROLE_PILOT, "instructor", []operation{OPERATION_READ}},
+		{constants.ROLE_PILOT, "booker", []operation{OPERATION_READ}},
+		{constants.ROLE_PILOT, "other", []operation{OPERATION_READ}},
+		{constants.ROLE_GUEST, "pilot", []operation{OPERATION_READ}},
+		{constants.ROLE_GUEST, "instructor", nil},
+		{constants.ROLE_GUEST, "booker", []operation{OPERATION_READ}},
+		{constants.ROLE_GUEST, "other", nil},
+		{"unknown", "pilot", nil},
+		{"unknown", "other", nil},
+	}
+
+	for _, test := range tests {
+		client := newTestClient(USER_ID, test.role)
+		reservation := reservationOwnedAs(test.ownership)
+		for _, op := range allOperations {
+			expected := false
+			for _, allowed := range test.allowed {
+				expected = expected || allowed == op
+			}
+
+			if got := client.isAllowed(op, reservation); got != expected {
+				t.Errorf("%s %s on %s reservation: isAllowed = %v, want %v", test.role, op, test.ownership, got, expected)
+			}
+
+			err := client.authorize(op, reservation)
+			if expected && err != nil {
+				t.Errorf("%s %s on %s reservation: authorize = %v, want nil", test.role, op, test.ownership, err)
+			}
+			if !expected && !errors.Is(err, ReservationUnauthorizedError) {
+				t.Errorf("%s %s on %s reservation: authorize = %v, want ReservationUnauthorizedError", test.role, op, test.ownership, err)
+			}
+		}
+	}
+}
+
+// Callers without a member Id own no reservation, even the ones stored without pilot or booker.
+func TestIsAllowedWithoutUserId(t *testing.T) {
+	client := newTestClient("", constants.ROLE_GUEST)
+	if client.isAllowed(OPERATION_READ, Reservation{}) {
+		t.Error("guest without member Id allowed to read a reservation without pilot")
+	}
+	client = newTestClient("", constants.ROLE_PILOT)
+	if client.isAllowed(OPERATION_UPDATE, Reservation{}) {
+		t.Error("pilot without member Id allowed to update a reservation without pilot")
+	}
+}
+
+func TestAuthorizeList(t *testing.T) {
+	own, other := USER_ID, OTHER_ID
+	tests := []struct {
+		name      string
+		userId    string
+		role      string
+		pilot     *string
+		wantPilot *string
+		wantError bool
+	}{
+		{"admin lists all", USER_ID, constants.ROLE_ADMIN, nil, nil, false},
+		{"admin lists another pilot", USER_ID, constants.ROLE_ADMIN, &other, &other, false},
+		{"instructor lists all", USER_ID, constants.ROLE_INSTRUCTOR, nil, nil, false},
+		{"instructor lists another pilot", USER_ID, constants.ROLE_INSTRUCTOR, &other, &other, false},
+		{"pilot lists all", USER_ID, constants.ROLE_PILOT, nil, nil, false},
+		{"pilot lists another pilot", USER_ID, constants.ROLE_PILOT, &other, &other, false},
+		{"guest is restricted to their own", USER_ID, constants.ROLE_GUEST, nil, &own, false},
+		{"guest lists their own", USER_ID, constants.ROLE_GUEST, &own, &own, false},
+		{"guest lists another pilot", USER_ID, constants.ROLE_GUEST, &other, nil, true},
+		{"guest without member Id", "", constants.ROLE_GUEST, nil, nil, true},
+		{"unknown role", USER_ID, "unknown", nil, nil, true},
+	}
+
+	for _, test := range tests {
+		client := newTestClient(test.userId, test.role)
+		out, err := client.authorizeList(ListInput{Pilot: test.pilot})
+		if test.wantError {
+			if !errors.Is(err, ReservationUnauthorizedError) {
+				t.Errorf("%s: error = %v, want ReservationUnauthorizedError", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: error = %v, want nil", test.name, err)
+			continue
+		}
+		if (out.Pilot == nil) != (test.wantPilot == nil) || (out.Pilot != nil && *out.Pilot != *test.wantPilot) {
+			t.Errorf("%s: pilot filter = %v, want %v", test.name, out.Pilot, test.wantPilot)
+		}
+	}
+}
+
+func TestAuthorizeAvailability(t *testing.T) {
+	tests := []struct {
+		role      string
+		wantError bool
+	}{
+		{constants.ROLE_ADMIN, false},
+		{constants.ROLE_INSTRUCTOR, false},
+		{constants.ROLE_PILOT, false},
+		{constants.ROLE_GUEST, false},
+		{"unknown", true},
+		{"", true},
+	}
+
+	for _, test := range tests {
+		err := newTestClient(USER_ID, test.role).authorizeAvailability()
+		if test.wantError && !errors.Is(err, ReservationUnauthorizedError) {
+			t.Errorf("%q: error = %v, want ReservationUnauthorizedError", test.role, err)
+		}
+		if !test.wantError && err != nil {
+			t.Errorf("%q: error = %v, want nil", test.role, err)
+		}
+	}
+}
@@ -4,40 +4,53 @@ Package reservation provides methods for performing CRUD operations on reservati
 package reservation
 
 import (
+	"aviator/aircraft"
+	"aviator/checkout"
+	"aviator/constants"
 	"aviator/database"
-	"encoding/base64"
-	"encoding/json"
+	"aviator/maintenance"
+	"aviator/member"
+	"aviator/reservationtype"
+	"aviator/resource"
+	"aviator/tenant"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/oklog/ulid/v2"
 )
 
-const CLUB_PARTITION_KEY = "CLUB"
-
-// In a real app, CLUB_ID would be a dynamic variable
-const CLUB_ID = "01HR9ZZNRFCKMAYNW3RY561QCP"
 const RESERVATION_PARTITION_KEY = "RESERVATION"
 
+// Format of the UTC times used in keys and GSI data, sortable as strings
+const TIME_KEY_FORMAT = "2006-01-02T15:04:05Z"
+
 type ReservationApiInterface interface {
 	Logger() *slog.Logger
 	SetLogger(logger *slog.Logger)
 	CreateOrUpdate(input Reservation) (*Reservation, error)
 	Get(reservationId string) (*Reservation, error)
 	List(input ListInput) (*ListOutput, error)
-	Delete(reservationId string) error
+	Patch(reservationId string, patch ReservationPatch) (*Reservation, error)
+	Delete(reservationId string, expectedVersion int) error
+	History(reservationId string, input HistoryInput) (*HistoryOutput, error)
+	Transition(reservationId string, input TransitionInput) (*Reservation, error)
+	CreateSeries(input SeriesInput) (*SeriesOutput, error)
+	GetSeries(seriesId string) (*SeriesOutput, error)
+	PatchSeries(reservationId string, scope string, patch ReservationPatch) (*SeriesOutput, error)
+	CancelSeries(reservationId string, scope string, input TransitionInput) (*SeriesOutput, error)
+	Availability(input AvailabilityInput) (*AvailabilityOutput, error)
+	MaintenanceImpact(block maintenance.Block) (*MaintenanceImpact, error)
 }
 
 type Config struct {
-	Logger         *slog.Logger
-	DatabaseClient database.Client
-	TenantId       string
-	UserId         string
-	UserRole       string
+	tenant.Scope
 }
 
 type Client struct {
@@ -48,20 +61,33 @@ type Client struct {
 type Reservation struct {
 	// Reservation Id: e.g. 01H55420KY47HRVVPK1Z3BSACK
 	Id string `json:"id"`
-	// Reserved aircraft Id: e.g. HB-KFQ
+	// Reserved aircraft Id, empty when only rooms or simulators are reserved: e.g. HB-KFQ
 	Aircraft string `json:"aircraft"`
+	// Ids of the rooms and simulators reserved along with the aircraft: e.g. ["01HRB3T7C6M8N2Q4W9X5Y1Z0AB"]
+	Resources []string `dynamodbav:",omitempty" json:"resources"`
 	// Flight type
 	ReservationType string `json:"reservationType"`
-	// Pilot who will fly, usually the same as Booker
+	// Member Id of the member who made the reservation, set from the authenticated caller
+	Booker string `json:"booker"`
+	// Member Id of the pilot who will fly, usually the same as Booker
 	Pilot string `json:"pilot"`
-	// Instructor (if any)
+	// Member Id of the instructor (if any)
 	Instructor *string `dynamodbav:",omitempty" json:"instructor"`
 	// Start time of the reservation
 	StartTime time.Time `json:"startTime"`
 	// End time of the reservation
 	EndTime time.Time `json:"endTime"`
 	// Any remarks the booker wants to set for this reservation: e.g. "Short flight to the Matterhorn"
-	Remarks   string    `json:"remarks"`
+	Remarks string `json:"remarks"`
+	// Lifecycle status, changed by transitions only: e.g. confirmed
+	Status string `json:"status"`
+	// Reason given when cancelling the reservation
+	CancellationReason *string `dynamodbav:",omitempty" json:"cancellationReason"`
+	// Series the reservation is an occurrence of (if any), set when the series is created
+	SeriesId *string `dynamodbav:",omitempty" json:"seriesId"`
+	// Version of the reservation, incremented on every write.
+	// When set on an update, the update only succeeds if the stored reservation still has this version.
+	Version   int       `json:"version"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
@@ -72,12 +98,64 @@ type databaseItem struct {
 	PK string
 	// Sort key: e.g. RESERVATION#01H55420KY47HRVVPK1Z3BSACK
 	SK string
+	// GSI1 primary key, not set when no aircraft is reserved: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP#AIRCRAFT#HB-KFQ
+	GSI1PK string `dynamodbav:",omitempty"`
+	// GSI1 sort key, ordered by start time: e.g. RESERVATION#2023-04-05T12:30:00Z#01H55420KY47HRVVPK1Z3BSACK
+	GSI1SK string
 
 	// Item type: reservation
 	ItemType string
+	GSIData  gsiData
 	Reservation
 }
 
+// Reservation attributes projected into GSI1 and used to filter listings.
+// Times are stored in UTC with a fixed format so they can be compared as strings.
+type gsiData struct {
+	Aircraft        string
+	Resources       []string `dynamodbav:",omitempty"`
+	ReservationType string
+	Booker          string
+	Pilot           string
+	Instructor      *string `dynamodbav:",omitempty"`
+	StartTime       string
+	EndTime         string
+	Remarks         string
+	Status          string
+	SeriesId        *string `dynamodbav:",omitempty"`
+	Version         int
+}
+
+func (c *Client) newDatabaseItem(input Reservation) databaseItem {
+	// Reservations without aircraft are only listed from the calendars of their members and resources
+	var gsi1pk string
+	if input.Aircraft != "" {
+		gsi1pk = c.aircraftPK(input.Aircraft)
+	}
+	return databaseItem{
+		PK:       c.clubPK(),
+		SK:       fmt.Sprintf("%s#%s", RESERVATION_PARTITION_KEY, input.Id),
+		GSI1PK:   gsi1pk,
+		GSI1SK:   fmt.Sprintf("%s#%s#%s", RESERVATION_PARTITION_KEY, input.StartTime.UTC().Format(TIME_KEY_FORMAT), input.Id),
+		ItemType: "reservation",
+		GSIData: gsiData{
+			Aircraft:        input.Aircraft,
+			Resources:       input.Resources,
+			ReservationType: input.ReservationType,
+			Booker:          input.Booker,
+			Pilot:           input.Pilot,
+			Instructor:      input.Instructor,
+			StartTime:       input.StartTime.UTC().Format(TIME_KEY_FORMAT),
+			EndTime:         input.EndTime.UTC().Format(TIME_KEY_FORMAT),
+			Remarks:         input.Remarks,
+			Status:          input.Status,
+			SeriesId:        input.SeriesId,
+			Version:         input.Version,
+		},
+		Reservation: input,
+	}
+}
+
 // Returns a new reservation API client from the provided config.
 func NewFromConfig(c Config) *Client {
 	return &Client{Config: c}
@@ -111,152 +189,654 @@ func (c *Client) CreateOrUpdate(input Reservation) (*Reservation, error) {
 		c.Logger().Info("updating reservation")
 	}
 
-	// Check invalid times
-	if input.StartTime == input.EndTime {
-		return nil, ReservationTimesEqualError
+	// Reservations are only created with a new id, so that an update cannot skip the checks of a creation
+	var previous *Reservation
+	if !newReservation {
+		var err error
+		previous, err = c.getItem(input.Id, false)
+		if err != nil {
+			return nil, err
+		}
+		if previous == nil {
+			return nil, ReservationNotFoundError
+		}
 	}
+	input.Booker = c.booker(previous)
+	c.SetLogger(c.Logger().With("booker", input.Booker))
 
-	if input.EndTime.Compare(input.StartTime) < 0 {
-		return nil, ReservationTimesSwappedError
+	op := OPERATION_UPDATE
+	if newReservation {
+		op = OPERATION_CREATE
+	}
+	// Updates are also checked against the stored reservation when writing it
+	err := c.authorize(op, input)
+	if err != nil {
+		return nil, err
 	}
 
 	if newReservation {
 		input.Id = ulid.Make().String()
+		input.Version = 0
 		c.SetLogger(c.Logger().With("reservation", input.Id))
+	}
+
+	err = c.validate(input, previous)
+	if err != nil {
+		return nil, err
+	}
 
+	var out *Reservation
+	for attempt := 1; ; attempt++ {
+		var err error
+		out, err = c.write(input, newReservation)
+		if err == nil {
+			break
+		}
+		if !database.IsConditionalCheckFailure(err) || attempt == MAX_WRITE_ATTEMPTS {
+			return nil, err
+		}
+		c.Logger().Warn("concurrent reservation write detected, retrying", "attempt", attempt)
+	}
+
+	if newReservation {
+		c.Logger().Info("reservation created", "version", out.Version)
+	} else {
+		c.Logger().Info("reservation updated", "version", out.Version)
+	}
+
+	return out, nil
+}
+
+// Runs all business validations on a reservation about to be written, except the maintenance blocks of its aircraft
+// and the prepaid balance of its pilot which are checked when writing it, see maintenanceCheckItem and balanceCheckItem.
+// Previous is the stored version of an updated reservation, nil for a new one.
+func (c *Client) validate(input Reservation, previous *Reservation) error {
+	// Check invalid times
+	if input.StartTime == input.EndTime {
+		return ReservationTimesEqualError
+	}
+
+	if input.EndTime.Compare(input.StartTime) < 0 {
+		return ReservationTimesSwappedError
+	}
+
+	if previous == nil {
 		if input.StartTime.Compare(time.Now()) < 0 {
-			return nil, ReservationCreateTimePastError
+			return ReservationCreateTimePastError
 		}
 	} else if time.Now().Sub(input.EndTime).Seconds() > 0 {
-		return nil, ReservationPastUpdateError
+		return ReservationPastUpdateError
 	}
 
-	databaseItem := databaseItem{
-		PK:          fmt.Sprintf("%s#%s", CLUB_PARTITION_KEY, CLUB_ID),
-		SK:          fmt.Sprintf("%s#%s", RESERVATION_PARTITION_KEY, input.Id),
-		ItemType:    "reservation",
-		Reservation: input,
+	if input.Instructor != nil && *input.Instructor == input.Pilot {
+		return ReservationSamePilotInstructorError
+	}
+
+	if input.Aircraft == "" && len(input.Resources) == 0 {
+		return ReservationNoResourceError
 	}
 
-	out, err := c.DatabaseClient.Put(database.PutInput{Item: databaseItem})
+	err := c.validateReservationType(input)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	input.CreatedAt = out.CreatedAt
-	input.UpdatedAt = out.UpdatedAt
+	err = c.validateResources(input)
+	if err != nil {
+		return err
+	}
 
-	if newReservation {
-		c.Logger().Info("reservation created")
-	} else {
-		c.Logger().Info("reservation updated")
+	err = c.validateCrew(input)
+	if err != nil {
+		return err
 	}
 
-	return &input, nil
+	if input.Aircraft == "" {
+		return nil
+	}
+	reservedAircraft, err := c.validateAircraft(input.Aircraft)
+	if err != nil {
+		return err
+	}
+	return c.validateCheckout(input, *reservedAircraft)
 }
 
-type ListInput struct {
-	NextToken *string
-	Limit     *int32
+// Returns the booker of the reservation: the caller for new reservations, the original booker otherwise.
+// Reservations stored before bookers were recorded are considered booked by their pilot.
+func (c *Client) booker(previous *Reservation) string {
+	if previous == nil {
+		return c.UserId
+	}
+	if previous.Booker == "" {
+		return previous.Pilot
+	}
+	return previous.Booker
 }
 
-type ListOutput struct {
-	NextToken *string       `json:"nextToken"`
-	Results   []Reservation `json:"results"`
+// Checks that the reservation type is in the catalog of the club and that the reservation follows its rules.
+func (c *Client) validateReservationType(input Reservation) error {
+	reservationTypeClient := reservationtype.NewFromConfig(reservationtype.Config{Scope: c.Scope})
+
+	reservationType, err := reservationTypeClient.Get(input.ReservationType)
+	if errors.Is(err, reservationtype.ReservationTypeNotFoundError) {
+		return ReservationInvalidReservationTypeError
+	}
+	if err != nil {
+		return err
+	}
+
+	if reservationType.RequiresInstructor && input.Instructor == nil {
+		return ReservationInstructorRequiredError
+	}
+	if !reservationType.DurationAllowed(input.EndTime.Sub(input.StartTime)) {
+		return ReservationInvalidDurationError
+	}
+	return nil
 }
 
-type ExclusiveStartKey struct {
-	PK string `json:"PK"`
-	SK string `json:"SK"`
+// Checks that the aircraft is registered in the club and can be reserved, and returns it.
+func (c *Client) validateAircraft(registration string) (*aircraft.Aircraft, error) {
+	aircraftClient := aircraft.NewFromConfig(aircraft.Config{Scope: c.Scope})
+
+	reservedAircraft, err := aircraftClient.Get(registration)
+	if errors.Is(err, aircraft.AircraftNotFoundError) {
+		return nil, ReservationInvalidAircraftError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if reservedAircraft.Status != aircraft.STATUS_ACTIVE {
+		return nil, ReservationInactiveAircraftError
+	}
+	return reservedAircraft, nil
 }
 
-// Returns stored data for all reservations.
-func (c *Client) List(input ListInput) (*ListOutput, error) {
-	c.Logger().Info("listing reservations")
+// Checks that the rooms and simulators exist, can be reserved by the caller and follow their own rules.
+func (c *Client) validateResources(input Reservation) error {
+	resourceClient := resource.NewFromConfig(resource.Config{Scope: c.Scope})
 
-	var keyConditionExpression *string
-	var expressionAttributeValues = make(map[string]types.AttributeValue)
+	for i, resourceId := range input.Resources {
+		for _, other := range input.Resources[:i] {
+			if other == resourceId {
+				return ReservationDuplicateResourceError
+			}
+		}
+
+		reservedResource, err := resourceClient.Get(resourceId)
+		if errors.Is(err, resource.ResourceNotFoundError) {
+			return ReservationInvalidResourceError
+		}
+		if err != nil {
+			return err
+		}
+
+		if reservedResource.Status != resource.STATUS_ACTIVE {
+			return ReservationInactiveResourceError
+		}
+		if !reservedResource.AllowsRole(c.UserRole) {
+			if reservedResource.ResourceType == resource.TYPE_SIMULATOR {
+				return ReservationSimulatorAccessDenyError
+			}
+			return ReservationRoomAccessDenyError
+		}
+		if reservedResource.RequiresInstructor && input.Instructor == nil {
+			return ReservationResourceInstructorRequiredError
+		}
+	}
+	return nil
+}
+
+// Checks that the pilot is checked out on the aircraft, or on its type, until the end of the reservation.
+// Like the SEP rating, a checkout is only required when flying without an instructor.
+func (c *Client) validateCheckout(input Reservation, reservedAircraft aircraft.Aircraft) error {
+	if input.Instructor != nil {
+		return nil
+	}
+
+	checkoutClient := checkout.NewFromConfig(checkout.Config{Scope: c.Scope})
+
+	checkedOut, err := checkoutClient.IsCheckedOut(input.Pilot, reservedAircraft, input.EndTime)
+	if err != nil {
+		return err
+	}
+	if !checkedOut {
+		return ReservationAircraftAccessDenyError
+	}
+	return nil
+}
 
-	pk := fmt.Sprintf("%s#%s", CLUB_PARTITION_KEY, CLUB_ID)
-	var sk string
+// Checks that the booker, pilot and instructor are club members and, when an aircraft is reserved,
+// that the pilot is allowed to fly until the end of the reservation.
+// Student pilots have no SEP rating yet, it is only required when flying without an instructor.
+func (c *Client) validateCrew(input Reservation) error {
+	memberClient := member.NewFromConfig(member.Config{Scope: c.Scope})
 
-	keyConditionExpression = aws.String("PK = :pk AND begins_with(SK, :sk)")
-	sk = RESERVATION_PARTITION_KEY
-	expressionAttributeValues[":sk"] = &types.AttributeValueMemberS{
-		Value: sk,
+	pilot, err := memberClient.Get(input.Pilot)
+	if errors.Is(err, member.MemberNotFoundError) {
+		return ReservationInvalidPilotError
 	}
-	expressionAttributeValues[":pk"] = &types.AttributeValueMemberS{
-		Value: pk,
+	if err != nil {
+		return err
 	}
 
-	var exclusiveStartKey map[string]types.AttributeValue
-	if input.NextToken != nil {
-		nextTokenData, err := base64.StdEncoding.DecodeString(*input.NextToken)
+	if input.Instructor != nil {
+		_, err := memberClient.Get(*input.Instructor)
+		if errors.Is(err, member.MemberNotFoundError) {
+			return ReservationInvalidInstructorError
+		}
 		if err != nil {
-			return nil, err
+			return err
 		}
+	} else if input.Aircraft != "" && !pilot.SepRatingValidUntil(input.EndTime) {
+		return ReservationExpiredSepRatingError
+	}
 
-		var nextToken map[string]string
-		err = json.Unmarshal([]byte(nextTokenData), &nextToken)
+	if input.Aircraft != "" && !pilot.MedicalValidUntil(input.EndTime) {
+		return ReservationExpiredMedicalRatingError
+	}
+
+	if input.Booker != input.Pilot && (input.Instructor == nil || input.Booker != *input.Instructor) {
+		_, err := memberClient.Get(input.Booker)
+		if errors.Is(err, member.MemberNotFoundError) {
+			return ReservationInvalidBookerError
+		}
 		if err != nil {
-			return nil, err
+			return err
 		}
+	}
+	return nil
+}
 
-		nextTokenMap, err := attributevalue.MarshalMap(nextToken)
+// Writes the reservation and its slot locks in a single transaction, and returns the written reservation.
+// Fails with a conditional check failure if a concurrent write modified the reservation or one of its slot locks in the meantime.
+func (c *Client) write(input Reservation, newReservation bool) (*Reservation, error) {
+	var previous *Reservation
+	if !newReservation {
+		var err error
+		previous, err = c.getItem(input.Id, true)
+		if err != nil {
+			return nil, err
+		}
+		if previous == nil {
+			return nil, ReservationNotFoundError
+		}
+		err = c.authorize(OPERATION_UPDATE, *previous)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		exclusiveStartKey = nextTokenMap
+	expectedVersion := 0
+	input.Status = STATUS_PENDING
+	input.CancellationReason = nil
+	input.SeriesId = nil
+	if previous != nil {
+		expectedVersion = previous.Version
+		// The status only changes through transitions
+		if !isOpen(previous.Status) {
+			return nil, ReservationClosedError
+		}
+		input.Status = previous.Status
+		input.SeriesId = previous.SeriesId
 	}
+	if input.Version != 0 && input.Version != expectedVersion {
+		c.Logger().Info("reservation was modified in the meantime", "version", input.Version, "storedVersion", expectedVersion)
+		return nil, ReservationVersionMismatchError
+	}
+	input.Version = expectedVersion + 1
 
-	queryInput := database.QueryInput{
-		Limit:                     input.Limit,
-		KeyConditionExpression:    keyConditionExpression,
-		ExpressionAttributeValues: expressionAttributeValues,
-		ExclusiveStartKey:         exclusiveStartKey,
+	var conditionExpression *string
+	if newReservation {
+		conditionExpression = aws.String("attribute_not_exists(PK)")
+	}
+	update, updatedAt, err := c.DatabaseClient.NewReplace(database.PutInput{
+		Item:                c.newDatabaseItem(input),
+		ConditionExpression: conditionExpression,
+		ExpectedVersion:     &expectedVersion,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	output, err := c.DatabaseClient.Query(&queryInput)
+	input.UpdatedAt = updatedAt
+	input.CreatedAt = updatedAt
+	op := OPERATION_CREATE
+	if previous != nil {
+		input.CreatedAt = previous.CreatedAt
+		op = OPERATION_UPDATE
+	}
+
+	return c.transactWrite(input, previous, op, types.TransactWriteItem{Update: update})
+}
+
+// Writes the reservation item along with the slot locks, calendar entries and history entry of the change in a single transaction.
+// Extra items, like the flight log of a check-in, are written in the same transaction.
+// The timestamps of the written reservation must already be set on the input.
+func (c *Client) transactWrite(input Reservation, previous *Reservation, op operation, reservationItem types.TransactWriteItem,
+	extraItems ...types.TransactWriteItem) (*Reservation, error) {
+	historyItem, err := c.historyEntryItem(op, previous, &input)
 	if err != nil {
 		return nil, err
 	}
+	transactItems := append([]types.TransactWriteItem{reservationItem, *historyItem}, extraItems...)
 
-	var reservations = make([]Reservation, 0)
-	for _, item := range output.Items {
-		var reservation Reservation
-		err := attributevalue.UnmarshalMap(item, &reservation)
+	// Created and updated reservations are checked against the maintenance blocks and the prepaid balance
+	// along with their slots, the transitions of a reservation are not affected by them
+	if (op == OPERATION_CREATE || op == OPERATION_UPDATE) && input.Aircraft != "" {
+		checkItem, err := c.maintenanceCheckItem(input)
+		if err != nil {
+			return nil, err
+		}
+		transactItems = append(transactItems, *checkItem)
+
+		checkItem, err = c.balanceCheckItem(input, previous)
 		if err != nil {
 			return nil, err
 		}
-		reservations = append(reservations, reservation)
+		if checkItem != nil {
+			transactItems = append(transactItems, *checkItem)
+		}
 	}
 
-	var nextToken *string
-	if len(output.LastEvaluatedKey) > 0 {
-		token := new(ExclusiveStartKey)
-		err = attributevalue.UnmarshalMap(output.LastEvaluatedKey, token)
+	// Cancelled reservations do not hold any slot.
+	// Every resource is locked in the same transaction, so the reservation holds all of them or none.
+	locks := make(map[string]*slotLockItem)
+	if input.Status != STATUS_CANCELLED {
+		locks, err = c.readSlotLocks(lockedResources(input), slotDays(input.StartTime, input.EndTime))
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		jsonOut, err := json.Marshal(token)
+	err = c.overlapError(locks, input)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, lock := range locks {
+		transactItem, err := c.lockSlotItem(lock, input)
 		if err != nil {
 			return nil, err
 		}
+		transactItems = append(transactItems, *transactItem)
+	}
 
-		nextToken = aws.String(string(base64.StdEncoding.EncodeToString(jsonOut)))
+	// Release the slots and resources the reservation no longer covers
+	if previous != nil {
+		previousLocks, err := c.readSlotLocks(lockedResources(*previous), slotDays(previous.StartTime, previous.EndTime))
+		if err != nil {
+			return nil, err
+		}
+		for sk, lock := range previousLocks {
+			if _, ok := locks[sk]; ok {
+				continue
+			}
+			if _, ok := lock.Reservations[input.Id]; ok {
+				transactItems = append(transactItems, *c.unlockSlotItem(lock, input.Id))
+			}
+		}
 	}
 
+	calendarItems, err := c.putCalendarEntryItems(input, previous)
 	if err != nil {
 		return nil, err
+	}
+	transactItems = append(transactItems, calendarItems...)
+
+	// Every slot lock is one item, so long reservations of many resources can exceed the size of a transaction
+	if len(transactItems) > MAX_TRANSACT_ITEMS {
+		c.Logger().Info("reservation write exceeds the transaction size", "items", len(transactItems))
+		return nil, ReservationTooManyItemsError
+	}
+
+	_, err = c.DatabaseClient.TransactWriteItems(dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &input, nil
+}
+
+type ListInput struct {
+	NextToken *string
+	Limit     *int32
+	// Only return reservations of this aircraft: e.g. HB-KFQ
+	Aircraft *string
+	// Only return reservations of this room or simulator
+	Resource *string
+	// Only return reservations made by this booker
+	Booker *string
+	// Only return reservations flown by this pilot
+	Pilot *string
+	// Only return reservations with this instructor
+	Instructor *string
+	// Only return reservations with this instructor, or flown by them as pilot
+	InstructorPlus *string
+	// Only return reservations of this type
+	ReservationType *string
+	// Only return reservations with this status, cancelled reservations are excluded by default
+	Status *string
+	// Only return reservations starting between Start and End, both must be provided
+	Start *time.Time
+	End   *time.Time
+}
+
+type ListOutput struct {
+	NextToken *string       `json:"nextToken"`
+	Results   []Reservation `json:"results"`
+}
+
+// Returns stored data for all reservations matching the input filters.
+func (c *Client) List(input ListInput) (*ListOutput, error) {
+	c.Logger().Info("listing reservations")
+
+	input, err := c.authorizeList(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if (input.Start == nil) != (input.End == nil) {
+		return nil, ReservationTimeRangeError
+	}
+	if input.Start != nil && input.End.Before(*input.Start) {
+		return nil, ReservationTimesSwappedError
+	}
+
+	var index *string
+	var keyConditionExpression *string
+	var expressionAttributeValues = make(map[string]types.AttributeValue)
+	var expressionAttributeNames map[string]string
+
+	// Reservations of an aircraft, a resource or a member are sorted by start time in GSI1, so time windows are a key range
+	var indexPK *string
+	if input.Aircraft != nil {
+		c.SetLogger(c.Logger().With("aircraft", *input.Aircraft))
+		indexPK = aws.String(c.aircraftPK(*input.Aircraft))
+	} else if input.Resource != nil {
+		c.SetLogger(c.Logger().With("resource", *input.Resource))
+		indexPK = aws.String(c.resourcePK(*input.Resource))
+	} else if memberId := firstNonNil(input.Pilot, input.Booker, input.Instructor, input.InstructorPlus); memberId != nil {
+		c.SetLogger(c.Logger().With("member", *memberId))
+		indexPK = aws.String(c.memberPK(*memberId))
+	}
+
+	timeWindowInKey := false
+	if indexPK != nil {
+		index = aws.String("GSI1")
+		expressionAttributeValues[":pk"] = &types.AttributeValueMemberS{
+			Value: *indexPK,
+		}
+		if input.Start != nil {
+			timeWindowInKey = true
+			keyConditionExpression = aws.String("GSI1PK = :pk AND GSI1SK BETWEEN :skStart AND :skEnd")
+			expressionAttributeValues[":skStart"] = &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("%s#%s", RESERVATION_PARTITION_KEY, input.Start.UTC().Format(TIME_KEY_FORMAT)),
+			}
+			// "~" sorts after the "#<id>" suffix, which makes the end of the window inclusive
+			expressionAttributeValues[":skEnd"] = &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("%s#%s~", RESERVATION_PARTITION_KEY, input.End.UTC().Format(TIME_KEY_FORMAT)),
+			}
+		} else {
+			keyConditionExpression = aws.String("GSI1PK = :pk AND begins_with(GSI1SK, :sk)")
+		}
+	} else {
+		keyConditionExpression = aws.String("PK = :pk AND begins_with(SK, :sk)")
+		expressionAttributeValues[":pk"] = &types.AttributeValueMemberS{
+			Value: c.clubPK(),
+		}
+	}
+	if !timeWindowInKey {
+		expressionAttributeValues[":sk"] = &types.AttributeValueMemberS{
+			Value: RESERVATION_PARTITION_KEY + "#",
+		}
+	}
+
+	// GSIData is stored on both the table and the index, so the same filters apply to both
+	filters := make([]string, 0)
+	if input.Aircraft != nil && input.Resource != nil {
+		filters = append(filters, "contains(GSIData.Resources, :resource)")
+		expressionAttributeValues[":resource"] = &types.AttributeValueMemberS{Value: *input.Resource}
+	}
+	if input.Booker != nil {
+		filters = append(filters, "GSIData.Booker = :booker")
+		expressionAttributeValues[":booker"] = &types.AttributeValueMemberS{Value: *input.Booker}
+	}
+	if input.Pilot != nil {
+		filters = append(filters, "GSIData.Pilot = :pilot")
+		expressionAttributeValues[":pilot"] = &types.AttributeValueMemberS{Value: *input.Pilot}
+	}
+	if input.Instructor != nil {
+		filters = append(filters, "GSIData.Instructor = :instructor")
+		expressionAttributeValues[":instructor"] = &types.AttributeValueMemberS{Value: *input.Instructor}
+	}
+	if input.InstructorPlus != nil {
+		filters = append(filters, "(GSIData.Instructor = :instructorPlus OR GSIData.Pilot = :instructorPlus)")
+		expressionAttributeValues[":instructorPlus"] = &types.AttributeValueMemberS{Value: *input.InstructorPlus}
+	}
+	if input.ReservationType != nil {
+		filters = append(filters, "GSIData.ReservationType = :reservationType")
+		expressionAttributeValues[":reservationType"] = &types.AttributeValueMemberS{Value: *input.ReservationType}
+	}
+	// Status is a reserved word
+	expressionAttributeNames = map[string]string{"#status": "Status"}
+	if input.Status != nil {
+		filters = append(filters, "GSIData.#status = :status")
+		expressionAttributeValues[":status"] = &types.AttributeValueMemberS{Value: *input.Status}
 	} else {
-		c.Logger().Info("reservations listed", "count", len(reservations), "isNextToken", nextToken != nil)
-		return &ListOutput{
-			NextToken: nextToken,
-			Results:   reservations,
-		}, nil
+		filters = append(filters, "GSIData.#status <> :cancelled")
+		expressionAttributeValues[":cancelled"] = &types.AttributeValueMemberS{Value: STATUS_CANCELLED}
+	}
+	if input.Start != nil && !timeWindowInKey {
+		filters = append(filters, "GSIData.StartTime BETWEEN :start AND :end")
+		expressionAttributeValues[":start"] = &types.AttributeValueMemberS{Value: input.Start.UTC().Format(TIME_KEY_FORMAT)}
+		expressionAttributeValues[":end"] = &types.AttributeValueMemberS{Value: input.End.UTC().Format(TIME_KEY_FORMAT)}
+	}
+	var filterExpression *string
+	if len(filters) > 0 {
+		filterExpression = aws.String(strings.Join(filters, " AND "))
+	}
+
+	exclusiveStartKey, err := database.DecodeNextToken(input.NextToken, c.clubPK())
+	if err != nil {
+		return nil, err
+	}
+
+	queryInput := database.QueryInput{
+		Index:                     index,
+		KeyConditionExpression:    keyConditionExpression,
+		ExpressionAttributeValues: expressionAttributeValues,
+		ExpressionAttributeNames:  expressionAttributeNames,
+		ExclusiveStartKey:         exclusiveStartKey,
+		FilterExpression:          filterExpression,
+	}
+
+	// The limit applies before filtering, so keep querying until the page is full or the partition is exhausted
+	var reservations = make([]Reservation, 0)
+	var lastEvaluatedKey map[string]types.AttributeValue
+	for {
+		if input.Limit != nil {
+			queryInput.Limit = aws.Int32(*input.Limit - int32(len(reservations)))
+		}
+
+		output, err := c.DatabaseClient.Query(&queryInput)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range output.Items {
+			reservation, err := unmarshalListItem(item, index != nil)
+			if err != nil {
+				return nil, err
+			}
+			reservations = append(reservations, *reservation)
+		}
+
+		lastEvaluatedKey = output.LastEvaluatedKey
+		if input.Limit == nil || len(lastEvaluatedKey) == 0 || int32(len(reservations)) >= *input.Limit {
+			break
+		}
+		queryInput.ExclusiveStartKey = lastEvaluatedKey
+	}
+
+	nextToken, err := database.EncodeNextToken(lastEvaluatedKey)
+	if err != nil {
+		return nil, err
 	}
+
+	c.Logger().Info("reservations listed", "count", len(reservations), "isNextToken", nextToken != nil)
+	return &ListOutput{
+		NextToken: nextToken,
+		Results:   reservations,
+	}, nil
+}
+
+// Items read from GSI1 only contain the projected attributes, the reservation is rebuilt from GSIData.
+func unmarshalListItem(item map[string]types.AttributeValue, fromIndex bool) (*Reservation, error) {
+	reservation := new(Reservation)
+	if !fromIndex {
+		err := attributevalue.UnmarshalMap(item, reservation)
+		normalizeStatus(reservation)
+		return reservation, err
+	}
+
+	var indexItem struct {
+		Id        string
+		CreatedAt time.Time
+		UpdatedAt time.Time
+		GSIData   gsiData
+	}
+	err := attributevalue.UnmarshalMap(item, &indexItem)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime, err := time.Parse(TIME_KEY_FORMAT, indexItem.GSIData.StartTime)
+	if err != nil {
+		return nil, err
+	}
+	endTime, err := time.Parse(TIME_KEY_FORMAT, indexItem.GSIData.EndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation.Id = indexItem.Id
+	reservation.Aircraft = indexItem.GSIData.Aircraft
+	reservation.Resources = indexItem.GSIData.Resources
+	reservation.ReservationType = indexItem.GSIData.ReservationType
+	reservation.Booker = indexItem.GSIData.Booker
+	reservation.Pilot = indexItem.GSIData.Pilot
+	reservation.Instructor = indexItem.GSIData.Instructor
+	reservation.StartTime = startTime
+	reservation.EndTime = endTime
+	reservation.Remarks = indexItem.GSIData.Remarks
+	reservation.Status = indexItem.GSIData.Status
+	reservation.SeriesId = indexItem.GSIData.SeriesId
+	reservation.Version = indexItem.GSIData.Version
+	reservation.CreatedAt = indexItem.CreatedAt
+	reservation.UpdatedAt = indexItem.UpdatedAt
+	return reservation, nil
 }
 
 // Returns stored data for a reservation.
@@ -265,12 +845,15 @@ func (c *Client) Get(reservationId string) (*Reservation, error) {
 	c.Logger().Info("retrieving reservation")
 
 	output, err := c.DatabaseClient.Get(database.GetInput{
-		PK: fmt.Sprintf("%s#%s", CLUB_PARTITION_KEY, CLUB_ID),
+		PK: c.clubPK(),
 		SK: fmt.Sprintf("%s#%s", RESERVATION_PARTITION_KEY, reservationId),
 	})
 	if err != nil {
 		return nil, err
 	}
+	if output.Item == nil {
+		return nil, ReservationNotFoundError
+	}
 
 	reservation := new(Reservation)
 	err = attributevalue.UnmarshalMap(output.Item, reservation)
@@ -278,22 +861,150 @@ func (c *Client) Get(reservationId string) (*Reservation, error) {
 		return nil, err
 	}
 
+	normalizeStatus(reservation)
+	err = c.authorize(OPERATION_READ, *reservation)
+	if err != nil {
+		return nil, err
+	}
+
 	c.Logger().Info("reservation retrieved")
 	return reservation, nil
 }
 
-func (c *Client) Delete(reservationId string) error {
+// Returns the stored reservation, or nil if it does not exist.
+func (c *Client) getItem(reservationId string, consistentRead bool) (*Reservation, error) {
+	output, err := c.DatabaseClient.Get(database.GetInput{
+		PK:             c.clubPK(),
+		SK:             fmt.Sprintf("%s#%s", RESERVATION_PARTITION_KEY, reservationId),
+		ConsistentRead: consistentRead,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.Item == nil {
+		return nil, nil
+	}
+
+	reservation := new(Reservation)
+	err = attributevalue.UnmarshalMap(output.Item, reservation)
+	if err != nil {
+		return nil, err
+	}
+	normalizeStatus(reservation)
+	return reservation, nil
+}
+
+// Deletes a reservation. When expectedVersion is not 0, the reservation is only deleted if it still has this version.
+// Only pending reservations can be deleted, so that the reservations which were confirmed are kept for statistics.
+func (c *Client) Delete(reservationId string, expectedVersion int) error {
 	c.SetLogger(c.Logger().With("reservation", reservationId))
 	c.Logger().Info("deleting reservation")
 
-	_, err := c.DatabaseClient.Delete(&database.DeleteInput{
-		PK: fmt.Sprintf("%s#%s", CLUB_PARTITION_KEY, CLUB_ID),
-		SK: fmt.Sprintf("%s#%s", RESERVATION_PARTITION_KEY, reservationId),
-	})
+	for attempt := 1; ; attempt++ {
+		err := c.delete(reservationId, expectedVersion)
+		if err == nil {
+			break
+		}
+		if !database.IsConditionalCheckFailure(err) || attempt == MAX_WRITE_ATTEMPTS {
+			return err
+		}
+		c.Logger().Warn("concurrent reservation write detected, retrying", "attempt", attempt)
+	}
+
+	c.Logger().Info("reservation deleted")
+	return nil
+}
+
+// Deletes the reservation and releases its slot locks in a single transaction.
+func (c *Client) delete(reservationId string, expectedVersion int) error {
+	reservation, err := c.getItem(reservationId, true)
+	if err != nil {
+		return err
+	}
+	if reservation == nil {
+		if expectedVersion != 0 {
+			return ReservationVersionMismatchError
+		}
+		return nil
+	}
+	err = c.authorize(OPERATION_DELETE, *reservation)
+	if err != nil {
+		return err
+	}
+	if reservation.Status != STATUS_PENDING {
+		return ReservationDeleteNotPendingError
+	}
+	if expectedVersion != 0 && expectedVersion != reservation.Version {
+		c.Logger().Info("reservation was modified in the meantime", "version", expectedVersion, "storedVersion", reservation.Version)
+		return ReservationVersionMismatchError
+	}
+
+	// The stored version guards against concurrent updates between the read and the delete
+	versionCondition := fmt.Sprintf("attribute_not_exists(%s)", database.VERSION_ATTRIBUTE)
+	var versionValues map[string]types.AttributeValue
+	if reservation.Version != 0 {
+		versionCondition = fmt.Sprintf("%s = :version", database.VERSION_ATTRIBUTE)
+		versionValues = map[string]types.AttributeValue{
+			":version": &types.AttributeValueMemberN{Value: fmt.Sprint(reservation.Version)},
+		}
+	}
+
+	transactItems := []types.TransactWriteItem{
+		{
+			Delete: &types.Delete{
+				TableName: aws.String(c.DatabaseClient.TableName),
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: c.clubPK()},
+					"SK": &types.AttributeValueMemberS{Value: fmt.Sprintf("%s#%s", RESERVATION_PARTITION_KEY, reservationId)},
+				},
+				ConditionExpression:       aws.String(versionCondition),
+				ExpressionAttributeValues: versionValues,
+			},
+		},
+	}
+
+	locks, err := c.readSlotLocks(lockedResources(*reservation), slotDays(reservation.StartTime, reservation.EndTime))
+	if err != nil {
+		return err
+	}
+	for _, lock := range locks {
+		if _, ok := lock.Reservations[reservationId]; ok {
+			transactItems = append(transactItems, *c.unlockSlotItem(lock, reservationId))
+		}
+	}
+
+	for _, cal := range c.reservationCalendars(*reservation) {
+		transactItems = append(transactItems, c.deleteCalendarEntryItem(cal.Key, reservationId))
+	}
 
+	historyItem, err := c.historyEntryItem(OPERATION_DELETE, reservation, nil)
 	if err != nil {
-		c.Logger().Info("reservation deleted")
+		return err
 	}
+	transactItems = append(transactItems, *historyItem)
 
+	_, err = c.DatabaseClient.TransactWriteItems(dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
 	return err
 }
+
+// Returns the partition key of the tenant club owning the reservations.
+func (c *Client) clubPK() string {
+	return fmt.Sprintf("%s#%s", constants.CLUB_PARTITION_KEY, c.TenantId)
+}
+
+// Returns the GSI1 partition key grouping the reservations of an aircraft.
+func (c *Client) aircraftPK(aircraftRegistration string) string {
+	return fmt.Sprintf("%s#%s#%s", c.clubPK(), aircraft.AIRCRAFT_PARTITION_KEY, aircraftRegistration)
+}
+
+// Returns the first of the values that is set, or nil if none is.
+func firstNonNil(values ...*string) *string {
+	for _, value := range values {
+		if value != nil {
+			return value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package reservation
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maximum number of occurrences of a series
+const MAX_SERIES_OCCURRENCES = 100
+
+// Number of periods expanded without finding an occurrence before giving up on a rule that never matches
+const MAX_EMPTY_PERIODS = 1000
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Recurrence rule of a series, the subset of RFC 5545 RRULE supported by the club:
+// FREQ (DAILY, WEEKLY or MONTHLY), INTERVAL, BYDAY (DAILY and WEEKLY only), and COUNT or UNTIL.
+// Weeks start on Monday.
+type recurrenceRule struct {
+	Frequency string
+	Interval  int
+	ByDay     []time.Weekday
+	Count     int
+	Until     *time.Time
+}
+
+// Parses an RRULE value: e.g. FREQ=WEEKLY;BYDAY=TU,TH;COUNT=10.
+// Floating and date-only UNTIL values are read in the given location, dates include the whole day.
+func parseRecurrenceRule(rule string, location *time.Location) (*recurrenceRule, error) {
+	r := &recurrenceRule{Interval: 1}
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:"), ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, ReservationInvalidRecurrenceRuleError
+		}
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			r.Frequency = strings.ToUpper(value)
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return nil, ReservationInvalidRecurrenceRuleError
+			}
+			r.Interval = interval
+		case "BYDAY":
+			for _, day := range strings.Split(strings.ToUpper(value), ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return nil, ReservationInvalidRecurrenceRuleError
+				}
+				r.ByDay = append(r.ByDay, weekday)
+			}
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return nil, ReservationInvalidRecurrenceRuleError
+			}
+			r.Count = count
+		case "UNTIL":
+			until, err := parseUntil(value, location)
+			if err != nil {
+				return nil, ReservationInvalidRecurrenceRuleError
+			}
+			r.Until = &until
+		case "WKST":
+			if strings.ToUpper(value) != "MO" {
+				return nil, ReservationInvalidRecurrenceRuleError
+			}
+		default:
+			return nil, ReservationInvalidRecurrenceRuleError
+		}
+	}
+
+	switch r.Frequency {
+	case "DAILY", "WEEKLY":
+	case "MONTHLY":
+		if len(r.ByDay) > 0 {
+			return nil, ReservationInvalidRecurrenceRuleError
+		}
+	default:
+		return nil, ReservationInvalidRecurrenceRuleError
+	}
+	// A series must end, and COUNT and UNTIL are mutually exclusive
+	if (r.Count == 0) == (r.Until == nil) {
+		return nil, ReservationInvalidRecurrenceRuleError
+	}
+
+	// Weekly occurrences are generated in week order
+	sort.Slice(r.ByDay, func(i, j int) bool {
+		return weekdayIndex(r.ByDay[i]) < weekdayIndex(r.ByDay[j])
+	})
+	return r, nil
+}
+
+func parseUntil(value string, location *time.Location) (time.Time, error) {
+	if until, err := time.Parse("20060102T150405Z", value); err == nil {
+		return until, nil
+	}
+	if until, err := time.ParseInLocation("20060102T150405", value, location); err == nil {
+		return until, nil
+	}
+	until, err := time.ParseInLocation("20060102", value, location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return until.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+// Days since Monday
+func weekdayIndex(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
+
+// Returns the start times of the occurrences, keeping the wall clock time of start in the given location.
+// Occurrences before start and at one of the excluded times are skipped, excluded times still count for COUNT.
+func (r recurrenceRule) expand(start time.Time, location *time.Location, excluded []time.Time) ([]time.Time, error) {
+	start = start.In(location)
+	occurrences := make([]time.Time, 0)
+	count := 0
+	emptyPeriods := 0
+	for period := 0; emptyPeriods < MAX_EMPTY_PERIODS; period++ {
+		candidates := r.periodCandidates(start, period, location)
+		if len(candidates) == 0 {
+			emptyPeriods++
+		}
+		for _, candidate := range candidates {
+			if candidate.Before(start) {
+				continue
+			}
+			if r.Until != nil && candidate.After(*r.Until) {
+				return occurrences, nil
+			}
+			emptyPeriods = 0
+			count++
+			if !containsTime(excluded, candidate) {
+				occurrences = append(occurrences, candidate)
+			}
+			if len(occurrences) > MAX_SERIES_OCCURRENCES {
+				return nil, ReservationTooManyOccurrencesError
+			}
+			if r.Count > 0 && count == r.Count {
+				return occurrences, nil
+			}
+		}
+	}
+	return occurrences, nil
+}
+
+// Returns the candidate start times of the nth period of the rule, in chronological order.
+func (r recurrenceRule) periodCandidates(start time.Time, period int, location *time.Location) []time.Time {
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), location)
+	}
+
+	switch r.Frequency {
+	case "DAILY":
+		day := at(start.Year(), start.Month(), start.Day()+period*r.Interval)
+		if len(r.ByDay) > 0 && !containsWeekday(r.ByDay, day.Weekday()) {
+			return nil
+		}
+		return []time.Time{day}
+	case "WEEKLY":
+		monday := start.Day() - weekdayIndex(start.Weekday()) + period*r.Interval*7
+		if len(r.ByDay) == 0 {
+			return []time.Time{at(start.Year(), start.Month(), monday+weekdayIndex(start.Weekday()))}
+		}
+		candidates := make([]time.Time, 0, len(r.ByDay))
+		for _, weekday := range r.ByDay {
+			candidates = append(candidates, at(start.Year(), start.Month(), monday+weekdayIndex(weekday)))
+		}
+		return candidates
+	case "MONTHLY":
+		month := at(start.Year(), start.Month()+time.Month(period*r.Interval), 1)
+		day := at(month.Year(), month.Month(), start.Day())
+		// Months without this day are skipped, as required by RFC 5545
+		if day.Month() != month.Month() {
+			return nil
+		}
+		return []time.Time{day}
+	}
+	return nil
+}
+
+func containsWeekday(weekdays []time.Weekday, weekday time.Weekday) bool {
+	for _, w := range weekdays {
+		if w == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTime(times []time.Time, t time.Time) bool {
+	for _, candidate := range times {
+		if candidate.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
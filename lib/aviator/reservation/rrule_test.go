@@ -0,0 +1,299 @@
+package reservation
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location
+}
+
+func TestParseRecurrenceRule(t *testing.T) {
+	zurich := mustLoadLocation(t, "Europe/Zurich")
+	tests := []struct {
+		rule      string
+		want      recurrenceRule
+		wantError bool
+	}{
+		{rule: "FREQ=DAILY;COUNT=3", want: recurrenceRule{Frequency: "DAILY", Interval: 1, Count: 3}},
+		{rule: "RRULE:freq=weekly;byday=th,tu;interval=2;count=4", want: recurrenceRule{
+			Frequency: "WEEKLY", Interval: 2, Count: 4, ByDay: []time.Weekday{time.Tuesday, time.Thursday},
+		}},
+		{rule: "FREQ=WEEKLY;BYDAY=SU,MO;COUNT=2;WKST=MO", want: recurrenceRule{
+			Frequency: "WEEKLY", Interval: 1, Count: 2, ByDay: []time.Weekday{time.Monday, time.Sunday},
+		}},
+		{rule: "FREQ=MONTHLY;COUNT=6", want: recurrenceRule{Frequency: "MONTHLY", Interval: 1, Count: 6}},
+		{rule: "FREQ", wantError: true},
+		{rule: "FREQ=YEARLY;COUNT=2", wantError: true},
+		{rule: "FREQ=DAILY", wantError: true},
+		{rule: "FREQ=DAILY;COUNT=2;UNTIL=20240310", wantError: true},
+		{rule: "FREQ=DAILY;COUNT=0", wantError: true},
+		{rule: "FREQ=DAILY;INTERVAL=0;COUNT=2", wantError: true},
+		{rule: "FREQ=WEEKLY;BYDAY=XX;COUNT=2", wantError: true},
+		{rule: "FREQ=WEEKLY;BYDAY=1MO;COUNT=2", wantError: true},
+		{rule: "FREQ=MONTHLY;BYDAY=MO;COUNT=2", wantError: true},
+		{rule: "FREQ=DAILY;COUNT=2;WKST=SU", wantError: true},
+		{rule: "FREQ=DAILY;COUNT=2;BYMONTH=3", wantError: true},
+		{rule: "FREQ=DAILY;UNTIL=2024-03-10", wantError: true},
+	}
+
+	for _, test := range tests {
+		got, err := parseRecurrenceRule(test.rule, zurich)
+		if test.wantError {
+			if !errors.Is(err, ReservationInvalidRecurrenceRuleError) {
+				t.Errorf("%s: error = %v, want ReservationInvalidRecurrenceRuleError", test.rule, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: error = %v", test.rule, err)
+			continue
+		}
+		if got.Frequency != test.want.Frequency || got.Interval != test.want.Interval || got.Count != test.want.Count ||
+			got.Until != nil || !equalWeekdays(got.ByDay, test.want.ByDay) {
+			t.Errorf("%s: rule = %+v, want %+v", test.rule, *got, test.want)
+		}
+	}
+}
+
+func TestParseUntil(t *testing.T) {
+	zurich := mustLoadLocation(t, "Europe/Zurich")
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		// UTC times are kept as is
+		{"20240307T090000Z", time.Date(2024, 3, 7, 9, 0, 0, 0, time.UTC)},
+		// Floating times are read in the club timezone
+		{"20240307T100000", time.Date(2024, 3, 7, 9, 0, 0, 0, time.UTC)},
+		// Dates include the whole day in the club timezone
+		{"20240307", time.Date(2024, 3, 7, 23, 0, 0, 0, time.UTC).Add(-time.Nanosecond)},
+		{"20240707", time.Date(2024, 7, 7, 22, 0, 0, 0, time.UTC).Add(-time.Nanosecond)},
+	}
+	for _, test := range tests {
+		got, err := parseUntil(test.value, zurich)
+		if err != nil {
+			t.Errorf("%s: error = %v", test.value, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("%s: until = %s, want %s", test.value, got.UTC(), test.want)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	zurich := mustLoadLocation(t, "Europe/Zurich")
+	at := func(year int, month time.Month, day int, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, zurich)
+	}
+	// Tuesday
+	start := at(2024, 3, 5, 10)
+
+	tests := []struct {
+		name     string
+		rule     string
+		start    time.Time
+		excluded []time.Time
+		want     []time.Time
+	}{
+		{
+			name:  "daily count",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: start,
+			want:  []time.Time{at(2024, 3, 5, 10), at(2024, 3, 6, 10), at(2024, 3, 7, 10)},
+		},
+		{
+			name:  "daily on weekdays",
+			rule:  "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR;COUNT=5",
+			start: at(2024, 3, 7, 10),
+			want:  []time.Time{at(2024, 3, 7, 10), at(2024, 3, 8, 10), at(2024, 3, 11, 10), at(2024, 3, 12, 10), at(2024, 3, 13, 10)},
+		},
+		{
+			name:  "every other week on Tuesday and Thursday",
+			rule:  "FREQ=WEEKLY;BYDAY=TU,TH;INTERVAL=2;COUNT=5",
+			start: start,
+			want:  []time.Time{at(2024, 3, 5, 10), at(2024, 3, 7, 10), at(2024, 3, 19, 10), at(2024, 3, 21, 10), at(2024, 4, 2, 10)},
+		},
+		{
+			name:  "weekly days before the start are skipped in the first week",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=3",
+			start: start,
+			want:  []time.Time{at(2024, 3, 6, 10), at(2024, 3, 11, 10), at(2024, 3, 13, 10)},
+		},
+		{
+			name:  "weekly without days repeats the day of the start",
+			rule:  "FREQ=WEEKLY;COUNT=2",
+			start: start,
+			want:  []time.Time{at(2024, 3, 5, 10), at(2024, 3, 12, 10)},
+		},
+		{
+			name:  "monthly skips months without the day",
+			rule:  "FREQ=MONTHLY;COUNT=4",
+			start: at(2024, 1, 31, 10),
+			want:  []time.Time{at(2024, 1, 31, 10), at(2024, 3, 31, 10), at(2024, 5, 31, 10), at(2024, 7, 31, 10)},
+		},
+		{
+			name:  "monthly on the 29th of February of leap years",
+			rule:  "FREQ=MONTHLY;INTERVAL=12;COUNT=2",
+			start: at(2024, 2, 29, 10),
+			want:  []time.Time{at(2024, 2, 29, 10), at(2028, 2, 29, 10)},
+		},
+		{
+			name:  "until an occurrence includes it",
+			rule:  "FREQ=DAILY;UNTIL=20240307T090000Z",
+			start: start,
+			want:  []time.Time{at(2024, 3, 5, 10), at(2024, 3, 6, 10), at(2024, 3, 7, 10)},
+		},
+		{
+			name:  "until just before an occurrence excludes it",
+			rule:  "FREQ=DAILY;UNTIL=20240307T085959Z",
+			start: start,
+			want:  []time.Time{at(2024, 3, 5, 10), at(2024, 3, 6, 10)},
+		},
+		{
+			name:  "floating until in the club timezone",
+			rule:  "FREQ=DAILY;UNTIL=20240306T100000",
+			start: start,
+			want:  []time.Time{at(2024, 3, 5, 10), at(2024, 3, 6, 10)},
+		},
+		{
+			name:  "date-only until includes the whole day",
+			rule:  "FREQ=DAILY;UNTIL=20240307",
+			start: at(2024, 3, 5, 23),
+			want:  []time.Time{at(2024, 3, 5, 23), at(2024, 3, 6, 23), at(2024, 3, 7, 23)},
+		},
+		{
+			name:     "excluded dates are removed and still count",
+			rule:     "FREQ=DAILY;COUNT=3",
+			start:    start,
+			excluded: []time.Time{at(2024, 3, 6, 10)},
+			want:     []time.Time{at(2024, 3, 5, 10), at(2024, 3, 7, 10)},
+		},
+		{
+			name:     "excluded dates match the instant whatever the timezone",
+			rule:     "FREQ=DAILY;UNTIL=20240307",
+			start:    start,
+			excluded: []time.Time{time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC), at(2024, 3, 6, 11)},
+			want:     []time.Time{at(2024, 3, 6, 10), at(2024, 3, 7, 10)},
+		},
+		{
+			name:  "wall clock time is kept across the start of summer time",
+			rule:  "FREQ=WEEKLY;COUNT=3",
+			start: at(2024, 3, 23, 10),
+			want: []time.Time{
+				time.Date(2024, 3, 23, 9, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 30, 9, 0, 0, 0, time.UTC),
+				time.Date(2024, 4, 6, 8, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "wall clock time is kept across the end of summer time",
+			rule:  "FREQ=DAILY;COUNT=2",
+			start: at(2024, 10, 26, 10),
+			want: []time.Time{
+				time.Date(2024, 10, 26, 8, 0, 0, 0, time.UTC),
+				time.Date(2024, 10, 27, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "a rule which never matches ends after the empty periods",
+			rule:  "FREQ=DAILY;BYDAY=MO;INTERVAL=7;COUNT=1",
+			start: start,
+			want:  []time.Time{},
+		},
+	}
+
+	for _, test := range tests {
+		rule, err := parseRecurrenceRule(test.rule, zurich)
+		if err != nil {
+			t.Errorf("%s: parse error = %v", test.name, err)
+			continue
+		}
+		got, err := rule.expand(test.start, zurich, test.excluded)
+		if err != nil {
+			t.Errorf("%s: error = %v", test.name, err)
+			continue
+		}
+		if !equalTimes(got, test.want) {
+			t.Errorf("%s: occurrences = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestExpandLimits(t *testing.T) {
+	zurich := mustLoadLocation(t, "Europe/Zurich")
+	start := time.Date(2024, 3, 5, 10, 0, 0, 0, zurich)
+	tests := []struct {
+		name      string
+		rule      string
+		excluded  []time.Time
+		wantCount int
+		wantError bool
+	}{
+		{name: "maximum count", rule: "FREQ=DAILY;COUNT=100", wantCount: MAX_SERIES_OCCURRENCES},
+		{name: "count over the maximum", rule: "FREQ=DAILY;COUNT=101", wantError: true},
+		{name: "until over the maximum", rule: "FREQ=DAILY;UNTIL=20250305", wantError: true},
+		{
+			name:      "excluded dates do not count towards the maximum",
+			rule:      "FREQ=DAILY;COUNT=101",
+			excluded:  []time.Time{start},
+			wantCount: MAX_SERIES_OCCURRENCES,
+		},
+		{name: "empty periods stop an unbounded rule", rule: "FREQ=DAILY;BYDAY=MO;INTERVAL=7;UNTIL=29991231", wantCount: 0},
+	}
+
+	for _, test := range tests {
+		rule, err := parseRecurrenceRule(test.rule, zurich)
+		if err != nil {
+			t.Errorf("%s: parse error = %v", test.name, err)
+			continue
+		}
+		got, err := rule.expand(start, zurich, test.excluded)
+		if test.wantError {
+			if !errors.Is(err, ReservationTooManyOccurrencesError) {
+				t.Errorf("%s: error = %v, want ReservationTooManyOccurrencesError", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: error = %v", test.name, err)
+			continue
+		}
+		if len(got) != test.wantCount {
+			t.Errorf("%s: %d occurrences, want %d", test.name, len(got), test.wantCount)
+		}
+	}
+}
+
+func equalTimes(a []time.Time, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalWeekdays(a []time.Weekday, b []time.Weekday) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,716 @@
+package reservation
+
+import (
+	"aviator/club"
+	"aviator/constants"
+	"aviator/database"
+	aviatorErrors "aviator/errors"
+	"aviator/ledger"
+	"aviator/maintenance"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/oklog/ulid/v2"
+)
+
+const SERIES_PARTITION_KEY = "SERIES"
+
+// Scopes of an edit of an occurrence of a series
+const SCOPE_THIS = "this"
+const SCOPE_FOLLOWING = "following"
+const SCOPE_ALL = "all"
+
+// Items written by every series transaction besides its occurrences:
+// the series, the maintenance check of the aircraft and the balance check of the pilot
+const SERIES_TRANSACT_ITEMS = 3
+
+// Recurring reservation to create, expanded into one reservation per occurrence
+type SeriesInput struct {
+	// First occurrence of the series, its start and end times give the time of day and duration of all occurrences
+	Reservation
+	// Recurrence rule in RFC 5545 format: e.g. FREQ=WEEKLY;BYDAY=TU,TH;COUNT=10
+	RRule string `json:"rrule"`
+	// Start times of the occurrences to skip
+	ExDates []time.Time `json:"exDates"`
+}
+
+// Item used to store a series of recurring reservations
+type Series struct {
+	// Series Id: e.g. 01HRB6M4D1T9W8Y3Q2ZKXN5P7C
+	Id string `json:"id"`
+	// Recurrence rule in RFC 5545 format: e.g. FREQ=WEEKLY;BYDAY=TU,TH;COUNT=10
+	RRule string `json:"rrule"`
+	// Start times of the skipped occurrences
+	ExDates []time.Time `dynamodbav:",omitempty" json:"exDates"`
+	// Member Id of the member who created the series
+	Booker string `json:"booker"`
+	// Reserved occurrences, in chronological order
+	Occurrences []SeriesOccurrence `json:"occurrences"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	UpdatedAt   time.Time          `json:"updatedAt"`
+}
+
+type SeriesOccurrence struct {
+	ReservationId string `json:"reservationId"`
+	// Start time of the occurrence when the series was created
+	StartTime time.Time `json:"startTime"`
+}
+
+// Database item to store a series.
+type seriesItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key: e.g. SERIES#01HRB6M4D1T9W8Y3Q2ZKXN5P7C
+	SK string
+
+	// Item type: reservationSeries
+	ItemType string
+	Series
+}
+
+// Occurrence of a series that could not be written
+type OccurrenceConflict struct {
+	// Reservation Id, empty for occurrences that could not be created
+	ReservationId string    `json:"reservationId,omitempty"`
+	StartTime     time.Time `json:"startTime"`
+	// Id of the error preventing the write: e.g. reservation_overbooking_conflict
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+type SeriesOutput struct {
+	Series *Series `json:"series"`
+	// Reservations written or read, in chronological order
+	Reservations []Reservation `json:"reservations"`
+	// Occurrences that could not be written
+	Conflicts []OccurrenceConflict `json:"conflicts"`
+}
+
+// Records the business error preventing the write of an occurrence, other errors are returned.
+func (output *SeriesOutput) addConflict(reservationId string, startTime time.Time, err error) error {
+	var aviatorError aviatorErrors.AviatorError
+	if !errors.As(err, &aviatorError) {
+		return err
+	}
+	output.Conflicts = append(output.Conflicts, OccurrenceConflict{
+		ReservationId: reservationId,
+		StartTime:     startTime,
+		Error:         aviatorError.Id,
+		Message:       aviatorError.Message.EN,
+	})
+	return nil
+}
+
+func seriesSK(seriesId string) string {
+	return fmt.Sprintf("%s#%s", SERIES_PARTITION_KEY, seriesId)
+}
+
+// Creates a series of recurring reservations.
+// Occurrences are written atomically in chunks, each chunk in a single transaction along with the series.
+// Occurrences that fail validation or overlap another reservation are reported as conflicts and skipped.
+func (c *Client) CreateSeries(input SeriesInput) (*SeriesOutput, error) {
+	c.SetLogger(c.Logger().With(
+		"aircraft", input.Aircraft,
+		"pilot", input.Pilot,
+		"type", input.ReservationType,
+		"rrule", input.RRule))
+	c.Logger().Info("creating reservation series")
+
+	template := input.Reservation
+	template.Booker = c.UserId
+	template.Status = STATUS_PENDING
+	template.CancellationReason = nil
+	template.Version = 1
+	err := c.authorize(OPERATION_CREATE, template)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := c.clubLocation()
+	if err != nil {
+		return nil, err
+	}
+	rule, err := parseRecurrenceRule(input.RRule, location)
+	if err != nil {
+		return nil, err
+	}
+	starts, err := rule.expand(template.StartTime, location, input.ExDates)
+	if err != nil {
+		return nil, err
+	}
+	if len(starts) == 0 {
+		return nil, ReservationEmptySeriesError
+	}
+
+	series := Series{
+		Id:          ulid.Make().String(),
+		RRule:       input.RRule,
+		ExDates:     input.ExDates,
+		Booker:      template.Booker,
+		Occurrences: make([]SeriesOccurrence, 0, len(starts)),
+	}
+	c.SetLogger(c.Logger().With("series", series.Id))
+	output := &SeriesOutput{
+		Series:       &series,
+		Reservations: make([]Reservation, 0, len(starts)),
+		Conflicts:    make([]OccurrenceConflict, 0),
+	}
+
+	duration := template.EndTime.Sub(template.StartTime)
+	occurrences := make([]Reservation, 0, len(starts))
+	minimumBalances := make(map[string]int64)
+	for _, start := range starts {
+		occurrence := template
+		occurrence.Id = ulid.Make().String()
+		occurrence.StartTime = start
+		occurrence.EndTime = start.Add(duration)
+		occurrence.SeriesId = &series.Id
+
+		err = c.validate(occurrence, nil)
+		if err == nil && occurrence.Aircraft != "" {
+			// The earlier occurrences are not written yet, so they are added to the cost committed by the pilot
+			var minimum *int64
+			minimum, err = c.validatePrepaidBalance(occurrence, nil, occurrences)
+			if minimum != nil {
+				minimumBalances[occurrence.Id] = *minimum
+			}
+		}
+		// Each occurrence is written in a single transaction along with the series and its checks
+		if err == nil && SERIES_TRANSACT_ITEMS+occurrenceItems(occurrence) > MAX_TRANSACT_ITEMS {
+			err = ReservationTooManyItemsError
+		}
+		if err != nil {
+			err = output.addConflict("", start, err)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+
+	for len(occurrences) > 0 {
+		chunk := seriesChunk(occurrences)
+		occurrences = occurrences[len(chunk):]
+
+		var written []Reservation
+		var conflicts []OccurrenceConflict
+		for attempt := 1; ; attempt++ {
+			written, conflicts, err = c.writeSeriesChunk(series, chunk, minimumBalances)
+			if err == nil {
+				break
+			}
+			if !database.IsConditionalCheckFailure(err) || attempt == MAX_WRITE_ATTEMPTS {
+				return nil, err
+			}
+			c.Logger().Warn("concurrent reservation write detected, retrying", "attempt", attempt)
+		}
+
+		for _, occurrence := range written {
+			series.Occurrences = append(series.Occurrences, SeriesOccurrence{
+				ReservationId: occurrence.Id,
+				StartTime:     occurrence.StartTime,
+			})
+			if series.CreatedAt.IsZero() {
+				series.CreatedAt = occurrence.CreatedAt
+			}
+			series.UpdatedAt = occurrence.UpdatedAt
+		}
+		output.Reservations = append(output.Reservations, written...)
+		output.Conflicts = append(output.Conflicts, conflicts...)
+	}
+
+	if len(series.Occurrences) == 0 {
+		c.Logger().Info("no occurrence of the series could be reserved", "conflicts", len(output.Conflicts))
+		return nil, ReservationSeriesConflictError
+	}
+
+	c.Logger().Info("reservation series created", "occurrences", len(output.Reservations), "conflicts", len(output.Conflicts))
+	return output, nil
+}
+
+// Returns the first occurrences that can be written in a single transaction along with the series and its checks.
+// Occurrences must each fit in such a transaction, see occurrenceItems.
+func seriesChunk(occurrences []Reservation) []Reservation {
+	items := SERIES_TRANSACT_ITEMS
+	for i, occurrence := range occurrences {
+		items += occurrenceItems(occurrence)
+		if items > MAX_TRANSACT_ITEMS && i > 0 {
+			return occurrences[:i]
+		}
+	}
+	return occurrences
+}
+
+// Returns the maximum number of transaction items writing an occurrence:
+// reservation, history entry, calendar entries and at most one slot lock per resource and day.
+func occurrenceItems(occurrence Reservation) int {
+	return 2 + len(reservationMembers(occurrence)) + len(occurrence.Resources) +
+		len(lockedResources(occurrence))*len(slotDays(occurrence.StartTime, occurrence.EndTime))
+}
+
+// Writes the occurrences that do not overlap any reservation or maintenance block in a single transaction, along with the series.
+// The series is created by the first chunk and the written occurrences are appended to it by the following ones.
+// Minimum balances are the balances of the pilot the occurrences were validated against, see validatePrepaidBalance.
+// Returns the written occurrences and the conflicting ones.
+func (c *Client) writeSeriesChunk(series Series, chunk []Reservation, minimumBalances map[string]int64) ([]Reservation,
+	[]OccurrenceConflict, error) {
+	days := make([]string, 0)
+	for _, occurrence := range chunk {
+		days = append(days, slotDays(occurrence.StartTime, occurrence.EndTime)...)
+	}
+	// All occurrences hold the same resources
+	locks, err := c.readSlotLocks(lockedResources(chunk[0]), days)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The version of the maintenance blocks is read before checking the occurrences, like in maintenanceCheckItem
+	maintenanceClient := maintenance.NewFromConfig(maintenance.Config{Scope: c.Scope})
+	maintenanceVersion := 0
+	if chunk[0].Aircraft != "" {
+		maintenanceVersion, err = maintenanceClient.LockVersion(chunk[0].Aircraft)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	written := make([]Reservation, 0, len(chunk))
+	conflicts := make([]OccurrenceConflict, 0)
+	transactItems := make([]types.TransactWriteItem, 0)
+	changedLocks := make(map[string]bool)
+	for _, occurrence := range chunk {
+		// Occurrences added to the locks by this chunk are checked as well
+		err = c.overlapError(locks, occurrence)
+		if err == nil && occurrence.Aircraft != "" {
+			err = c.validateMaintenance(occurrence)
+		}
+		if err != nil {
+			var conflictError aviatorErrors.AviatorError
+			if !errors.As(err, &conflictError) {
+				return nil, nil, err
+			}
+			conflicts = append(conflicts, OccurrenceConflict{
+				StartTime: occurrence.StartTime,
+				Error:     conflictError.Id,
+				Message:   conflictError.Message.EN,
+			})
+			continue
+		}
+
+		expectedVersion := 0
+		update, updatedAt, err := c.DatabaseClient.NewReplace(database.PutInput{
+			Item:                c.newDatabaseItem(occurrence),
+			ConditionExpression: aws.String("attribute_not_exists(PK)"),
+			ExpectedVersion:     &expectedVersion,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		occurrence.CreatedAt = updatedAt
+		occurrence.UpdatedAt = updatedAt
+
+		historyItem, err := c.historyEntryItem(OPERATION_CREATE, nil, &occurrence)
+		if err != nil {
+			return nil, nil, err
+		}
+		calendarItems, err := c.putCalendarEntryItems(occurrence, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		transactItems = append(transactItems, types.TransactWriteItem{Update: update}, *historyItem)
+		transactItems = append(transactItems, calendarItems...)
+
+		for _, lockedResource := range lockedResources(occurrence) {
+			for _, day := range slotDays(occurrence.StartTime, occurrence.EndTime) {
+				sk := slotLockSK(lockedResource, day)
+				locks[sk].Reservations[occurrence.Id] = slotFrom(occurrence)
+				changedLocks[sk] = true
+			}
+		}
+		written = append(written, occurrence)
+	}
+	if len(written) == 0 {
+		return written, conflicts, nil
+	}
+
+	// A transaction cannot write an item twice, so each lock is written once with all its new reservations
+	for sk := range changedLocks {
+		transactItem, err := c.writeSlotLockItem(locks[sk])
+		if err != nil {
+			return nil, nil, err
+		}
+		transactItems = append(transactItems, *transactItem)
+	}
+
+	seriesItem, err := c.seriesWriteItem(series, written)
+	if err != nil {
+		return nil, nil, err
+	}
+	transactItems = append(transactItems, *seriesItem)
+	if chunk[0].Aircraft != "" {
+		transactItems = append(transactItems, maintenanceClient.NewLockCheckItem(chunk[0].Aircraft, maintenanceVersion))
+	}
+	// Later occurrences were validated along with the earlier ones, so the highest minimum covers the whole chunk
+	minimumBalance, checkBalance := int64(0), false
+	for _, occurrence := range written {
+		if minimum, ok := minimumBalances[occurrence.Id]; ok && (!checkBalance || minimum > minimumBalance) {
+			minimumBalance, checkBalance = minimum, true
+		}
+	}
+	if checkBalance {
+		ledgerClient := ledger.NewFromConfig(ledger.Config{Scope: c.Scope})
+		transactItems = append(transactItems, ledgerClient.NewBalanceCheckItem(chunk[0].Pilot, minimumBalance))
+	}
+
+	_, err = c.DatabaseClient.TransactWriteItems(dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return written, conflicts, nil
+}
+
+// Builds the transaction item creating the series with its first written occurrences,
+// or appending the written occurrences to an existing series.
+func (c *Client) seriesWriteItem(series Series, written []Reservation) (*types.TransactWriteItem, error) {
+	occurrences := make([]SeriesOccurrence, 0, len(written))
+	for _, occurrence := range written {
+		occurrences = append(occurrences, SeriesOccurrence{
+			ReservationId: occurrence.Id,
+			StartTime:     occurrence.StartTime,
+		})
+	}
+
+	if len(series.Occurrences) == 0 {
+		series.Occurrences = occurrences
+		update, _, err := c.DatabaseClient.NewReplace(database.PutInput{
+			Item: seriesItem{
+				PK:       c.clubPK(),
+				SK:       seriesSK(series.Id),
+				ItemType: "reservationSeries",
+				Series:   series,
+			},
+			ConditionExpression: aws.String("attribute_not_exists(PK)"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &types.TransactWriteItem{Update: update}, nil
+	}
+
+	values, err := attributevalue.Marshal(occurrences)
+	if err != nil {
+		return nil, err
+	}
+	return &types.TransactWriteItem{
+		Update: &types.Update{
+			TableName: aws.String(c.DatabaseClient.TableName),
+			Key: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: c.clubPK()},
+				"SK": &types.AttributeValueMemberS{Value: seriesSK(series.Id)},
+			},
+			UpdateExpression:    aws.String("SET Occurrences = list_append(Occurrences, :occurrences), UpdatedAt = :updatedAt"),
+			ConditionExpression: aws.String("attribute_exists(PK)"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":occurrences": values,
+				":updatedAt":   &types.AttributeValueMemberS{Value: written[0].UpdatedAt.Format("2006-01-02T15:04:05.000Z")},
+			},
+		},
+	}, nil
+}
+
+// Returns the timezone of the club, in which the occurrences of a series keep the same time of day.
+// Clubs without a known timezone use UTC.
+func (c *Client) clubLocation() (*time.Location, error) {
+	_, location, err := c.reservationClub()
+	return location, err
+}
+
+// Returns the club of the reservations, or nil if it is not found, along with its timezone.
+// Clubs without a known timezone use UTC.
+func (c *Client) reservationClub() (*club.Club, *time.Location, error) {
+	clubClient := club.NewFromConfig(club.Config{Scope: c.Scope})
+
+	reservationClub, err := clubClient.Get(c.TenantId)
+	if errors.Is(err, club.ClubNotFoundError) {
+		c.Logger().Warn("club not found, using UTC")
+		return nil, time.UTC, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	location, err := time.LoadLocation(reservationClub.Timezone)
+	if err != nil {
+		c.Logger().Warn("invalid club timezone, using UTC", "timezone", reservationClub.Timezone)
+		return reservationClub, time.UTC, nil
+	}
+	return reservationClub, location, nil
+}
+
+func (c *Client) getSeries(seriesId string) (*Series, error) {
+	output, err := c.DatabaseClient.Get(database.GetInput{
+		PK: c.clubPK(),
+		SK: seriesSK(seriesId),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.Item == nil {
+		return nil, nil
+	}
+
+	series := new(Series)
+	err = attributevalue.UnmarshalMap(output.Item, series)
+	if err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// Returns a series along with the reservations of its occurrences the caller may read.
+// Deleted occurrences are left out.
+func (c *Client) GetSeries(seriesId string) (*SeriesOutput, error) {
+	c.SetLogger(c.Logger().With("series", seriesId))
+	c.Logger().Info("retrieving reservation series")
+
+	series, err := c.getSeries(seriesId)
+	if err != nil {
+		return nil, err
+	}
+	if series == nil {
+		return nil, ReservationSeriesNotFoundError
+	}
+
+	output := &SeriesOutput{
+		Series:       series,
+		Reservations: make([]Reservation, 0, len(series.Occurrences)),
+		Conflicts:    make([]OccurrenceConflict, 0),
+	}
+	for _, occurrence := range series.Occurrences {
+		reservation, err := c.getItem(occurrence.ReservationId, false)
+		if err != nil {
+			return nil, err
+		}
+		if reservation != nil && c.isAllowed(OPERATION_READ, *reservation) {
+			output.Reservations = append(output.Reservations, *reservation)
+		}
+	}
+	// Members who may not read any occurrence do not learn about the series
+	if len(output.Reservations) == 0 && c.UserRole != constants.ROLE_ADMIN {
+		return nil, ReservationSeriesNotFoundError
+	}
+
+	c.Logger().Info("reservation series retrieved", "occurrences", len(output.Reservations))
+	return output, nil
+}
+
+// Returns the series of an occurrence and the Ids of the occurrences targeted by an edit of the given scope:
+// the occurrence itself, the occurrences of its series from this one on, or all occurrences of its series.
+func (c *Client) scopedOccurrences(occurrence Reservation, scope string) (*Series, []string, error) {
+	switch scope {
+	case "", SCOPE_THIS:
+		return nil, []string{occurrence.Id}, nil
+	case SCOPE_FOLLOWING, SCOPE_ALL:
+	default:
+		return nil, nil, ReservationInvalidScopeError
+	}
+	if occurrence.SeriesId == nil {
+		return nil, nil, ReservationInvalidScopeError
+	}
+
+	series, err := c.getSeries(*occurrence.SeriesId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if series == nil {
+		return nil, nil, ReservationSeriesNotFoundError
+	}
+	return series, series.scopedIds(occurrence.Id, scope), nil
+}
+
+// Returns the Ids of the occurrences of the series from the given one on, or all of them for SCOPE_ALL.
+func (s Series) scopedIds(reservationId string, scope string) []string {
+	ids := make([]string, 0, len(s.Occurrences))
+	selected := scope == SCOPE_ALL
+	for _, o := range s.Occurrences {
+		selected = selected || o.ReservationId == reservationId
+		if selected {
+			ids = append(ids, o.ReservationId)
+		}
+	}
+	return ids
+}
+
+// Reads the other occurrences targeted by an edit, the edited occurrence is returned as is.
+// Returns nil for deleted, closed and past occurrences, which are left as they are.
+func (c *Client) editableOccurrence(edited Reservation, reservationId string) (*Reservation, error) {
+	if reservationId == edited.Id {
+		return &edited, nil
+	}
+	occurrence, err := c.getItem(reservationId, false)
+	if err != nil {
+		return nil, err
+	}
+	if occurrence == nil || !isOpen(occurrence.Status) || occurrence.EndTime.Before(time.Now()) {
+		return nil, nil
+	}
+	return occurrence, nil
+}
+
+// Applies a partial update to an occurrence and, depending on the scope, to the following or all occurrences of its series.
+// Start and end times move the other occurrences by the same amount as the edited one.
+// Occurrences that cannot be updated are reported as conflicts.
+func (c *Client) PatchSeries(reservationId string, scope string, patch ReservationPatch) (*SeriesOutput, error) {
+	c.SetLogger(c.Logger().With("reservation", reservationId, "scope", scope))
+	c.Logger().Info("patching reservation series")
+
+	edited, err := c.getItem(reservationId, false)
+	if err != nil {
+		return nil, err
+	}
+	if edited == nil {
+		return nil, ReservationNotFoundError
+	}
+	err = c.authorize(OPERATION_UPDATE, *edited)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Version != nil && *patch.Version != edited.Version {
+		return nil, ReservationVersionMismatchError
+	}
+
+	series, ids, err := c.scopedOccurrences(*edited, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &SeriesOutput{
+		Series:       series,
+		Reservations: make([]Reservation, 0, len(ids)),
+		Conflicts:    make([]OccurrenceConflict, 0),
+	}
+	for _, id := range ids {
+		occurrence, err := c.editableOccurrence(*edited, id)
+		if err != nil {
+			return nil, err
+		}
+		if occurrence == nil {
+			continue
+		}
+
+		occurrencePatch := patch
+		if id != edited.Id {
+			occurrencePatch.Version = nil
+		}
+		if patch.StartTime != nil {
+			occurrencePatch.StartTime = aws.Time(occurrence.StartTime.Add(patch.StartTime.Sub(edited.StartTime)))
+		}
+		if patch.EndTime != nil {
+			occurrencePatch.EndTime = aws.Time(occurrence.EndTime.Add(patch.EndTime.Sub(edited.EndTime)))
+		}
+
+		out, err := c.retryWrite(func() (*Reservation, error) {
+			return c.patch(id, occurrencePatch)
+		})
+		if err != nil {
+			err = output.addConflict(id, occurrence.StartTime, err)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		output.Reservations = append(output.Reservations, *out)
+	}
+
+	c.Logger().Info("reservation series patched", "occurrences", len(output.Reservations), "conflicts", len(output.Conflicts))
+	return output, nil
+}
+
+// Cancels an occurrence and, depending on the scope, the following or all occurrences of its series.
+// Occurrences that can no longer be cancelled are left as they are, the ones failing to be cancelled are reported as conflicts.
+func (c *Client) CancelSeries(reservationId string, scope string, input TransitionInput) (*SeriesOutput, error) {
+	c.SetLogger(c.Logger().With("reservation", reservationId, "scope", scope))
+	c.Logger().Info("cancelling reservation series")
+
+	input.Operation = string(OPERATION_CANCEL)
+	if input.Reason == nil || strings.TrimSpace(*input.Reason) == "" {
+		return nil, ReservationCancellationReasonRequiredError
+	}
+
+	edited, err := c.getItem(reservationId, false)
+	if err != nil {
+		return nil, err
+	}
+	if edited == nil {
+		return nil, ReservationNotFoundError
+	}
+	err = c.authorize(OPERATION_CANCEL, *edited)
+	if err != nil {
+		return nil, err
+	}
+	if input.Version != nil && *input.Version != edited.Version {
+		return nil, ReservationVersionMismatchError
+	}
+
+	series, ids, err := c.scopedOccurrences(*edited, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &SeriesOutput{
+		Series:       series,
+		Reservations: make([]Reservation, 0, len(ids)),
+		Conflicts:    make([]OccurrenceConflict, 0),
+	}
+	for _, id := range ids {
+		occurrence, err := c.editableOccurrence(*edited, id)
+		if err != nil {
+			return nil, err
+		}
+		if occurrence == nil {
+			continue
+		}
+		occurrenceInput := input
+		if id != edited.Id {
+			if _, ok := transitions[occurrence.Status][OPERATION_CANCEL]; !ok {
+				continue
+			}
+			occurrenceInput.Version = nil
+		}
+
+		out, err := c.retryWrite(func() (*Reservation, error) {
+			return c.transition(id, OPERATION_CANCEL, occurrenceInput)
+		})
+		if err != nil {
+			err = output.addConflict(id, occurrence.StartTime, err)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		output.Reservations = append(output.Reservations, *out)
+	}
+
+	c.Logger().Info("reservation series cancelled", "occurrences", len(output.Reservations), "conflicts", len(output.Conflicts))
+	return output, nil
+}
+
+// Runs a reservation write, retrying it when a concurrent write modified one of its items in the meantime.
+func (c *Client) retryWrite(write func() (*Reservation, error)) (*Reservation, error) {
+	for attempt := 1; ; attempt++ {
+		out, err := write()
+		if err == nil || !database.IsConditionalCheckFailure(err) || attempt == MAX_WRITE_ATTEMPTS {
+			return out, err
+		}
+		c.Logger().Warn("concurrent reservation write detected, retrying", "attempt", attempt)
+	}
+}
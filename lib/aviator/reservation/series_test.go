@@ -0,0 +1,193 @@
+package reservation
+
+import (
+	"aviator/constants"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Returns occurrences flown by the same pilot, an hour each day from the given start.
+func dailyOccurrences(count int, start time.Time, duration time.Duration, resources []string) []Reservation {
+	occurrences := make([]Reservation, 0, count)
+	for i := 0; i < count; i++ {
+		startTime := start.AddDate(0, 0, i)
+		occurrences = append(occurrences, Reservation{
+			Aircraft:  "HB-KFQ",
+			Resources: resources,
+			Pilot:     USER_ID,
+			Booker:    USER_ID,
+			StartTime: startTime,
+			EndTime:   startTime.Add(duration),
+		})
+	}
+	return occurrences
+}
+
+func TestOccurrenceItems(t *testing.T) {
+	start := time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC)
+	instructor := OTHER_ID
+	tests := []struct {
+		name       string
+		occurrence Reservation
+		want       int
+	}{
+		{
+			// Reservation, history, one calendar, aircraft and pilot locks
+			name:       "aircraft on one day",
+			occurrence: dailyOccurrences(1, start, time.Hour, nil)[0],
+			want:       5,
+		},
+		{
+			// Reservation, history, two calendars, aircraft, pilot and instructor locks
+			name: "aircraft with an instructor",
+			occurrence: Reservation{
+				Aircraft: "HB-KFQ", Pilot: USER_ID, Booker: USER_ID, Instructor: &instructor,
+				StartTime: start, EndTime: start.Add(time.Hour),
+			},
+			want: 7,
+		},
+		{
+			// Reservation, history, one member and two resource calendars, four locks on each of three days
+			name:       "aircraft and two resources over three days",
+			occurrence: dailyOccurrences(1, start, 50*time.Hour, []string{"room", "simulator"})[0],
+			want:       2 + 3 + 4*3,
+		},
+	}
+	for _, test := range tests {
+		if got := occurrenceItems(test.occurrence); got != test.want {
+			t.Errorf("%s: occurrenceItems = %d, want %d", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSeriesChunk(t *testing.T) {
+	start := time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC)
+	many := make([]string, 0, 30)
+	for i := 0; i < 30; i++ {
+		many = append(many, string(rune('a'+i)))
+	}
+	tests := []struct {
+		name        string
+		occurrences []Reservation
+		want        int
+	}{
+		{"single occurrence", dailyOccurrences(1, start, time.Hour, nil), 1},
+		// The series, its checks and 19 occurrences of 5 items fill 98 items, a 20th would exceed 100
+		{"fills the transaction", dailyOccurrences(25, start, time.Hour, nil), 19},
+		{"exactly fits", dailyOccurrences(19, start, time.Hour, nil), 19},
+		// Occurrences too large for a transaction are rejected before chunking, the chunk always progresses
+		{"oversized first occurrence", dailyOccurrences(3, start, 50*time.Hour, many), 1},
+	}
+	for _, test := range tests {
+		got := seriesChunk(test.occurrences)
+		if len(got) != test.want {
+			t.Errorf("%s: chunk of %d occurrences, want %d", test.name, len(got), test.want)
+		}
+		if len(got) > 0 && &got[0] != &test.occurrences[0] {
+			t.Errorf("%s: chunk does not start with the first occurrence", test.name)
+		}
+	}
+
+	// Chunking all occurrences writes each of them once, in order
+	occurrences := dailyOccurrences(50, start, time.Hour, nil)
+	remaining := occurrences
+	written := 0
+	for len(remaining) > 0 {
+		chunk := seriesChunk(remaining)
+		items := SERIES_TRANSACT_ITEMS
+		for _, occurrence := range chunk {
+			items += occurrenceItems(occurrence)
+		}
+		if items > MAX_TRANSACT_ITEMS {
+			t.Errorf("chunk of %d items exceeds the transaction size", items)
+		}
+		written += len(chunk)
+		remaining = remaining[len(chunk):]
+	}
+	if written != len(occurrences) {
+		t.Errorf("%d occurrences written, want %d", written, len(occurrences))
+	}
+}
+
+func TestScopedIds(t *testing.T) {
+	series := Series{Occurrences: []SeriesOccurrence{
+		{ReservationId: "a"}, {ReservationId: "b"}, {ReservationId: "c"},
+	}}
+	tests := []struct {
+		reservationId string
+		scope         string
+		want          []string
+	}{
+		{"a", SCOPE_FOLLOWING, []string{"a", "b", "c"}},
+		{"b", SCOPE_FOLLOWING, []string{"b", "c"}},
+		{"c", SCOPE_FOLLOWING, []string{"c"}},
+		{"b", SCOPE_ALL, []string{"a", "b", "c"}},
+		{"c", SCOPE_ALL, []string{"a", "b", "c"}},
+		// Occurrences removed from the series do not select any other
+		{"x", SCOPE_FOLLOWING, []string{}},
+	}
+	for _, test := range tests {
+		got := series.scopedIds(test.reservationId, test.scope)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s from %s: ids = %v, want %v", test.scope, test.reservationId, got, test.want)
+		}
+	}
+}
+
+// Scopes needing the series are rejected for reservations outside of a series, and unknown scopes for all.
+func TestScopedOccurrencesWithoutSeries(t *testing.T) {
+	client := newTestClient(USER_ID, constants.ROLE_ADMIN)
+	seriesId := "01HRB6M4D1T9W8Y3Q2ZKXN5P7C"
+	tests := []struct {
+		scope     string
+		seriesId  *string
+		wantIds   []string
+		wantError error
+	}{
+		{"", nil, []string{"r"}, nil},
+		{SCOPE_THIS, &seriesId, []string{"r"}, nil},
+		{SCOPE_FOLLOWING, nil, nil, ReservationInvalidScopeError},
+		{SCOPE_ALL, nil, nil, ReservationInvalidScopeError},
+		{"next", &seriesId, nil, ReservationInvalidScopeError},
+	}
+	for _, test := range tests {
+		_, ids, err := client.scopedOccurrences(Reservation{Id: "r", SeriesId: test.seriesId}, test.scope)
+		if test.wantError != nil {
+			if !errors.Is(err, test.wantError) {
+				t.Errorf("%q: error = %v, want %v", test.scope, err, test.wantError)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(ids, test.wantIds) {
+			t.Errorf("%q: ids = %v, error = %v, want %v", test.scope, ids, err, test.wantIds)
+		}
+	}
+}
+
+func TestAddConflict(t *testing.T) {
+	output := &SeriesOutput{Conflicts: make([]OccurrenceConflict, 0)}
+	start := time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC)
+
+	if err := output.addConflict("r", start, ReservationTooManyItemsError); err != nil {
+		t.Fatalf("business error: addConflict = %v, want nil", err)
+	}
+	want := OccurrenceConflict{
+		ReservationId: "r",
+		StartTime:     start,
+		Error:         ReservationTooManyItemsError.Id,
+		Message:       ReservationTooManyItemsError.Message.EN,
+	}
+	if len(output.Conflicts) != 1 || output.Conflicts[0] != want {
+		t.Errorf("conflicts = %+v, want [%+v]", output.Conflicts, want)
+	}
+
+	other := errors.New("network error")
+	if err := output.addConflict("r", start, other); err != other {
+		t.Errorf("other error: addConflict = %v, want %v", err, other)
+	}
+	if len(output.Conflicts) != 1 {
+		t.Errorf("other error recorded as a conflict")
+	}
+}
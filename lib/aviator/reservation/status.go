@@ -0,0 +1,230 @@
+package reservation
+
+import (
+	"aviator/aircraft"
+	"aviator/billing"
+	"aviator/database"
+	"aviator/flightlog"
+	"aviator/ledger"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Reservation made, waiting for a confirmation by the club
+const STATUS_PENDING = "pending"
+
+// Reservation confirmed by the club
+const STATUS_CONFIRMED = "confirmed"
+
+// Aircraft handed over to the pilot
+const STATUS_CHECKED_OUT = "checked-out"
+
+// Aircraft returned by the pilot
+const STATUS_COMPLETED = "completed"
+
+// Reservation cancelled, kept for statistics but not holding its slot anymore
+const STATUS_CANCELLED = "cancelled"
+
+// Pilot did not show up
+const STATUS_NO_SHOW = "no-show"
+
+// Allowed transitions: the status reached by each operation, per current status.
+// Completed, cancelled and no-show reservations are closed and cannot change anymore.
+var transitions = map[string]map[operation]string{
+	STATUS_PENDING: {
+		OPERATION_CONFIRM: STATUS_CONFIRMED,
+		OPERATION_CANCEL:  STATUS_CANCELLED,
+		OPERATION_NO_SHOW: STATUS_NO_SHOW,
+	},
+	STATUS_CONFIRMED: {
+		OPERATION_CHECK_OUT: STATUS_CHECKED_OUT,
+		OPERATION_CANCEL:    STATUS_CANCELLED,
+		OPERATION_NO_SHOW:   STATUS_NO_SHOW,
+	},
+	STATUS_CHECKED_OUT: {
+		OPERATION_CHECK_IN: STATUS_COMPLETED,
+	},
+}
+
+// Returns true if the reservation can still be modified.
+func isOpen(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+// Reservations stored before statuses existed were all considered confirmed.
+func normalizeStatus(reservation *Reservation) {
+	if reservation.Status == "" {
+		reservation.Status = STATUS_CONFIRMED
+	}
+}
+
+type TransitionInput struct {
+	// Operation moving the reservation to its next status: confirm, cancel, check-out, check-in or no-show
+	Operation string `json:"-"`
+	// Reason of a cancellation, required to cancel
+	Reason *string `json:"reason"`
+	// The transition only succeeds if the stored reservation still has this version
+	Version *int `json:"version"`
+	// What was actually flown, required to check in a reservation with an aircraft
+	FlightLog *flightlog.FlightLog `json:"flightLog"`
+}
+
+// Attributes of the reservation item changed by a transition, field names are the attribute names.
+type transitionItem struct {
+	Status             *string
+	CancellationReason *string
+	GSIData            *map[string]interface{}
+}
+
+// Moves a reservation to its next status.
+// Cancelled reservations release their slots, so that the aircraft can be reserved again.
+// Checking in a reservation with an aircraft records its flight log, moves the meters of the aircraft forward and charges the pilot.
+func (c *Client) Transition(reservationId string, input TransitionInput) (*Reservation, error) {
+	c.SetLogger(c.Logger().With("reservation", reservationId, "operation", input.Operation))
+	c.Logger().Info("changing reservation status")
+
+	op := operation(input.Operation)
+	if op == OPERATION_CANCEL && (input.Reason == nil || strings.TrimSpace(*input.Reason) == "") {
+		return nil, ReservationCancellationReasonRequiredError
+	}
+
+	var out *Reservation
+	for attempt := 1; ; attempt++ {
+		var err error
+		out, err = c.transition(reservationId, op, input)
+		if err == nil {
+			break
+		}
+		if !database.IsConditionalCheckFailure(err) || attempt == MAX_WRITE_ATTEMPTS {
+			return nil, err
+		}
+		c.Logger().Warn("concurrent reservation write detected, retrying", "attempt", attempt)
+	}
+
+	c.Logger().Info("reservation status changed", "status", out.Status, "version", out.Version)
+	return out, nil
+}
+
+func (c *Client) transition(reservationId string, op operation, input TransitionInput) (*Reservation, error) {
+	previous, err := c.getItem(reservationId, true)
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		return nil, ReservationNotFoundError
+	}
+	err = c.authorize(op, *previous)
+	if err != nil {
+		return nil, err
+	}
+	if input.Version != nil && *input.Version != previous.Version {
+		c.Logger().Info("reservation was modified in the meantime", "version", *input.Version, "storedVersion", previous.Version)
+		return nil, ReservationVersionMismatchError
+	}
+
+	status, ok := transitions[previous.Status][op]
+	if !ok {
+		c.Logger().Info("invalid reservation status transition", "status", previous.Status)
+		return nil, ReservationInvalidTransitionError
+	}
+
+	next := *previous
+	next.Status = status
+	next.Version = previous.Version + 1
+	item := transitionItem{
+		Status:  &next.Status,
+		GSIData: &map[string]interface{}{"Status": next.Status, "Version": next.Version},
+	}
+	if op == OPERATION_CANCEL {
+		next.CancellationReason = input.Reason
+		item.CancellationReason = input.Reason
+	}
+
+	var flightLogItems []types.TransactWriteItem
+	if op == OPERATION_CHECK_IN && previous.Aircraft != "" {
+		flightLogItems, err = c.flightLogItems(*previous, input.FlightLog)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	update, updatedAt, err := c.DatabaseClient.NewUpdate(database.UpdateInput{
+		PK:                  c.clubPK(),
+		SK:                  fmt.Sprintf("%s#%s", RESERVATION_PARTITION_KEY, reservationId),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_exists(PK)"),
+		ExpectedVersion:     &previous.Version,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("building reservation update: %w", err)
+	}
+	next.UpdatedAt = updatedAt
+
+	return c.transactWrite(next, previous, op, types.TransactWriteItem{Update: update}, flightLogItems...)
+}
+
+// Returns the items writing the flight log of a reservation being checked in, recording the meter readings on its aircraft
+// and charging the flight to the pilot.
+// The readings at the start of the flight cannot be lower than the last readings of the aircraft.
+func (c *Client) flightLogItems(reservation Reservation, input *flightlog.FlightLog) ([]types.TransactWriteItem, error) {
+	if input == nil {
+		return nil, ReservationFlightLogRequiredError
+	}
+	flightLog := *input
+	flightLog.ReservationId = reservation.Id
+	flightLog.Aircraft = reservation.Aircraft
+	flightLog.Pilot = reservation.Pilot
+	flightLog.Instructor = reservation.Instructor
+	err := flightLog.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	aircraftClient := aircraft.NewFromConfig(aircraft.Config{Scope: c.Scope})
+	flownAircraft, err := aircraftClient.Get(reservation.Aircraft)
+	if errors.Is(err, aircraft.AircraftNotFoundError) {
+		return nil, ReservationInvalidAircraftError
+	}
+	if err != nil {
+		return nil, err
+	}
+	if flightLog.HobbsStart < flownAircraft.Hobbs || flightLog.TachStart < flownAircraft.Tach {
+		c.Logger().Info("meter readings lower than the aircraft ones", "hobbs", flownAircraft.Hobbs, "tach", flownAircraft.Tach)
+		return nil, ReservationMeterRegressionError
+	}
+
+	flightLogClient := flightlog.NewFromConfig(flightlog.Config{Scope: c.Scope})
+	put, err := flightLogClient.NewPut(flightLog)
+	if err != nil {
+		return nil, err
+	}
+
+	// The meter update is conditioned on the stored readings, so a concurrent flight log makes the transaction fail and retry
+	meterUpdate := aircraftClient.NewMeterUpdate(reservation.Aircraft,
+		flightLog.HobbsStart, flightLog.HobbsEnd, flightLog.TachStart, flightLog.TachEnd)
+	transactItems := []types.TransactWriteItem{{Put: put}, {Update: meterUpdate}}
+
+	billingClient := billing.NewFromConfig(billing.Config{Scope: c.Scope})
+	charges, err := billingClient.Compute(flightLog, reservation.ReservationType)
+	if err != nil {
+		return nil, err
+	}
+	chargeItems, err := billingClient.NewPuts(charges)
+	if err != nil {
+		return nil, err
+	}
+
+	// The prepaid account of the pilot is debited in the same transaction as the charges
+	ledgerClient := ledger.NewFromConfig(ledger.Config{Scope: c.Scope})
+	ledgerItems, err := ledgerClient.NewChargeItems(reservation.Pilot, reservation.Id, charges)
+	if err != nil {
+		return nil, err
+	}
+	transactItems = append(transactItems, chargeItems...)
+	return append(transactItems, ledgerItems...), nil
+}
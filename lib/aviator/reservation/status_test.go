@@ -0,0 +1,104 @@
+package reservation
+
+import (
+	"aviator/constants"
+	"errors"
+	"testing"
+)
+
+var allStatuses = []string{
+	STATUS_PENDING, STATUS_CONFIRMED, STATUS_CHECKED_OUT, STATUS_COMPLETED, STATUS_CANCELLED, STATUS_NO_SHOW,
+}
+
+var transitionOperations = []operation{
+	OPERATION_CONFIRM, OPERATION_CANCEL, OPERATION_CHECK_OUT, OPERATION_CHECK_IN, OPERATION_NO_SHOW,
+}
+
+func TestTransitions(t *testing.T) {
+	tests := []struct {
+		status string
+		// Status reached by each allowed operation, the other operations are invalid
+		next map[operation]string
+	}{
+		{STATUS_PENDING, map[operation]string{
+			OPERATION_CONFIRM: STATUS_CONFIRMED,
+			OPERATION_CANCEL:  STATUS_CANCELLED,
+			OPERATION_NO_SHOW: STATUS_NO_SHOW,
+		}},
+		{STATUS_CONFIRMED, map[operation]string{
+			OPERATION_CHECK_OUT: STATUS_CHECKED_OUT,
+			OPERATION_CANCEL:    STATUS_CANCELLED,
+			OPERATION_NO_SHOW:   STATUS_NO_SHOW,
+		}},
+		{STATUS_CHECKED_OUT, map[operation]string{
+			OPERATION_CHECK_IN: STATUS_COMPLETED,
+		}},
+		{STATUS_COMPLETED, nil},
+		{STATUS_CANCELLED, nil},
+		{STATUS_NO_SHOW, nil},
+	}
+
+	for _, test := range tests {
+		for _, op := range transitionOperations {
+			want, wantOk := test.next[op]
+			got, ok := transitions[test.status][op]
+			if ok != wantOk || got != want {
+				t.Errorf("%s from %s: got %q (%v), want %q (%v)", op, test.status, got, ok, want, wantOk)
+			}
+		}
+	}
+}
+
+func TestIsOpen(t *testing.T) {
+	open := map[string]bool{
+		STATUS_PENDING:     true,
+		STATUS_CONFIRMED:   true,
+		STATUS_CHECKED_OUT: true,
+	}
+	for _, status := range append(allStatuses, "", "unknown") {
+		if got := isOpen(status); got != open[status] {
+			t.Errorf("isOpen(%q) = %v, want %v", status, got, open[status])
+		}
+	}
+}
+
+func TestNormalizeStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{"", STATUS_CONFIRMED},
+		{STATUS_PENDING, STATUS_PENDING},
+		{STATUS_CANCELLED, STATUS_CANCELLED},
+	}
+	for _, test := range tests {
+		reservation := Reservation{Status: test.status}
+		normalizeStatus(&reservation)
+		if reservation.Status != test.want {
+			t.Errorf("normalizeStatus(%q) = %q, want %q", test.status, reservation.Status, test.want)
+		}
+	}
+}
+
+// A cancellation without a reason is rejected before the reservation is read.
+func TestTransitionRequiresCancellationReason(t *testing.T) {
+	empty, blank := "", "  \t"
+	tests := []struct {
+		name   string
+		reason *string
+	}{
+		{"missing reason", nil},
+		{"empty reason", &empty},
+		{"blank reason", &blank},
+	}
+	for _, test := range tests {
+		client := newTestClient(USER_ID, constants.ROLE_ADMIN)
+		_, err := client.Transition("01H55420KY47HRVVPK1Z3BSACK", TransitionInput{
+			Operation: string(OPERATION_CANCEL),
+			Reason:    test.reason,
+		})
+		if !errors.Is(err, ReservationCancellationReasonRequiredError) {
+			t.Errorf("%s: error = %v, want ReservationCancellationReasonRequiredError", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package reservationtype
+
+import "aviator/errors"
+
+var ReservationTypeNotFoundError = errors.AviatorError{
+	Id: "reservation_type_not_found",
+	Message: errors.Message{
+		EN: "The selected reservation type does not exist",
+		FR: "Le type de réservation sélectionné n'existe pas",
+	},
+	ApiError: 404,
+}
+
+var ReservationTypeAlreadyExistsError = errors.AviatorError{
+	Id: "reservation_type_already_exists",
+	Message: errors.Message{
+		EN: "A reservation type with this name already exists",
+		FR: "Un type de réservation avec ce nom existe déjà",
+	},
+	ApiError: 409,
+}
+
+var ReservationTypeAccessDenyError = errors.AviatorError{
+	Id: "reservation_type_access_deny",
+	Message: errors.Message{
+		EN: "You do not have permission to manage the reservation types",
+		FR: "Vous n'avez pas l'autorisation de gérer les types de réservation",
+	},
+	ApiError: 401,
+}
+
+var ReservationTypeInvalidNameError = errors.AviatorError{
+	Id: "reservation_type_invalid_name",
+	Message: errors.Message{
+		EN: "The name of a reservation type cannot be empty nor contain #",
+		FR: "Le nom d'un type de réservation ne peut pas être vide ni contenir #",
+	},
+	ApiError: 400,
+}
+
+var ReservationTypeInvalidDurationError = errors.AviatorError{
+	Id: "reservation_type_invalid_duration",
+	Message: errors.Message{
+		EN: "The durations of a reservation type cannot be negative, and the minimum duration cannot exceed the maximum duration",
+		FR: "Les durées d'un type de réservation ne peuvent pas être négatives, et la durée minimale ne peut pas dépasser la durée maximale",
+	},
+	ApiError: 400,
+}
+
+var ReservationTypeInvalidInstructorRateError = errors.AviatorError{
+	Id: "reservation_type_invalid_instructor_rate",
+	Message: errors.Message{
+		EN: "The instructor hourly rate cannot be negative",
+		FR: "Le tarif horaire de l'instructeur ne peut pas être négatif",
+	},
+	ApiError: 400,
+}
@@ -0,0 +1,267 @@
+/*
+Package reservationtype provides methods for performing CRUD operations on the catalog of reservation types of a club.
+*/
+package reservationtype
+
+import (
+	"aviator/constants"
+	"aviator/database"
+	"aviator/tenant"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const RESERVATION_TYPE_PARTITION_KEY = "RESERVATION_TYPE"
+
+type ReservationTypeApiInterface interface {
+	Logger() *slog.Logger
+	SetLogger(logger *slog.Logger)
+	Create(input ReservationType) (*ReservationType, error)
+	Get(name string) (*ReservationType, error)
+	List(input ListInput) (*ListOutput, error)
+	Update(input ReservationType) (*ReservationType, error)
+	Delete(name string) error
+}
+
+type Config struct {
+	tenant.Scope
+}
+
+type Client struct {
+	Config
+}
+
+// Item used to store a reservation type
+type ReservationType struct {
+	// Name, unique within a club and referenced by reservations: e.g. Training
+	Name string `json:"name"`
+	// Reservations of this type must have an instructor
+	RequiresInstructor bool `json:"requiresInstructor"`
+	// Minimum duration of a reservation in minutes, 0 if there is none
+	MinDurationMinutes int `json:"minDurationMinutes"`
+	// Maximum duration of a reservation in minutes, 0 if there is none
+	MaxDurationMinutes int `json:"maxDurationMinutes"`
+	// Reservations of this type count toward the booking quota of the pilot
+	CountsTowardQuota bool `json:"countsTowardQuota"`
+	// Price per hour of block time charged for the instructor of a reservation of this type: e.g. 80
+	InstructorHourlyRate float64   `json:"instructorHourlyRate"`
+	CreatedAt            time.Time `json:"createdAt"`
+	UpdatedAt            time.Time `json:"updatedAt"`
+}
+
+// Database item to store the reservation type.
+type databaseItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key: e.g. RESERVATION_TYPE#Training
+	SK string
+
+	// Item type: reservationType
+	ItemType string
+	ReservationType
+}
+
+// Returns a new reservation type API client from the provided config.
+func NewFromConfig(c Config) *Client {
+	return &Client{Config: c}
+}
+
+func (c *Client) Logger() *slog.Logger {
+	return c.Config.Logger
+}
+
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.Config.Logger = logger
+}
+
+// Returns true if the duration is allowed for reservations of this type.
+func (t ReservationType) DurationAllowed(duration time.Duration) bool {
+	if t.MinDurationMinutes > 0 && duration < time.Duration(t.MinDurationMinutes)*time.Minute {
+		return false
+	}
+	if t.MaxDurationMinutes > 0 && duration > time.Duration(t.MaxDurationMinutes)*time.Minute {
+		return false
+	}
+	return true
+}
+
+// Adds a reservation type to the catalog of the club
+func (c *Client) Create(input ReservationType) (*ReservationType, error) {
+	c.SetLogger(c.Logger().With("reservationType", input.Name))
+	c.Logger().Info("creating reservation type")
+
+	out, err := c.put(input, "attribute_not_exists(PK)")
+	if err != nil {
+		var conditionalCheckFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionalCheckFailed) {
+			return nil, ReservationTypeAlreadyExistsError
+		}
+		return nil, err
+	}
+
+	c.Logger().Info("reservation type created")
+	return out, nil
+}
+
+// Replaces the stored data of an existing reservation type
+func (c *Client) Update(input ReservationType) (*ReservationType, error) {
+	c.SetLogger(c.Logger().With("reservationType", input.Name))
+	c.Logger().Info("updating reservation type")
+
+	out, err := c.put(input, "attribute_exists(PK)")
+	if err != nil {
+		var conditionalCheckFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionalCheckFailed) {
+			return nil, ReservationTypeNotFoundError
+		}
+		return nil, err
+	}
+
+	c.Logger().Info("reservation type updated")
+	return out, nil
+}
+
+func (c *Client) put(input ReservationType, conditionExpression string) (*ReservationType, error) {
+	if c.UserRole != constants.ROLE_ADMIN {
+		return nil, ReservationTypeAccessDenyError
+	}
+	if strings.TrimSpace(input.Name) == "" || strings.Contains(input.Name, "#") {
+		return nil, ReservationTypeInvalidNameError
+	}
+	if input.MinDurationMinutes < 0 || input.MaxDurationMinutes < 0 ||
+		(input.MaxDurationMinutes > 0 && input.MinDurationMinutes > input.MaxDurationMinutes) {
+		return nil, ReservationTypeInvalidDurationError
+	}
+	if input.InstructorHourlyRate < 0 {
+		return nil, ReservationTypeInvalidInstructorRateError
+	}
+
+	databaseItem := databaseItem{
+		PK:              c.clubPK(),
+		SK:              fmt.Sprintf("%s#%s", RESERVATION_TYPE_PARTITION_KEY, input.Name),
+		ItemType:        "reservationType",
+		ReservationType: input,
+	}
+
+	out, err := c.DatabaseClient.Put(database.PutInput{
+		Item:                databaseItem,
+		ConditionExpression: aws.String(conditionExpression),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	input.CreatedAt = out.CreatedAt
+	input.UpdatedAt = out.UpdatedAt
+	return &input, nil
+}
+
+type ListInput struct {
+	NextToken *string
+	Limit     *int32
+}
+
+type ListOutput struct {
+	NextToken *string           `json:"nextToken"`
+	Results   []ReservationType `json:"results"`
+}
+
+// Returns all reservation types of the club, ordered by name.
+func (c *Client) List(input ListInput) (*ListOutput, error) {
+	c.Logger().Info("listing reservation types")
+
+	exclusiveStartKey, err := database.DecodeNextToken(input.NextToken, c.clubPK())
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.DatabaseClient.Query(&database.QueryInput{
+		Limit:                  input.Limit,
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: c.clubPK()},
+			":sk": &types.AttributeValueMemberS{Value: RESERVATION_TYPE_PARTITION_KEY + "#"},
+		},
+		ExclusiveStartKey: exclusiveStartKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reservationTypes := make([]ReservationType, 0)
+	err = attributevalue.UnmarshalListOfMaps(output.Items, &reservationTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	nextToken, err := database.EncodeNextToken(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("reservation types listed", "count", len(reservationTypes), "isNextToken", nextToken != nil)
+	return &ListOutput{
+		NextToken: nextToken,
+		Results:   reservationTypes,
+	}, nil
+}
+
+// Returns stored data for a reservation type.
+func (c *Client) Get(name string) (*ReservationType, error) {
+	c.SetLogger(c.Logger().With("reservationType", name))
+	c.Logger().Info("retrieving reservation type")
+
+	output, err := c.DatabaseClient.Get(database.GetInput{
+		PK: c.clubPK(),
+		SK: fmt.Sprintf("%s#%s", RESERVATION_TYPE_PARTITION_KEY, name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.Item == nil {
+		return nil, ReservationTypeNotFoundError
+	}
+
+	reservationType := new(ReservationType)
+	err = attributevalue.UnmarshalMap(output.Item, reservationType)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("reservation type retrieved")
+	return reservationType, nil
+}
+
+// Removes a reservation type from the catalog. Existing reservations keep their type,
+// but can no longer be modified until they use a type of the catalog.
+func (c *Client) Delete(name string) error {
+	c.SetLogger(c.Logger().With("reservationType", name))
+	c.Logger().Info("deleting reservation type")
+
+	if c.UserRole != constants.ROLE_ADMIN {
+		return ReservationTypeAccessDenyError
+	}
+
+	_, err := c.DatabaseClient.Delete(&database.DeleteInput{
+		PK: c.clubPK(),
+		SK: fmt.Sprintf("%s#%s", RESERVATION_TYPE_PARTITION_KEY, name),
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Logger().Info("reservation type deleted")
+	return nil
+}
+
+// Returns the partition key of the tenant club owning the reservation type.
+func (c *Client) clubPK() string {
+	return fmt.Sprintf("%s#%s", constants.CLUB_PARTITION_KEY, c.TenantId)
+}
@@ -0,0 +1,57 @@
+package resource
+
+import "aviator/errors"
+
+var ResourceNotFoundError = errors.AviatorError{
+	Id: "resource_not_found",
+	Message: errors.Message{
+		EN: "The selected resource does not exist",
+		FR: "La ressource sélectionnée n'existe pas",
+	},
+	ApiError: 404,
+}
+
+var ResourceAccessDenyError = errors.AviatorError{
+	Id: "resource_access_deny",
+	Message: errors.Message{
+		EN: "You do not have permission to manage the resources",
+		FR: "Vous n'avez pas l'autorisation de gérer les ressources",
+	},
+	ApiError: 401,
+}
+
+var ResourceInvalidTypeError = errors.AviatorError{
+	Id: "resource_invalid_type",
+	Message: errors.Message{
+		EN: "The type of a resource must be room or simulator",
+		FR: "Le type d'une ressource doit être salle ou simulateur",
+	},
+	ApiError: 400,
+}
+
+var ResourceInvalidNameError = errors.AviatorError{
+	Id: "resource_invalid_name",
+	Message: errors.Message{
+		EN: "The name of a resource cannot be empty",
+		FR: "Le nom d'une ressource ne peut pas être vide",
+	},
+	ApiError: 400,
+}
+
+var ResourceInvalidStatusError = errors.AviatorError{
+	Id: "resource_invalid_status",
+	Message: errors.Message{
+		EN: "The status of a resource must be active or inactive",
+		FR: "Le statut d'une ressource doit être actif ou inactif",
+	},
+	ApiError: 400,
+}
+
+var ResourceInvalidRoleError = errors.AviatorError{
+	Id: "resource_invalid_role",
+	Message: errors.Message{
+		EN: "The allowed roles of a resource must be admin, instructor, pilot or guest",
+		FR: "Les rôles autorisés d'une ressource doivent être admin, instructeur, pilote ou invité",
+	},
+	ApiError: 400,
+}
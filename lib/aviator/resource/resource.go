@@ -0,0 +1,291 @@
+/*
+Package resource provides methods for performing CRUD operations on the bookable resources of a club other than aircraft,
+such as briefing rooms and flight simulators.
+*/
+package resource
+
+import (
+	"aviator/constants"
+	"aviator/database"
+	"aviator/tenant"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/oklog/ulid/v2"
+)
+
+const RESOURCE_PARTITION_KEY = "RESOURCE"
+
+// Briefing or meeting room
+const TYPE_ROOM = "room"
+
+// Flight simulator or flight and navigation procedures trainer (FNPT)
+const TYPE_SIMULATOR = "simulator"
+
+// Resource can be reserved
+const STATUS_ACTIVE = "active"
+
+// Resource is kept but cannot be reserved anymore
+const STATUS_INACTIVE = "inactive"
+
+type ResourceApiInterface interface {
+	Logger() *slog.Logger
+	SetLogger(logger *slog.Logger)
+	CreateOrUpdate(input Resource) (*Resource, error)
+	Get(resourceId string) (*Resource, error)
+	List(input ListInput) (*ListOutput, error)
+	Delete(resourceId string) error
+}
+
+type Config struct {
+	tenant.Scope
+}
+
+type Client struct {
+	Config
+}
+
+// Item used to store a bookable resource
+type Resource struct {
+	// Resource Id: e.g. 01HRB3T7C6M8N2Q4W9X5Y1Z0AB
+	Id string `json:"id"`
+	// Either room or simulator
+	ResourceType string `json:"resourceType"`
+	// Display name: e.g. Briefing room 1
+	Name string `json:"name"`
+	// Reservations of this resource must have an instructor
+	RequiresInstructor bool `json:"requiresInstructor"`
+	// Roles allowed to reserve the resource, every role is allowed when empty: e.g. ["instructor", "pilot"]
+	AllowedRoles []string `json:"allowedRoles"`
+	// Either active or inactive
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Database item to store the resource.
+type databaseItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key: e.g. RESOURCE#01HRB3T7C6M8N2Q4W9X5Y1Z0AB
+	SK string
+
+	// Item type: resource
+	ItemType string
+	Resource
+}
+
+// Returns a new resource API client from the provided config.
+func NewFromConfig(c Config) *Client {
+	return &Client{Config: c}
+}
+
+func (c *Client) Logger() *slog.Logger {
+	return c.Config.Logger
+}
+
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.Config.Logger = logger
+}
+
+// Returns true if members with the given role can reserve the resource.
+// Admins can reserve every resource.
+func (r Resource) AllowsRole(role string) bool {
+	if role == constants.ROLE_ADMIN || len(r.AllowedRoles) == 0 {
+		return true
+	}
+	for _, allowed := range r.AllowedRoles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Create or update a resource. Only admins can manage the resources.
+func (c *Client) CreateOrUpdate(input Resource) (*Resource, error) {
+	newResource := input.Id == ""
+	conditionExpression := "attribute_exists(PK)"
+	if newResource {
+		input.Id = ulid.Make().String()
+		conditionExpression = "attribute_not_exists(PK)"
+		c.SetLogger(c.Logger().With("resource", input.Id))
+		c.Logger().Info("creating resource")
+	} else {
+		c.SetLogger(c.Logger().With("resource", input.Id))
+		c.Logger().Info("updating resource")
+	}
+
+	if c.UserRole != constants.ROLE_ADMIN {
+		return nil, ResourceAccessDenyError
+	}
+
+	if input.Status == "" {
+		input.Status = STATUS_ACTIVE
+	}
+	if input.AllowedRoles == nil {
+		input.AllowedRoles = make([]string, 0)
+	}
+	err := validate(input)
+	if err != nil {
+		return nil, err
+	}
+
+	databaseItem := databaseItem{
+		PK:       c.clubPK(),
+		SK:       fmt.Sprintf("%s#%s", RESOURCE_PARTITION_KEY, input.Id),
+		ItemType: "resource",
+		Resource: input,
+	}
+
+	out, err := c.DatabaseClient.Put(database.PutInput{
+		Item:                databaseItem,
+		ConditionExpression: aws.String(conditionExpression),
+	})
+	if err != nil {
+		var conditionalCheckFailed *types.ConditionalCheckFailedException
+		if !newResource && errors.As(err, &conditionalCheckFailed) {
+			return nil, ResourceNotFoundError
+		}
+		return nil, err
+	}
+
+	input.CreatedAt = out.CreatedAt
+	input.UpdatedAt = out.UpdatedAt
+
+	if newResource {
+		c.Logger().Info("resource created")
+	} else {
+		c.Logger().Info("resource updated")
+	}
+
+	return &input, nil
+}
+
+func validate(input Resource) error {
+	if input.ResourceType != TYPE_ROOM && input.ResourceType != TYPE_SIMULATOR {
+		return ResourceInvalidTypeError
+	}
+	if strings.TrimSpace(input.Name) == "" {
+		return ResourceInvalidNameError
+	}
+	if input.Status != STATUS_ACTIVE && input.Status != STATUS_INACTIVE {
+		return ResourceInvalidStatusError
+	}
+	for _, role := range input.AllowedRoles {
+		switch role {
+		case constants.ROLE_ADMIN, constants.ROLE_INSTRUCTOR, constants.ROLE_PILOT, constants.ROLE_GUEST:
+		default:
+			return ResourceInvalidRoleError
+		}
+	}
+	return nil
+}
+
+type ListInput struct {
+	NextToken *string
+	Limit     *int32
+}
+
+type ListOutput struct {
+	NextToken *string    `json:"nextToken"`
+	Results   []Resource `json:"results"`
+}
+
+// Returns stored data for all resources of the club.
+func (c *Client) List(input ListInput) (*ListOutput, error) {
+	c.Logger().Info("listing resources")
+
+	exclusiveStartKey, err := database.DecodeNextToken(input.NextToken, c.clubPK())
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.DatabaseClient.Query(&database.QueryInput{
+		Limit:                  input.Limit,
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: c.clubPK()},
+			":sk": &types.AttributeValueMemberS{Value: RESOURCE_PARTITION_KEY + "#"},
+		},
+		ExclusiveStartKey: exclusiveStartKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]Resource, 0)
+	err = attributevalue.UnmarshalListOfMaps(output.Items, &resources)
+	if err != nil {
+		return nil, err
+	}
+
+	nextToken, err := database.EncodeNextToken(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("resources listed", "count", len(resources), "isNextToken", nextToken != nil)
+	return &ListOutput{
+		NextToken: nextToken,
+		Results:   resources,
+	}, nil
+}
+
+// Returns stored data for a resource.
+func (c *Client) Get(resourceId string) (*Resource, error) {
+	c.SetLogger(c.Logger().With("resource", resourceId))
+	c.Logger().Info("retrieving resource")
+
+	output, err := c.DatabaseClient.Get(database.GetInput{
+		PK: c.clubPK(),
+		SK: fmt.Sprintf("%s#%s", RESOURCE_PARTITION_KEY, resourceId),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.Item == nil {
+		return nil, ResourceNotFoundError
+	}
+
+	resource := new(Resource)
+	err = attributevalue.UnmarshalMap(output.Item, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("resource retrieved")
+	return resource, nil
+}
+
+// Removes a resource. Deactivating it keeps it in the existing reservations.
+func (c *Client) Delete(resourceId string) error {
+	c.SetLogger(c.Logger().With("resource", resourceId))
+	c.Logger().Info("deleting resource")
+
+	if c.UserRole != constants.ROLE_ADMIN {
+		return ResourceAccessDenyError
+	}
+
+	_, err := c.DatabaseClient.Delete(&database.DeleteInput{
+		PK: c.clubPK(),
+		SK: fmt.Sprintf("%s#%s", RESOURCE_PARTITION_KEY, resourceId),
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Logger().Info("resource deleted")
+	return nil
+}
+
+// Returns the partition key of the tenant club owning the resources.
+func (c *Client) clubPK() string {
+	return fmt.Sprintf("%s#%s", constants.CLUB_PARTITION_KEY, c.TenantId)
+}
@@ -0,0 +1,21 @@
+/*
+Package tenant provides the scope shared by the API clients serving a request: the caller, its club and its role.
+Clients built from the same scope always read and write the data of the same club on behalf of the same caller.
+*/
+package tenant
+
+import (
+	"aviator/database"
+	"log/slog"
+)
+
+type Scope struct {
+	Logger         *slog.Logger
+	DatabaseClient database.Client
+	// Club of the caller, whose partition holds all the data the clients read and write: e.g. 01HR9ZZNRFCKMAYNW3RY561QCP
+	TenantId string
+	// Member Id of the caller: e.g. 01H55420KY47HRVVPK1Z3BSACK
+	UserId string
+	// Role of the caller in the club: admin, instructor, pilot or guest
+	UserRole string
+}
@@ -27,9 +27,10 @@ type ErrorResponse struct {
 
 func ResponseHeaders() map[string]string {
 	return map[string]string{
-		"Access-Control-Allow-Origin":  "*",
-		"Access-Control-Allow-Methods": "*",
-		"Access-Control-Allow-Headers": "*",
+		"Access-Control-Allow-Origin":   "*",
+		"Access-Control-Allow-Methods":  "*",
+		"Access-Control-Allow-Headers":  "*",
+		"Access-Control-Expose-Headers": "ETag",
 	}
 }
 
@@ -63,6 +64,12 @@ func (c *ApiErrorClient) AwsError(err error) (events.APIGatewayProxyResponse, er
 			statusCode = http.StatusBadRequest
 			errorResponse = ErrorResponse{Message: fmt.Sprintf("%s: %s", http.StatusText(http.StatusBadRequest), apiErr.ErrorMessage())}
 			c.Logger.Warn("aws error", "code", statusCode, "message", apiErr.ErrorMessage())
+		case "ConditionalCheckFailedException", "TransactionCanceledException":
+			// Writes conflicting with existing or concurrently written items.
+			// Stale If-Match versions are reported by the domain packages with their own precondition errors.
+			statusCode = http.StatusConflict
+			errorResponse = ErrorResponse{Message: http.StatusText(http.StatusConflict)}
+			c.Logger.Warn("aws error", "code", statusCode, "message", apiErr.ErrorMessage())
 		case "AccessDeniedException":
 			statusCode = http.StatusUnauthorized
 			errorResponse = ErrorResponse{Message: http.StatusText(http.StatusUnauthorized)}
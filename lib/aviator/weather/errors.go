@@ -0,0 +1,111 @@
+package weather
+
+import "aviator/errors"
+
+var WeatherStationNotFoundError = errors.AviatorError{
+	Id: "weather_station_not_found",
+	Message: errors.Message{
+		EN: "Weather station not found",
+		FR: "Station météo introuvable",
+	},
+	ApiError: 404,
+}
+
+var WeatherStationAlreadyExistsError = errors.AviatorError{
+	Id: "weather_station_already_exists",
+	Message: errors.Message{
+		EN: "A weather station with this ICAO location indicator already exists",
+		FR: "Une station météo avec cet indicateur d'emplacement OACI existe déjà",
+	},
+	ApiError: 409,
+}
+
+var WeatherMeasurementNotFoundError = errors.AviatorError{
+	Id: "weather_measurement_not_found",
+	Message: errors.Message{
+		EN: "The weather station has no measurement yet",
+		FR: "La station météo n'a encore aucune mesure",
+	},
+	ApiError: 404,
+}
+
+var WeatherAccessDenyError = errors.AviatorError{
+	Id: "weather_access_deny",
+	Message: errors.Message{
+		EN: "Only admins can manage weather stations and ingest measurements",
+		FR: "Seuls les administrateurs peuvent gérer les stations météo et importer des mesures",
+	},
+	ApiError: 401,
+}
+
+var WeatherInvalidIcaoError = errors.AviatorError{
+	Id: "weather_invalid_icao",
+	Message: errors.Message{
+		EN: "The ICAO location indicator must be made of 4 letters",
+		FR: "L'indicateur d'emplacement OACI doit être composé de 4 lettres",
+	},
+	ApiError: 400,
+}
+
+var WeatherInvalidCoordinatesError = errors.AviatorError{
+	Id: "weather_invalid_coordinates",
+	Message: errors.Message{
+		EN: "The latitude must be between -90 and 90 degrees and the longitude between -180 and 180 degrees",
+		FR: "La latitude doit être comprise entre -90 et 90 degrés et la longitude entre -180 et 180 degrés",
+	},
+	ApiError: 400,
+}
+
+var WeatherInvalidElevationError = errors.AviatorError{
+	Id: "weather_invalid_elevation",
+	Message: errors.Message{
+		EN: "The elevation must be in feet above mean sea level",
+		FR: "L'altitude doit être exprimée en pieds au-dessus du niveau moyen de la mer",
+	},
+	ApiError: 400,
+}
+
+var WeatherInvalidRunwayError = errors.AviatorError{
+	Id: "weather_invalid_runway",
+	Message: errors.Message{
+		EN: "A runway requires a designator such as 05/23, a heading between 1 and 360 degrees and a positive length",
+		FR: "Une piste requiert un identifiant tel que 05/23, un cap entre 1 et 360 degrés et une longueur positive",
+	},
+	ApiError: 400,
+}
+
+var WeatherInvalidBatchError = errors.AviatorError{
+	Id: "weather_invalid_batch",
+	Message: errors.Message{
+		EN: "Between 1 and 25 measurements can be ingested at once",
+		FR: "Entre 1 et 25 mesures peuvent être importées à la fois",
+	},
+	ApiError: 400,
+}
+
+var WeatherInvalidMeasurementError = errors.AviatorError{
+	Id: "weather_invalid_measurement",
+	Message: errors.Message{
+		EN: "A measurement requires an observation time not in the future and plausible wind, visibility, ceiling, QNH and temperature readings",
+		FR: "Une mesure requiert une heure d'observation non future et des valeurs plausibles de vent, visibilité, plafond, QNH et température",
+	},
+	ApiError: 400,
+}
+
+var WeatherDuplicateMeasurementError = errors.AviatorError{
+	Id: "weather_duplicate_measurement",
+	Message: errors.Message{
+		EN: "Two measurements of the same ingestion share the same observation time",
+		FR: "Deux mesures d'un même import ont la même heure d'observation",
+	},
+	ApiError: 400,
+}
+
+var WeatherInvalidRangeError = errors.AviatorError{
+	Id: "weather_invalid_range",
+	Message: errors.Message{
+		EN: "Historical measurements require a start and an end, and the start must be before the end",
+		FR: "L'historique des mesures requiert un début et une fin, et le début doit précéder la fin",
+	},
+	ApiError: 400,
+}
@@ -0,0 +1,240 @@
+package weather
+
+import (
+	"aviator/constants"
+	"aviator/database"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Format of the UTC times used in keys, sortable as strings
+const TIME_KEY_FORMAT = "2006-01-02T15:04:05Z"
+
+// Maximum number of measurements per ingestion, the size of a DynamoDB batch write
+const MAX_INGEST_SIZE = 25
+
+// Tolerated drift of the clock of a station reporting measurements in the future
+const MAX_CLOCK_DRIFT = 5 * time.Minute
+
+// Item used to store a measurement of a weather station
+type Measurement struct {
+	// ICAO location indicator of the station, set from the ingestion path: e.g. LSGP
+	Station string `json:"station"`
+	// Time of the observation, measurements of a station are unique per second
+	ObservedAt time.Time `json:"observedAt"`
+	// Direction the wind blows from in degrees, empty for a variable wind: e.g. 240
+	WindDirection *int `dynamodbav:",omitempty" json:"windDirection"`
+	// Mean wind speed in knots: e.g. 12
+	WindSpeed float64 `json:"windSpeed"`
+	// Gust speed in knots (if any): e.g. 22
+	WindGust *float64 `dynamodbav:",omitempty" json:"windGust"`
+	// Prevailing visibility in metres, 9999 for 10 km or more: e.g. 9999
+	Visibility int `json:"visibility"`
+	// Height of the lowest broken or overcast layer in feet above the station, empty without ceiling: e.g. 3500
+	Ceiling *int `dynamodbav:",omitempty" json:"ceiling"`
+	// Pressure reduced to sea level in hectopascals: e.g. 1018.2
+	Qnh float64 `json:"qnh"`
+	// Air temperature in degrees Celsius: e.g. 14.5
+	Temperature float64 `json:"temperature"`
+	// Dew point in degrees Celsius (if any): e.g. 8.1
+	DewPoint  *float64  `dynamodbav:",omitempty" json:"dewPoint"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Database item to store the measurement.
+type measurementItem struct {
+	// Primary key, one partition per station: e.g. MEASUREMENT#01HR9ZZNRFCKMAYNW3RY561QCP#LSGP
+	PK string
+	// Sort key, ordered by observation time: e.g. MEASUREMENT#2024-04-07T16:20:00Z
+	SK string
+
+	// Item type: measurement
+	ItemType string
+	Measurement
+}
+
+func (c *Client) measurementPK(icao string) string {
+	return fmt.Sprintf("%s#%s#%s", constants.MEASUREMENT_PARTITION_KEY, c.TenantId, icao)
+}
+
+func measurementSK(observedAt time.Time) string {
+	return fmt.Sprintf("%s#%s", constants.MEASUREMENT_PARTITION_KEY, observedAt.UTC().Format(TIME_KEY_FORMAT))
+}
+
+// Checks that the readings of a measurement are physically possible.
+func (m Measurement) Valid() bool {
+	if m.ObservedAt.IsZero() || m.ObservedAt.After(time.Now().Add(MAX_CLOCK_DRIFT)) {
+		return false
+	}
+	if m.WindDirection != nil && (*m.WindDirection < 0 || *m.WindDirection > 360) {
+		return false
+	}
+	if m.WindSpeed < 0 || (m.WindGust != nil && *m.WindGust < m.WindSpeed) {
+		return false
+	}
+	if m.Visibility < 0 || (m.Ceiling != nil && *m.Ceiling < 0) {
+		return false
+	}
+	if m.Qnh < 850 || m.Qnh > 1100 || m.Temperature < -80 || m.Temperature > 60 {
+		return false
+	}
+	return m.DewPoint == nil || *m.DewPoint <= m.Temperature
+}
+
+// Stores measurements of a weather station, at most MAX_INGEST_SIZE at once. Only admins can ingest measurements.
+// A measurement observed at the same second as a stored one replaces it, so ingestions can safely be retried.
+func (c *Client) Ingest(icao string, measurements []Measurement) ([]Measurement, error) {
+	icao = strings.ToUpper(icao)
+	c.SetLogger(c.Logger().With("station", icao, "count", len(measurements)))
+	c.Logger().Info("ingesting measurements")
+
+	if c.UserRole != constants.ROLE_ADMIN {
+		return nil, WeatherAccessDenyError
+	}
+	if len(measurements) == 0 || len(measurements) > MAX_INGEST_SIZE {
+		return nil, WeatherInvalidBatchError
+	}
+	_, err := c.GetStation(icao)
+	if err != nil {
+		return nil, err
+	}
+
+	// A batch cannot write the same item twice
+	observed := make(map[string]bool)
+	items := make([]map[string]types.AttributeValue, 0, len(measurements))
+	now := time.Now().UTC()
+	for i := range measurements {
+		measurements[i].Station = icao
+		measurements[i].ObservedAt = measurements[i].ObservedAt.UTC().Truncate(time.Second)
+		measurements[i].CreatedAt = now
+		if !measurements[i].Valid() {
+			c.Logger().Info("invalid measurement", "observedAt", measurements[i].ObservedAt)
+			return nil, WeatherInvalidMeasurementError
+		}
+		sk := measurementSK(measurements[i].ObservedAt)
+		if observed[sk] {
+			return nil, WeatherDuplicateMeasurementError
+		}
+		observed[sk] = true
+
+		item, err := attributevalue.MarshalMap(measurementItem{
+			PK:          c.measurementPK(icao),
+			SK:          sk,
+			ItemType:    "measurement",
+			Measurement: measurements[i],
+		})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	err = c.DatabaseClient.BatchWriteItem(database.BatchWriteInput{Items: items})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(measurements, func(i, j int) bool {
+		return measurements[i].ObservedAt.Before(measurements[j].ObservedAt)
+	})
+	c.Logger().Info("measurements ingested")
+	return measurements, nil
+}
+
+// Returns the most recent measurement of a weather station.
+func (c *Client) Latest(icao string) (*Measurement, error) {
+	icao = strings.ToUpper(icao)
+	c.SetLogger(c.Logger().With("station", icao))
+	c.Logger().Info("retrieving latest measurement")
+
+	output, err := c.DatabaseClient.Query(&database.QueryInput{
+		Limit:                  aws.Int32(1),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: c.measurementPK(icao)},
+			":sk": &types.AttributeValueMemberS{Value: constants.MEASUREMENT_PARTITION_KEY + "#"},
+		},
+		ScanIndexForward: aws.Bool(false),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.Items) == 0 {
+		return nil, WeatherMeasurementNotFoundError
+	}
+
+	measurement := new(Measurement)
+	err = attributevalue.UnmarshalMap(output.Items[0], measurement)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("latest measurement retrieved", "observedAt", measurement.ObservedAt)
+	return measurement, nil
+}
+
+type HistoryInput struct {
+	Start     time.Time
+	End       time.Time
+	NextToken *string
+	Limit     *int32
+}
+
+type HistoryOutput struct {
+	NextToken *string       `json:"nextToken"`
+	Results   []Measurement `json:"results"`
+}
+
+// Returns the measurements of a weather station observed between two times included, oldest first.
+func (c *Client) History(icao string, input HistoryInput) (*HistoryOutput, error) {
+	icao = strings.ToUpper(icao)
+	c.SetLogger(c.Logger().With("station", icao,
+		"start", input.Start.Format(TIME_KEY_FORMAT), "end", input.End.Format(TIME_KEY_FORMAT)))
+	c.Logger().Info("listing measurements")
+
+	if input.Start.IsZero() || input.End.IsZero() || input.End.Before(input.Start) {
+		return nil, WeatherInvalidRangeError
+	}
+
+	exclusiveStartKey, err := database.DecodeNextToken(input.NextToken, c.measurementPK(icao))
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.DatabaseClient.Query(&database.QueryInput{
+		Limit:                  input.Limit,
+		KeyConditionExpression: aws.String("PK = :pk AND SK BETWEEN :start AND :end"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":    &types.AttributeValueMemberS{Value: c.measurementPK(icao)},
+			":start": &types.AttributeValueMemberS{Value: measurementSK(input.Start)},
+			":end":   &types.AttributeValueMemberS{Value: measurementSK(input.End)},
+		},
+		ExclusiveStartKey: exclusiveStartKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	measurements := make([]Measurement, 0)
+	err = attributevalue.UnmarshalListOfMaps(output.Items, &measurements)
+	if err != nil {
+		return nil, err
+	}
+
+	nextToken, err := database.EncodeNextToken(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("measurements listed", "count", len(measurements), "isNextToken", nextToken != nil)
+	return &HistoryOutput{
+		NextToken: nextToken,
+		Results:   measurements,
+	}, nil
+}
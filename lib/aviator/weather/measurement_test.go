@@ -0,0 +1,70 @@
+package weather
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMeasurementValid(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	floatPtr := func(f float64) *float64 { return &f }
+	now := time.Now().UTC()
+	// Returns a valid measurement of a fresh breeze, modified by change
+	newMeasurement := func(change func(m *Measurement)) Measurement {
+		m := Measurement{
+			Station:       "LSGP",
+			ObservedAt:    now.Add(-10 * time.Minute),
+			WindDirection: intPtr(240),
+			WindSpeed:     12,
+			WindGust:      floatPtr(22),
+			Visibility:    9999,
+			Ceiling:       intPtr(3500),
+			Qnh:           1018.2,
+			Temperature:   14.5,
+			DewPoint:      floatPtr(8.1),
+		}
+		if change != nil {
+			change(&m)
+		}
+		return m
+	}
+
+	tests := []struct {
+		name   string
+		change func(m *Measurement)
+		want   bool
+	}{
+		{"complete measurement", nil, true},
+		{"missing observation time", func(m *Measurement) { m.ObservedAt = time.Time{} }, false},
+		{"observed within the clock drift", func(m *Measurement) { m.ObservedAt = now.Add(MAX_CLOCK_DRIFT - time.Minute) }, true},
+		{"observed in the future", func(m *Measurement) { m.ObservedAt = now.Add(MAX_CLOCK_DRIFT + time.Minute) }, false},
+		{"variable wind", func(m *Measurement) { m.WindDirection = nil }, true},
+		{"wind from the north", func(m *Measurement) { m.WindDirection = intPtr(360) }, true},
+		{"calm", func(m *Measurement) { m.WindDirection, m.WindSpeed, m.WindGust = intPtr(0), 0, nil }, true},
+		{"negative wind direction", func(m *Measurement) { m.WindDirection = intPtr(-10) }, false},
+		{"wind direction above 360", func(m *Measurement) { m.WindDirection = intPtr(370) }, false},
+		{"negative wind speed", func(m *Measurement) { m.WindSpeed, m.WindGust = -1, nil }, false},
+		{"gust equal to the wind speed", func(m *Measurement) { m.WindGust = floatPtr(12) }, true},
+		{"gust below the wind speed", func(m *Measurement) { m.WindGust = floatPtr(10) }, false},
+		{"zero visibility", func(m *Measurement) { m.Visibility = 0 }, true},
+		{"negative visibility", func(m *Measurement) { m.Visibility = -1 }, false},
+		{"no ceiling", func(m *Measurement) { m.Ceiling = nil }, true},
+		{"negative ceiling", func(m *Measurement) { m.Ceiling = intPtr(-100) }, false},
+		{"missing qnh", func(m *Measurement) { m.Qnh = 0 }, false},
+		{"lowest qnh", func(m *Measurement) { m.Qnh = 850 }, true},
+		{"qnh too low", func(m *Measurement) { m.Qnh = 849.9 }, false},
+		{"highest qnh", func(m *Measurement) { m.Qnh = 1100 }, true},
+		{"qnh too high", func(m *Measurement) { m.Qnh = 1100.1 }, false},
+		{"qnh in inches of mercury", func(m *Measurement) { m.Qnh = 30.06 }, false},
+		{"temperature too low", func(m *Measurement) { m.Temperature, m.DewPoint = -80.5, nil }, false},
+		{"temperature too high", func(m *Measurement) { m.Temperature = 60.5 }, false},
+		{"no dew point", func(m *Measurement) { m.DewPoint = nil }, true},
+		{"saturated air", func(m *Measurement) { m.DewPoint = floatPtr(14.5) }, true},
+		{"dew point above the temperature", func(m *Measurement) { m.DewPoint = floatPtr(15) }, false},
+	}
+	for _, test := range tests {
+		if got := newMeasurement(test.change).Valid(); got != test.want {
+			t.Errorf("%s: Valid() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
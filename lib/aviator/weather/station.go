@@ -0,0 +1,286 @@
+/*
+Package weather provides methods for managing the weather stations of a club and ingesting their measurements.
+Stations are stored in the partition of the club, and the measurements of each station in a partition of their own,
+ordered by observation time, so the latest and historical readings are read with a single query.
+*/
+package weather
+
+import (
+	"aviator/constants"
+	"aviator/database"
+	"aviator/tenant"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// ICAO location indicator: e.g. LSGP
+var icaoPattern = regexp.MustCompile(`^[A-Z]{4}$`)
+
+// Runway designator, with the reciprocal runway (if any): e.g. 05/23 or 09L/27R
+var runwayPattern = regexp.MustCompile(`^(0[1-9]|[12][0-9]|3[0-6])[LCR]?(/(0[1-9]|[12][0-9]|3[0-6])[LCR]?)?$`)
+
+type WeatherApiInterface interface {
+	Logger() *slog.Logger
+	SetLogger(logger *slog.Logger)
+	CreateStation(input Station) (*Station, error)
+	GetStation(icao string) (*Station, error)
+	ListStations(input ListInput) (*ListStationsOutput, error)
+	UpdateStation(input Station) (*Station, error)
+	DeleteStation(icao string) error
+	Ingest(icao string, measurements []Measurement) ([]Measurement, error)
+	Latest(icao string) (*Measurement, error)
+	History(icao string, input HistoryInput) (*HistoryOutput, error)
+}
+
+type Config struct {
+	tenant.Scope
+}
+
+type Client struct {
+	Config
+}
+
+// Runway of the airfield of a station
+type Runway struct {
+	// Designator, with the reciprocal runway (if any): e.g. 05/23
+	Designator string `json:"designator"`
+	// Magnetic heading of the first runway in degrees: e.g. 52
+	Heading int `json:"heading"`
+	// Length in metres: e.g. 2800
+	Length int `json:"length"`
+	// Surface: e.g. asphalt or grass
+	Surface string `json:"surface"`
+}
+
+// Item used to store a weather station
+type Station struct {
+	// ICAO location indicator, unique within a club: e.g. LSGP
+	Icao string `json:"icao"`
+	Name string `json:"name"`
+	// Latitude in decimal degrees: e.g. 46.8428
+	Latitude float64 `json:"latitude"`
+	// Longitude in decimal degrees: e.g. 6.9153
+	Longitude float64 `json:"longitude"`
+	// Elevation in feet above mean sea level: e.g. 1465
+	Elevation int       `json:"elevation"`
+	Runways   []Runway  `json:"runways"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Database item to store the station.
+type stationItem struct {
+	// Primary key: e.g. CLUB#01HR9ZZNRFCKMAYNW3RY561QCP
+	PK string
+	// Sort key: e.g. STATION#LSGP
+	SK string
+
+	// Item type: station
+	ItemType string
+	Station
+}
+
+// Returns a new weather API client from the provided config.
+func NewFromConfig(c Config) *Client {
+	return &Client{Config: c}
+}
+
+func (c *Client) Logger() *slog.Logger {
+	return c.Config.Logger
+}
+
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.Config.Logger = logger
+}
+
+func (c *Client) stationSK(icao string) string {
+	return fmt.Sprintf("%s#%s", constants.STATION_PARTITION_KEY, icao)
+}
+
+// Adds a weather station to the club. Only admins can manage stations.
+func (c *Client) CreateStation(input Station) (*Station, error) {
+	c.SetLogger(c.Logger().With("station", input.Icao))
+	c.Logger().Info("creating station")
+
+	out, err := c.putStation(input, "attribute_not_exists(PK)")
+	if err != nil {
+		var conditionalCheckFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionalCheckFailed) {
+			return nil, WeatherStationAlreadyExistsError
+		}
+		return nil, err
+	}
+
+	c.Logger().Info("station created")
+	return out, nil
+}
+
+// Replaces the stored data of an existing weather station. Only admins can manage stations.
+func (c *Client) UpdateStation(input Station) (*Station, error) {
+	c.SetLogger(c.Logger().With("station", input.Icao))
+	c.Logger().Info("updating station")
+
+	out, err := c.putStation(input, "attribute_exists(PK)")
+	if err != nil {
+		var conditionalCheckFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionalCheckFailed) {
+			return nil, WeatherStationNotFoundError
+		}
+		return nil, err
+	}
+
+	c.Logger().Info("station updated")
+	return out, nil
+}
+
+func (c *Client) putStation(input Station, conditionExpression string) (*Station, error) {
+	if c.UserRole != constants.ROLE_ADMIN {
+		return nil, WeatherAccessDenyError
+	}
+	input.Icao = strings.ToUpper(input.Icao)
+	if !icaoPattern.MatchString(input.Icao) {
+		return nil, WeatherInvalidIcaoError
+	}
+	if input.Latitude < -90 || input.Latitude > 90 || input.Longitude < -180 || input.Longitude > 180 {
+		return nil, WeatherInvalidCoordinatesError
+	}
+	// Lowest airfields lie around the Dead Sea, 1300 feet below sea level
+	if input.Elevation < -1500 {
+		return nil, WeatherInvalidElevationError
+	}
+	if input.Runways == nil {
+		input.Runways = make([]Runway, 0)
+	}
+	for _, runway := range input.Runways {
+		if !runwayPattern.MatchString(runway.Designator) || runway.Heading < 1 || runway.Heading > 360 || runway.Length <= 0 {
+			return nil, WeatherInvalidRunwayError
+		}
+	}
+
+	out, err := c.DatabaseClient.Put(database.PutInput{
+		Item: stationItem{
+			PK:       c.clubPK(),
+			SK:       c.stationSK(input.Icao),
+			ItemType: "station",
+			Station:  input,
+		},
+		ConditionExpression: aws.String(conditionExpression),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	input.CreatedAt = out.CreatedAt
+	input.UpdatedAt = out.UpdatedAt
+	return &input, nil
+}
+
+// Returns stored data for a weather station.
+func (c *Client) GetStation(icao string) (*Station, error) {
+	c.SetLogger(c.Logger().With("station", icao))
+	c.Logger().Info("retrieving station")
+
+	output, err := c.DatabaseClient.Get(database.GetInput{
+		PK: c.clubPK(),
+		SK: c.stationSK(strings.ToUpper(icao)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.Item == nil {
+		return nil, WeatherStationNotFoundError
+	}
+
+	station := new(Station)
+	err = attributevalue.UnmarshalMap(output.Item, station)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("station retrieved")
+	return station, nil
+}
+
+type ListInput struct {
+	NextToken *string
+	Limit     *int32
+}
+
+type ListStationsOutput struct {
+	NextToken *string   `json:"nextToken"`
+	Results   []Station `json:"results"`
+}
+
+// Returns stored data for all weather stations of the club, ordered by ICAO location indicator.
+func (c *Client) ListStations(input ListInput) (*ListStationsOutput, error) {
+	c.Logger().Info("listing stations")
+
+	exclusiveStartKey, err := database.DecodeNextToken(input.NextToken, c.clubPK())
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.DatabaseClient.Query(&database.QueryInput{
+		Limit:                  input.Limit,
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: c.clubPK()},
+			":sk": &types.AttributeValueMemberS{Value: constants.STATION_PARTITION_KEY + "#"},
+		},
+		ExclusiveStartKey: exclusiveStartKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stations := make([]Station, 0)
+	err = attributevalue.UnmarshalListOfMaps(output.Items, &stations)
+	if err != nil {
+		return nil, err
+	}
+
+	nextToken, err := database.EncodeNextToken(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger().Info("stations listed", "count", len(stations), "isNextToken", nextToken != nil)
+	return &ListStationsOutput{
+		NextToken: nextToken,
+		Results:   stations,
+	}, nil
+}
+
+// Removes a weather station from the club. Its measurements are kept until they expire.
+func (c *Client) DeleteStation(icao string) error {
+	c.SetLogger(c.Logger().With("station", icao))
+	c.Logger().Info("deleting station")
+
+	if c.UserRole != constants.ROLE_ADMIN {
+		return WeatherAccessDenyError
+	}
+
+	_, err := c.DatabaseClient.Delete(&database.DeleteInput{
+		PK: c.clubPK(),
+		SK: c.stationSK(strings.ToUpper(icao)),
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Logger().Info("station deleted")
+	return nil
+}
+
+// Returns the partition key of the tenant club owning the stations.
+func (c *Client) clubPK() string {
+	return fmt.Sprintf("%s#%s", constants.CLUB_PARTITION_KEY, c.TenantId)
+}
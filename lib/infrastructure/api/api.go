@@ -19,6 +19,13 @@ type Input struct {
 	OpenAPISpecPath   string
 	DynamodbTableName string
 	DynamodbTableArn  pulumi.StringOutput
+	// Club allowed to create and list the other clubs
+	PlatformTenantId string
+	// JWKS document used to verify the tokens of the callers
+	JwksUrl string
+	// Expected iss and aud claims of the tokens, not checked if empty
+	JwtIssuer   string
+	JwtAudience string
 }
 
 type Output struct {
@@ -148,6 +155,7 @@ func lambdaExecutionRole(ctx *pulumi.Context, input Input) (*iam.Role, error) {
 								},
 								Resources: []string{
 									arn,
+									arn + "/index/*",
 								},
 							},
 						},
@@ -227,10 +235,14 @@ func lambdaInvocationRole(ctx *pulumi.Context) (*iam.Role, error) {
 }
 
 func appFunction(ctx *pulumi.Context, functionName string, rootDir string, input Input, role *iam.Role, otelLayerArn string) error {
-	environment := pulumi.All(input.DynamodbTableName).ApplyT(
+	environment := pulumi.All(input.DynamodbTableName, input.PlatformTenantId).ApplyT(
 		func(args []interface{}) pulumi.StringMap {
 			return pulumi.StringMap{
 				"DYNAMODB_TABLE_NAME": pulumi.String(args[0].(string)),
+				"PLATFORM_TENANT_ID":  pulumi.String(args[1].(string)),
+				"JWKS_URL":            pulumi.String(input.JwksUrl),
+				"JWT_ISSUER":          pulumi.String(input.JwtIssuer),
+				"JWT_AUDIENCE":        pulumi.String(input.JwtAudience),
 			}
 		},
 	).(pulumi.StringMapOutput)